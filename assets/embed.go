@@ -0,0 +1,215 @@
+// Package assets 嵌入 OCR 服务脚本、OCR 环境清单和界面文本字典，启动时提取到程序目录
+package assets
+
+import (
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	_const "qq_client/internal/const"
+	"sort"
+	"strings"
+	"time"
+)
+
+//go:embed ocr_setup.bat ocr_setup_simple.bat ocr_server.py download_model.py check_models.py fix_ocr_models.bat ocr_manifest.json ocr_requirements.* locales
+var FS embed.FS
+
+// 文件状态
+const (
+	StatusOK       = "ok"       // 与当前版本一致
+	StatusMissing  = "missing"  // 文件不存在
+	StatusOutdated = "outdated" // 上一版本提取后未被修改，需要更新
+	StatusModified = "modified" // 被用户修改（或来源未知），与当前版本不一致
+)
+
+// FileRecord 提取记录中的一个文件
+type FileRecord struct {
+	SHA256      string    `json:"sha256"`       // 提取时写入内容的 SHA-256
+	ExtractedAt time.Time `json:"extracted_at"` // 提取时间
+}
+
+// Manifest 提取记录
+type Manifest struct {
+	UpdatedAt time.Time             `json:"updated_at"`
+	Files     map[string]FileRecord `json:"files"` // 嵌入路径 -> 提取记录
+}
+
+// FileStatus 嵌入文件在磁盘上的状态
+type FileStatus struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Embedded string `json:"embedded"`       // 当前版本的 SHA-256
+	OnDisk   string `json:"disk,omitempty"` // 磁盘文件的 SHA-256
+	Backup   string `json:"backup,omitempty"`
+}
+
+// Extract 提取嵌入的文件到指定目录
+// @description: 文件按内容哈希区分版本：缺失或过期的文件自动提取；被用户修改的文件先备份再替换，
+// locales/ 中被用户修改的字典保留不替换。提取结果写入 assets_manifest.json
+// @param: dir string 目标目录
+// @return: []FileStatus 提取后与当前版本仍不一致的文件, error
+func Extract(dir string) ([]FileStatus, error) {
+	manifest := loadManifest(dir)
+	statuses, err := scan(dir, manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var mismatches []FileStatus
+	for _, status := range statuses {
+		outputFileName := filepath.Join(dir, filepath.FromSlash(status.Name))
+		switch status.Status {
+		case StatusOK:
+			if _, recorded := manifest.Files[status.Name]; !recorded {
+				manifest.Files[status.Name] = FileRecord{SHA256: status.Embedded, ExtractedAt: now}
+			}
+			continue
+		case StatusModified:
+			if userEditable(status.Name) {
+				fmt.Printf("[WARN] 文件 %s 已被修改，保留用户修改（与当前版本不一致）\n", status.Name)
+				mismatches = append(mismatches, status)
+				continue
+			}
+			backup := outputFileName + "." + now.Format(_const.AssetsBackupTimeFormat) + ".bak"
+			if err = os.Rename(outputFileName, backup); err != nil {
+				return nil, fmt.Errorf("备份文件 %s 失败: %v", status.Name, err)
+			}
+			fmt.Printf("文件 %s 已被修改，已备份到 %s\n", status.Name, backup)
+		}
+
+		content, err := FS.ReadFile(status.Name)
+		if err != nil {
+			return nil, fmt.Errorf("读取嵌入文件 %s 失败: %v", status.Name, err)
+		}
+		if err = writeFile(outputFileName, content); err != nil {
+			return nil, err
+		}
+		manifest.Files[status.Name] = FileRecord{SHA256: status.Embedded, ExtractedAt: now}
+		if status.Status == StatusMissing {
+			fmt.Printf("已提取文件: %s\n", status.Name)
+		} else {
+			fmt.Printf("已更新文件: %s\n", status.Name)
+		}
+	}
+
+	manifest.UpdatedAt = now
+	if err = saveManifest(dir, manifest); err != nil {
+		return nil, err
+	}
+	return mismatches, nil
+}
+
+// Status 检查嵌入文件在磁盘上的状态（不修改文件）
+// @param: dir string 目标目录
+// @return: []FileStatus 与当前版本不一致的文件, error
+func Status(dir string) ([]FileStatus, error) {
+	statuses, err := scan(dir, loadManifest(dir))
+	if err != nil {
+		return nil, err
+	}
+	var mismatches []FileStatus
+	for _, status := range statuses {
+		if status.Status != StatusOK {
+			mismatches = append(mismatches, status)
+		}
+	}
+	return mismatches, nil
+}
+
+// scan 比较全部嵌入文件与磁盘文件
+func scan(dir string, manifest *Manifest) ([]FileStatus, error) {
+	var statuses []FileStatus
+	err := fs.WalkDir(FS, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() || strings.HasSuffix(name, ".go") {
+			return nil
+		}
+		content, err := FS.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("读取嵌入文件 %s 失败: %v", name, err)
+		}
+		status := FileStatus{Name: name, Embedded: hashBytes(content)}
+
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		switch {
+		case os.IsNotExist(err):
+			status.Status = StatusMissing
+		case err != nil:
+			return fmt.Errorf("读取文件 %s 失败: %v", name, err)
+		default:
+			status.OnDisk = hashBytes(data)
+			record, recorded := manifest.Files[name]
+			switch {
+			case status.OnDisk == status.Embedded:
+				status.Status = StatusOK
+			case recorded && record.SHA256 == status.OnDisk:
+				status.Status = StatusOutdated
+			default:
+				// 没有提取记录（旧版本提取的文件）时无法判断是否被修改，按修改处理以免丢失用户改动
+				status.Status = StatusModified
+			}
+		}
+		statuses = append(statuses, status)
+		return nil
+	})
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, err
+}
+
+// userEditable 文件是否允许用户修改（修改后不被新版本替换）
+func userEditable(name string) bool {
+	return strings.HasPrefix(name, _const.AssetsUserEditableDir+"/")
+}
+
+// writeFile 写入文件（先写临时文件再改名，避免中断时留下不完整的脚本）
+func writeFile(path string, content []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("创建目录 %s 失败: %v", filepath.Dir(path), err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, content, 0644); err != nil {
+		return fmt.Errorf("写入文件 %s 失败: %v", path, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("写入文件 %s 失败: %v", path, err)
+	}
+	return nil
+}
+
+// loadManifest 读取提取记录，不存在或损坏时返回空记录
+func loadManifest(dir string) *Manifest {
+	manifest := &Manifest{}
+	if data, err := os.ReadFile(filepath.Join(dir, _const.AssetsManifestFile)); err == nil {
+		if err = json.Unmarshal(data, manifest); err != nil {
+			fmt.Printf("[WARN] 解析 %s 失败，将重新生成: %v\n", _const.AssetsManifestFile, err)
+		}
+	}
+	if manifest.Files == nil {
+		manifest.Files = make(map[string]FileRecord)
+	}
+	return manifest
+}
+
+// saveManifest 写入提取记录
+func saveManifest(dir string, manifest *Manifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化提取记录失败: %v", err)
+	}
+	return writeFile(filepath.Join(dir, _const.AssetsManifestFile), append(data, '\n'))
+}
+
+// hashBytes 计算 SHA-256（小写十六进制）
+func hashBytes(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
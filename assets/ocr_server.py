@@ -8,10 +8,13 @@ PaddleOCR HTTP 服务
 import os
 import sys
 import json
+import argparse
 import base64
 import logging
 import shutil
 import tarfile
+import threading
+import unicodedata
 from io import BytesIO
 from paddleocr import PaddleOCR
 from flask import Flask, request, jsonify
@@ -33,6 +36,25 @@ app = Flask(__name__)
 ocr = None
 ocr_initialized = False
 
+# 默认识别语言（与 initialize_paddleocr 一致）
+DEFAULT_OCR_LANG = 'ch'
+
+# 按语言提示懒加载的 PaddleOCR 实例
+ocr_lang_instances = {}
+ocr_lang_failed = set()
+ocr_lang_lock = threading.Lock()
+
+# PaddleOCR 实例不是线程安全的，Flask 以多线程运行，识别调用需要串行执行
+# 解码、裁剪和预处理仍可与其他请求并行
+ocr_inference_lock = threading.Lock()
+
+# 预处理开关默认值（请求未指定时全部启用，与旧版本行为一致）
+DEFAULT_PREPROCESS = {
+    "sharpen": True,
+    "contrast": True,
+    "edge_enhance": True
+}
+
 def check_and_clean_corrupted_models(cache_dir):
     """
     检查并清理损坏的模型文件
@@ -278,66 +300,197 @@ def initialize_paddleocr():
         
         return False
 
-def preprocess_image(img):
+def get_ocr_for_lang(lang):
+    """
+    获取指定语言的 PaddleOCR 实例
+    
+    @description: 未指定或为默认语言时使用主实例；其他语言首次使用时加载（需要对应模型），加载失败时回退到主实例
+    @param: lang string PaddleOCR 语言代码（如 en、ch、korean、german、ru）
+    @return: ocr PaddleOCR 实例
+    """
+    if not lang or lang == DEFAULT_OCR_LANG:
+        return ocr
+
+    with ocr_lang_lock:
+        if lang in ocr_lang_instances:
+            return ocr_lang_instances[lang]
+        if lang in ocr_lang_failed:
+            return ocr
+
+        try:
+            logger.info(f"加载语言 {lang} 的识别模型...")
+            instance = PaddleOCR(
+                use_gpu=False,
+                lang=lang,
+                show_log=False,
+                use_angle_cls=True,
+                det_db_thresh=0.4,
+                det_db_box_thresh=0.5,
+                det_db_unclip_ratio=1.6,
+                use_dilation=True,
+                det_db_score_mode='slow'
+            )
+            ocr_lang_instances[lang] = instance
+            logger.info(f"语言 {lang} 的识别模型加载完成")
+            return instance
+        except Exception as e:
+            logger.warning(f"加载语言 {lang} 的识别模型失败: {e}，使用默认模型")
+            ocr_lang_failed.add(lang)
+            return ocr
+
+def parse_preprocess(options):
+    """
+    解析预处理开关
+    
+    @param: options dict 请求中的 preprocess 字段（可选）
+    @return: dict 完整的预处理开关
+    """
+    result = dict(DEFAULT_PREPROCESS)
+    if isinstance(options, dict):
+        for key in result:
+            if key in options:
+                result[key] = bool(options[key])
+    return result
+
+def parse_rect(rect, width, height):
+    """
+    解析并裁剪矩形区域到图片范围内
+    
+    @param: rect dict {"left", "top", "right", "bottom"}（可选）
+    @param: width int 图片宽度
+    @param: height int 图片高度
+    @return: tuple (left, top, right, bottom)，未指定或无效时返回 None
+    """
+    if not isinstance(rect, dict):
+        return None
+    try:
+        left = max(0, min(int(rect.get('left', 0)), width))
+        top = max(0, min(int(rect.get('top', 0)), height))
+        right = max(0, min(int(rect.get('right', width)), width))
+        bottom = max(0, min(int(rect.get('bottom', height)), height))
+    except (TypeError, ValueError):
+        return None
+    if right <= left or bottom <= top:
+        return None
+    return (left, top, right, bottom)
+
+def parse_targets(target_text):
+    """
+    解析目标文字（支持单个字符串或字符串列表）
+    
+    @return: list 目标文字列表
+    """
+    if not target_text:
+        return []
+    if isinstance(target_text, str):
+        return [target_text]
+    if isinstance(target_text, list):
+        return [t for t in target_text if isinstance(t, str) and t.strip()]
+    return []
+
+def normalize_text(text):
+    """
+    规范化文字以便比较（与客户端 normalizeUIText 一致）
+    
+    @description: 全角转半角、转大写、去除所有空白
+    """
+    text = unicodedata.normalize('NFKC', text or '')
+    return "".join(text.split()).upper()
+
+def edit_distance(a, b):
+    """
+    计算两个字符串的编辑距离（按字符）
+    """
+    prev = list(range(len(b) + 1))
+    for i in range(1, len(a) + 1):
+        curr = [i] + [0] * len(b)
+        for j in range(1, len(b) + 1):
+            cost = 0 if a[i - 1] == b[j - 1] else 1
+            curr[j] = min(prev[j] + 1, curr[j - 1] + 1, prev[j - 1] + cost)
+        prev = curr
+    return prev[len(b)]
+
+def match_items(items, targets, min_confidence=0.0, max_edit_distance=0):
+    """
+    在识别结果中查找目标文字
+    
+    @description: 文本块整体及其中每个单词都作为候选，规范化后编辑距离不超过 max_edit_distance 即视为匹配
+    @param: items list 识别到的文本块
+    @param: targets list 目标文字列表（任一匹配即可）
+    @param: min_confidence float 最低置信度
+    @param: max_edit_distance int 允许的编辑距离
+    @return: list 匹配的文本块（附带 matched 字段）
+    """
+    expected = [(t, normalize_text(t)) for t in targets]
+    expected = [(t, n) for t, n in expected if n]
+    matches = []
+    for item in items:
+        if item["confidence"] < min_confidence:
+            continue
+        candidates = [normalize_text(item["text"])]
+        words = item["text"].split()
+        if len(words) > 1:
+            candidates.extend(normalize_text(w) for w in words)
+        for target, normalized in expected:
+            if any(edit_distance(c, normalized) <= max_edit_distance for c in candidates if c):
+                matches.append(dict(item, matched=target))
+                break
+    return matches
+
+def preprocess_image(img, options=None):
     """
     预处理图片（图像增强以提高模糊文字识别准确度）
     
     @description: 对图片进行锐化、对比度增强等处理，提高模糊文字的识别准确度，不改变图片尺寸
     @param: img Image PIL图片对象
+    @param: options dict 预处理开关（sharpen/contrast/edge_enhance），为空时全部启用
     @return: img_array ndarray 处理后的numpy数组
     """
+    options = parse_preprocess(options)
+
     # 确保图片是 RGB 模式
     if img.mode != 'RGB':
         img = img.convert('RGB')
     
     # 1. 锐化处理（Unsharp Masking）- 提高文字边缘清晰度
     # 使用轻微锐化，避免过度处理导致噪点
-    img = img.filter(ImageFilter.UnsharpMask(radius=1, percent=150, threshold=3))
+    if options["sharpen"]:
+        img = img.filter(ImageFilter.UnsharpMask(radius=1, percent=150, threshold=3))
     
     # 2. 对比度增强 - 提高文字与背景的对比度
-    enhancer = ImageEnhance.Contrast(img)
-    img = enhancer.enhance(1.3)  # 增强30%对比度
+    if options["contrast"]:
+        enhancer = ImageEnhance.Contrast(img)
+        img = enhancer.enhance(1.3)  # 增强30%对比度
     
     # 3. 轻微锐化边缘（针对模糊文字）
     # 使用边缘增强滤波器
-    img = img.filter(ImageFilter.EDGE_ENHANCE_MORE)
+    if options["edge_enhance"]:
+        img = img.filter(ImageFilter.EDGE_ENHANCE_MORE)
     
     # 4. 转换为 numpy 数组，保持原始分辨率
     img_array = np.array(img)
     
     return img_array
 
-def process_ocr_result(result, target_text=None):
+def extract_items(result, offset=(0, 0)):
     """
-    处理 OCR 识别结果
+    提取 OCR 识别结果中的文字和坐标
     
-    @description: 提取OCR识别结果中的文字和坐标，可选检查是否包含目标文字
     @param: result list OCR识别结果
-    @param: target_text string 目标文字（可选）
-    @return: response dict 处理后的响应数据
+    @param: offset tuple 坐标偏移（裁剪区域左上角），返回的坐标为原图坐标
+    @return: items list 文本块
     """
-    if not result:
-        return {
-            "code": 200, 
-            "data": "", 
-            "items": [],
-            "message": "没有识别到文字"
-        }
-    
-    # 提取所有文字和坐标
-    texts = []
     items = []
-    
-    for line in result:
+    dx, dy = offset
+    for line in result or []:
         if len(line) >= 2:
             # line[0] 是坐标信息（四个顶点）
             # line[1] 是 [文字内容, 置信度]
-            coordinates = line[0]  # [[x1,y1], [x2,y2], [x3,y3], [x4,y4]]
+            coordinates = [[float(coord[0]) + dx, float(coord[1]) + dy] for coord in line[0]]  # [[x1,y1], [x2,y2], [x3,y3], [x4,y4]]
             text = line[1][0].strip()
             confidence = line[1][1]
             
             if text:
-                texts.append(text)
                 items.append({
                     "text": text,
                     "confidence": float(confidence),
@@ -349,38 +502,83 @@ def process_ocr_result(result, target_text=None):
                         "bottom": int(max(coord[1] for coord in coordinates))
                     }
                 })
+    return items
+
+def build_match_response(items, targets, min_confidence=0.0, max_edit_distance=0):
+    """
+    根据文本块和目标文字生成响应
     
-    # 合并所有文字
-    full_text = " ".join(texts).strip()
-    
-    # 如果指定了目标文字，检查是否匹配
-    if target_text:
-        # 忽略大小写和空格进行比较
-        clean_full_text = full_text.replace(" ", "").lower()
-        clean_target_text = target_text.replace(" ", "").lower()
-        
-        if clean_target_text in clean_full_text:
+    @description: 未指定目标文字时 code=100（有文字）或 200（无文字）；指定时 code=100 表示找到目标，200 表示未找到
+    @param: items list 文本块
+    @param: targets list 目标文字列表
+    @return: response dict
+    """
+    full_text = " ".join(item["text"] for item in items).strip()
+    if not items:
+        return {"code": 200, "data": "", "items": [], "matches": [], "message": "没有识别到文字"}
+
+    if targets:
+        matches = match_items(items, targets, min_confidence, max_edit_distance)
+        if matches:
             return {
-                "code": 100, 
-                "data": target_text, 
+                "code": 100,
+                "data": matches[0]["matched"],
                 "items": items,
+                "matches": matches,
                 "full_text": full_text,
                 "message": "识别成功"
             }
-        else:
-            return {
-                "code": 200, 
-                "data": full_text, 
-                "items": items,
-                "message": "未找到目标文字"
-            }
+        return {"code": 200, "data": full_text, "items": items, "matches": [], "message": "未找到目标文字"}
+
+    return {"code": 100, "data": full_text, "items": items, "message": "识别成功"}
+
+def process_ocr_result(result, target_text=None, offset=(0, 0), min_confidence=0.0, max_edit_distance=0):
+    """
+    处理 OCR 识别结果
     
-    return {
-        "code": 100, 
-        "data": full_text, 
-        "items": items,
-        "message": "识别成功"
-    }
+    @description: 提取OCR识别结果中的文字和坐标，可选检查是否包含目标文字
+    @param: result list OCR识别结果
+    @param: target_text string|list 目标文字（可选，列表中任一匹配即可）
+    @param: offset tuple 坐标偏移（裁剪区域左上角）
+    @return: response dict 处理后的响应数据
+    """
+    items = extract_items(result, offset)
+    return build_match_response(items, parse_targets(target_text), min_confidence, max_edit_distance)
+
+def item_in_rect(item, rect):
+    """
+    文本块中心是否在矩形区域内
+    """
+    position = item["position"]
+    center_x = (position["left"] + position["right"]) / 2
+    center_y = (position["top"] + position["bottom"]) / 2
+    left, top, right, bottom = rect
+    return left <= center_x <= right and top <= center_y <= bottom
+
+def process_queries(items, queries, width, height, defaults):
+    """
+    在同一帧的识别结果上执行多个查询
+    
+    @param: items list 整帧（或整体裁剪区域）的文本块，坐标为原图坐标
+    @param: queries list 查询列表 [{"id", "target_text", "crop", "min_confidence", "max_edit_distance"}]
+    @param: defaults dict 查询未指定时使用的 min_confidence/max_edit_distance
+    @return: results list 每个查询的响应（附带 id）
+    """
+    results = []
+    for index, query in enumerate(queries):
+        if not isinstance(query, dict):
+            continue
+        rect = parse_rect(query.get('crop'), width, height)
+        scoped = [item for item in items if rect is None or item_in_rect(item, rect)]
+        response = build_match_response(
+            scoped,
+            parse_targets(query.get('target_text')),
+            float(query.get('min_confidence', defaults['min_confidence'])),
+            int(query.get('max_edit_distance', defaults['max_edit_distance']))
+        )
+        response["id"] = str(query.get('id', index))
+        results.append(response)
+    return results
 
 @app.route('/api/ocr', methods=['POST'])
 def ocr_recognition():
@@ -389,7 +587,8 @@ def ocr_recognition():
     
     @Tags OCR
     @Summary OCR文字识别（含坐标）
-    @Description 接收Base64编码的图片，返回识别的文字内容、坐标和置信度
+    @Description 接收Base64编码的图片，返回识别的文字内容、坐标和置信度；可指定目标文字、裁剪区域、语言提示和预处理开关
+    @Description 也可在同一帧上执行多个查询（queries），每个查询有自己的区域和目标文字
     @Accept application/json
     @Produce application/json
     @Success 200 {object} response.Response{data=string,items=array} "识别成功，返回文字、坐标、置信度"
@@ -397,6 +596,20 @@ def ocr_recognition():
     @Failure 500 {object} response.Response "服务器错误"
     @Router /api/ocr [post]
     
+    请求数据格式（除 image 外均可选）：
+    {
+        "image": "Base64 图片",
+        "target_text": "目标文字" 或 ["目标文字1", "目标文字2"],   // 任一匹配即 code=100
+        "crop": {"left": 0, "top": 0, "right": 100, "bottom": 50},   // 只识别该区域，返回坐标仍为原图坐标
+        "lang": "en",                                               // 语言提示（PaddleOCR 语言代码）
+        "preprocess": {"sharpen": true, "contrast": true, "edge_enhance": true},
+        "min_confidence": 0.5,
+        "max_edit_distance": 1,
+        "queries": [
+            {"id": "MUTE", "target_text": ["MUTE"], "crop": {...}}   // 在同一帧上的查询，结果见 results
+        ]
+    }
+
     返回数据格式：
     {
         "code": 100,
@@ -443,26 +656,46 @@ def ocr_recognition():
         logger.error(f"图片解码失败: {e}")
         return jsonify({"code": 400, "data": "", "message": "图片格式错误"})
 
-    # 获取目标文字（如果有）
+    # 获取目标文字、裁剪区域、语言提示和预处理开关（均可选）
     target_text = data.get('target_text', None)
+    width, height = img.size
+    crop = parse_rect(data.get('crop'), width, height)
+    lang = data.get('lang') or None
+    queries = data.get('queries') or []
+    try:
+        defaults = {
+            "min_confidence": float(data.get('min_confidence', 0.0)),
+            "max_edit_distance": int(data.get('max_edit_distance', 0))
+        }
+    except (TypeError, ValueError):
+        return jsonify({"code": 400, "data": "", "message": "min_confidence/max_edit_distance 格式错误"})
 
     # 执行 OCR 识别
     try:
-        logger.info("开始执行 OCR 识别...")
+        logger.info(f"开始执行 OCR 识别... (区域: {crop or '全图'}, 语言: {lang or DEFAULT_OCR_LANG}, 查询: {len(queries)})")
         
+        # 裁剪到指定区域，识别结果坐标加上偏移量还原为原图坐标
+        offset = (0, 0)
+        if crop:
+            img = img.crop(crop)
+            offset = (crop[0], crop[1])
+
         # 预处理图片（转换为 numpy 数组，保持原始分辨率）
-        img_array = preprocess_image(img)
+        img_array = preprocess_image(img, data.get('preprocess'))
         
         # 执行 OCR 识别（使用 cls=False 加快速度）
-        result = ocr.ocr(img_array, cls=False)
+        engine = get_ocr_for_lang(lang)
+        with ocr_inference_lock:
+            result = engine.ocr(img_array, cls=False)
+        lines = result[0] if result and len(result) > 0 else None
 
         # 处理识别结果
-        if result and len(result) > 0:
-            response = process_ocr_result(result[0], target_text)
-            item_count = len(response.get('items', []))
-            logger.info(f"OCR 识别完成: {response['message']}, 识别到 {item_count} 个文本块, 完整文字: {response['data']}")
-        else:
-            response = {"code": 200, "data": "", "items": [], "message": "没有识别到文字"}
+        response = process_ocr_result(lines, target_text, offset,
+                                      defaults['min_confidence'], defaults['max_edit_distance'])
+        if isinstance(queries, list) and queries:
+            response["results"] = process_queries(response.get('items', []), queries, width, height, defaults)
+        item_count = len(response.get('items', []))
+        logger.info(f"OCR 识别完成: {response['message']}, 识别到 {item_count} 个文本块, 完整文字: {response['data']}")
 
         return jsonify(response)
         
@@ -502,14 +735,18 @@ def index():
     """
     return jsonify({
         "service": "PaddleOCR HTTP API",
-        "version": "1.3.0",
+        "version": "1.4.0",
         "language": "中英文混合识别",
         "supported_languages": ["中文", "英文", "数字", "标点符号"],
         "features": [
             "文字识别",
             "坐标定位",
             "置信度评分",
-            "多文本块识别"
+            "多文本块识别",
+            "目标文字匹配",
+            "区域裁剪",
+            "语言提示",
+            "单帧多查询"
         ],
         "optimizations": [
             "多进程加速",
@@ -533,11 +770,27 @@ def index():
                     "box": "四个顶点坐标",
                     "position": "矩形边界框(left,top,right,bottom)"
                 }
-            ]
+            ],
+            "matches": "匹配目标文字的文本块（指定 target_text 时）",
+            "results": "每个查询的结果（指定 queries 时）"
         }
     })
 
+def parse_args():
+    """
+    解析命令行参数
+    
+    @description: 默认只监听本机；作为远程 OCR 主机时使用 --host 0.0.0.0
+    @return: args Namespace
+    """
+    parser = argparse.ArgumentParser(description="PaddleOCR HTTP 服务")
+    parser.add_argument('--host', default=os.environ.get('OCR_HOST', '127.0.0.1'), help="监听地址（默认 127.0.0.1）")
+    parser.add_argument('--port', type=int, default=int(os.environ.get('OCR_PORT', '1224')), help="监听端口（默认 1224）")
+    return parser.parse_args()
+
 if __name__ == '__main__':
+    args = parse_args()
+
     # 获取并设置工作目录为脚本所在目录
     try:
         script_dir = get_script_directory()
@@ -560,12 +813,12 @@ if __name__ == '__main__':
     
     # 启动 Flask 服务
     logger.info("="*60)
-    logger.info("Flask 服务监听: http://127.0.0.1:1224")
+    logger.info(f"Flask 服务监听: http://{args.host}:{args.port}")
     logger.info("="*60)
     
     app.run(
-        host='127.0.0.1',
-        port=1224,
+        host=args.host,
+        port=args.port,
         debug=False,
         threaded=True
     )
@@ -0,0 +1,34 @@
+// ocr_bundle 生成 OCR 环境离线安装包
+//
+// 在可以联网的机器上运行：
+//
+//	go run ./cmd/ocr_bundle -python python3 -out ocr_bundle.zip
+//
+// 安装包包含嵌入式 Python、get-pip.py、全部 wheel、识别模型以及固定了哈希的清单和锁文件。
+// 将 ocr_bundle.zip 放到无法联网的机器的程序目录下，首次启动时会解压到 wheelhouse 目录并离线安装。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	_const "qq_client/internal/const"
+	"qq_client/internal/ocrenv"
+)
+
+func main() {
+	manifestPath := flag.String("manifest", "assets/"+_const.OCRManifestFile, "清单文件路径（固定的哈希会写回）")
+	out := flag.String("out", _const.OCRBundleFile, "输出的安装包路径")
+	var opts ocrenv.PinOptions
+	flag.StringVar(&opts.Python, "python", "python", "用于运行 pip download 的 Python")
+	flag.StringVar(&opts.Wheelhouse, "wheelhouse", _const.OCRWheelhouseDir, "下载目录")
+	flag.StringVar(&opts.Platform, "platform", "win_amd64", "目标平台，为空表示当前平台（可包含源码包）")
+	flag.StringVar(&opts.PythonVersion, "python-version", "3.12", "目标 Python 版本")
+	flag.StringVar(&opts.Index, "index", "", "pip 索引，为空时使用清单中的第一个索引")
+	flag.Parse()
+
+	if err := ocrenv.BuildBundle(*manifestPath, *out, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "生成离线安装包失败: %v\n", err)
+		os.Exit(1)
+	}
+}
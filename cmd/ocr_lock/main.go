@@ -0,0 +1,37 @@
+// ocr_lock 固定 OCR 环境清单中的哈希并生成 pip 锁文件
+//
+// 在可以联网的机器上运行：
+//
+//	go run ./cmd/ocr_lock -python python3
+//
+// 下载清单中的嵌入式 Python、get-pip.py 和模型并写入 SHA-256，
+// 将 ocr_requirements.in 的全部依赖下载到 wheelhouse 目录并生成带 --hash 的锁文件，
+// 同时将 Linux 依赖下载到 wheelhouse/linux 并生成 Linux 锁文件。
+// 生成的 wheelhouse 目录可直接复制到无法联网的机器上离线安装。
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	_const "qq_client/internal/const"
+	"qq_client/internal/ocrenv"
+)
+
+func main() {
+	manifestPath := flag.String("manifest", "assets/"+_const.OCRManifestFile, "清单文件路径")
+	var opts ocrenv.PinOptions
+	flag.StringVar(&opts.Python, "python", "python", "用于运行 pip download 的 Python")
+	flag.StringVar(&opts.Wheelhouse, "wheelhouse", _const.OCRWheelhouseDir, "下载目录")
+	flag.StringVar(&opts.Platform, "platform", "win_amd64", "目标平台，为空表示当前平台（可包含源码包）")
+	flag.StringVar(&opts.PythonVersion, "python-version", "3.12", "目标 Python 版本")
+	flag.StringVar(&opts.Index, "index", "", "pip 索引，为空时使用清单中的第一个索引")
+	flag.StringVar(&opts.LinuxPlatform, "linux-platform", "manylinux2014_x86_64", "同时生成 Linux 锁文件的平台，为空时不生成")
+	flag.StringVar(&opts.LinuxPythonVersion, "linux-python-version", "", "Linux 主机的 Python 版本，为空时与 -python-version 相同")
+	flag.Parse()
+
+	if _, err := ocrenv.PinManifest(*manifestPath, opts); err != nil {
+		fmt.Fprintf(os.Stderr, "生成失败: %v\n", err)
+		os.Exit(1)
+	}
+}
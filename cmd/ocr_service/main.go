@@ -0,0 +1,113 @@
+// ocr_service 在 Linux 主机上安装并前台运行 OCR 服务
+//
+// 首次运行时提取 ocr_server.py 和环境清单，使用系统 Python 创建虚拟环境并按锁文件安装依赖：
+//
+//	go run ./cmd/ocr_service -dir /opt/ocr -config /opt/ocr/config.yaml
+//
+// 程序目录下有 wheelhouse 目录或 ocr_bundle.zip 时离线安装（依赖和识别模型均不联网）。
+// 服务在前台运行，输出写到标准输出，收到 SIGINT/SIGTERM 时停止服务后退出，适合由 systemd 管理。
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"qq_client/assets"
+	"qq_client/global"
+	_const "qq_client/internal/const"
+	"qq_client/internal/ocrenv"
+	"syscall"
+
+	"gopkg.in/yaml.v3"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "程序目录（提取脚本、创建虚拟环境和查找离线安装包）")
+	configPath := flag.String("config", "", "配置文件路径，读取其中的 ocr_host 和 ocr_port")
+	host := flag.String("host", "", "监听地址，覆盖配置文件")
+	port := flag.Int("port", 0, "监听端口，覆盖配置文件")
+	python := flag.String("python", "python3", "用于创建虚拟环境的 Python")
+	venv := flag.String("venv", "ocr_env", "虚拟环境目录（相对程序目录）")
+	setupOnly := flag.Bool("setup-only", false, "只安装环境，不启动服务")
+	flag.Parse()
+
+	if err := run(*dir, *configPath, *host, *port, *python, *venv, *setupOnly); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			fmt.Fprintf(os.Stderr, "OCR 服务已退出: %v\n", err)
+			os.Exit(exitErr.ExitCode())
+		}
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+// run 安装环境并运行服务
+func run(dir, configPath, host string, port int, python, venv string, setupOnly bool) error {
+	if configPath != "" {
+		data, err := os.ReadFile(configPath)
+		if err != nil {
+			return fmt.Errorf("读取配置文件失败: %v", err)
+		}
+		var cfg global.Config
+		if err = yaml.Unmarshal(data, &cfg); err != nil {
+			return fmt.Errorf("解析配置文件失败: %v", err)
+		}
+		global.ApplyOCRServiceConfig(&cfg)
+	}
+	global.ApplyOCRServiceConfig(&global.Config{OCRHost: host, OCRPort: port})
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err = os.Chdir(dir); err != nil {
+		return err
+	}
+	if _, err = assets.Extract("."); err != nil {
+		return fmt.Errorf("提取 OCR 文件失败: %v", err)
+	}
+
+	localDir := ocrenv.LocalDir(_const.OCRWheelhouseDir, _const.OCRBundleFile)
+	manifest, err := ocrenv.LoadLocalManifest(localDir, _const.OCRManifestFile)
+	if err != nil {
+		return err
+	}
+	venvPython, err := manifest.EnsureVenv(venv, python, localDir)
+	if err != nil {
+		return err
+	}
+
+	// 离线环境安装识别模型；联网环境由 PaddleOCR 首次运行时自行下载
+	if localDir != "" {
+		if err = manifest.InstallModels(localDir, localDir); err != nil {
+			return fmt.Errorf("安装离线模型失败: %v", err)
+		}
+	}
+	if missing := manifest.MissingModels(); len(missing) > 0 {
+		fmt.Printf("[WARN] 以下模型尚未安装，服务首次识别时将尝试联网下载: %v\n", missing)
+	}
+	if setupOnly {
+		fmt.Println("OCR 环境安装完成")
+		return nil
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("OCR 服务监听 %s:%d\n", global.OCRServiceHost, global.OCRServicePort)
+	err = ocrenv.RunServer(ctx, venvPython, filepath.Join(dir, "ocr_server.py"), global.OCRServiceHost, global.OCRServicePort)
+	if ctx.Err() != nil {
+		// 收到退出信号后服务被中断，属于正常停止
+		fmt.Println("OCR 服务已停止")
+		return nil
+	}
+	return err
+}
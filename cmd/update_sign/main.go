@@ -0,0 +1,102 @@
+// update_sign 生成更新签名密钥并为发布文件签名
+//
+// 生成密钥（私钥保存到文件，公钥构建时通过 -ldflags 注入 qq_client/global.UpdatePublicKey）：
+//
+//	go run ./cmd/update_sign -gen -key update_key
+//
+// 为发布文件签名，输出 client_update 消息中需要的 version、sha256 和 signature：
+//
+//	go run ./cmd/update_sign -key update_key -version 1.5.0 -file scum_client.exe
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"qq_client/internal/selfupdate"
+	"strings"
+)
+
+func main() {
+	gen := flag.Bool("gen", false, "生成新的密钥对")
+	keyPath := flag.String("key", "update_key", "私钥文件（base64）")
+	version := flag.String("version", "", "发布版本（需要与构建时注入的 global.Version 一致）")
+	file := flag.String("file", "", "发布文件")
+	flag.Parse()
+
+	var err error
+	if *gen {
+		err = generate(*keyPath)
+	} else {
+		err = sign(*keyPath, *version, *file)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generate 生成密钥对，私钥写入文件（已存在时拒绝覆盖），公钥输出到标准输出
+func generate(keyPath string) error {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(keyPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return fmt.Errorf("写入私钥失败: %v", err)
+	}
+	if _, err = f.WriteString(base64.StdEncoding.EncodeToString(privateKey) + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("私钥已保存到 %s，请妥善保管\n", keyPath)
+	fmt.Printf("公钥: %s\n", base64.StdEncoding.EncodeToString(publicKey))
+	return nil
+}
+
+// sign 为发布文件签名
+func sign(keyPath, versionText, file string) error {
+	if versionText == "" || file == "" {
+		return fmt.Errorf("需要 -version 和 -file")
+	}
+	version, err := selfupdate.ParseVersion(versionText)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return fmt.Errorf("读取私钥失败: %v", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return fmt.Errorf("私钥 %s 格式无效", keyPath)
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	digest := sha256.Sum256(content)
+	sum := hex.EncodeToString(digest[:])
+
+	release := selfupdate.Release{
+		Version:   version.String(),
+		SHA256:    sum,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, selfupdate.SignedMessage(version, sum))),
+	}
+	output, err := json.MarshalIndent(release, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(output))
+	return nil
+}
@@ -16,14 +16,24 @@ const (
 	GameWindowHeight = 593 // 游戏窗口高度
 )
 
-const (
-	// OCR 服务相关常量
+// OCR 服务地址，默认为本机；配置 ocr_host/ocr_port 后由 ApplyOCRServiceConfig 覆盖
+var (
 	OCRServiceHost = "127.0.0.1" // OCR 服务主机地址
 	OCRServicePort = 1224        // OCR 服务端口号
 )
 
-// GameUIText 游戏界面文本多语言映射
-// 用于OCR识别时支持多种语言
+// ApplyOCRServiceConfig 使用配置中的 OCR 服务地址（未配置的字段保持默认值）
+func ApplyOCRServiceConfig(cfg *Config) {
+	if cfg.OCRHost != "" {
+		OCRServiceHost = cfg.OCRHost
+	}
+	if cfg.OCRPort > 0 {
+		OCRServicePort = cfg.OCRPort
+	}
+}
+
+// GameUIText 内置的游戏界面文本多语言映射
+// 用于OCR识别时支持多种语言，仅在 locales/ 目录下的字典文件缺失对应 KEY 时作为最后的回退
 // Key为英文文本，Value为所有支持的语言版本（包括英文和中文）
 var GameUIText = map[string][]string{
 	"MUTE":     {"MUTE", "静音"},
@@ -0,0 +1,64 @@
+package global
+
+import _const "qq_client/internal/const"
+
+// LayoutAnchor 界面锚点定义
+// 锚点可以是客户区内的相对位置（RelX/RelY，取值 0~1），
+// 也可以是相对某个 OCR 识别文本（Ref）的偏移；两者同时配置时优先使用 Ref，Ref 不可用时回退到相对位置
+type LayoutAnchor struct {
+	RelX     float64 `json:"rel_x" yaml:"rel_x"`         // 相对客户区宽度的位置 (0~1)
+	RelY     float64 `json:"rel_y" yaml:"rel_y"`         // 相对客户区高度的位置 (0~1)
+	Ref      string  `json:"ref" yaml:"ref"`             // 参照的文本 KEY（如 MUTE），为空表示只使用相对位置
+	RefPoint string  `json:"ref_point" yaml:"ref_point"` // 参照点: top_left, top_right, bottom_left, bottom_right, center
+	OffsetX  int     `json:"offset_x" yaml:"offset_x"`   // 相对参照点的X偏移（基准分辨率、UI缩放1.0下的像素）
+	OffsetY  int     `json:"offset_y" yaml:"offset_y"`   // 相对参照点的Y偏移（基准分辨率、UI缩放1.0下的像素）
+	Color    string  `json:"color" yaml:"color"`         // 期望颜色（十六进制 RRGGBB），用于颜色探测点
+}
+
+// LayoutWindow 游戏窗口位置和大小
+type LayoutWindow struct {
+	X      int `json:"x" yaml:"x"`
+	Y      int `json:"y" yaml:"y"`
+	Width  int `json:"width" yaml:"width"`
+	Height int `json:"height" yaml:"height"`
+}
+
+// LayoutProfile 界面布局配置
+// Width/Height 为采集锚点时的游戏客户区大小，用于自动选择布局和缩放偏移量
+type LayoutProfile struct {
+	Name    string                  `json:"name" yaml:"name"`
+	Width   int                     `json:"width" yaml:"width"`       // 基准客户区宽度
+	Height  int                     `json:"height" yaml:"height"`     // 基准客户区高度
+	UIScale float64                 `json:"ui_scale" yaml:"ui_scale"` // 游戏内UI缩放，0 表示 1.0
+	Window  *LayoutWindow           `json:"window" yaml:"window"`     // 窗口位置，为空时不调整窗口
+	Anchors map[string]LayoutAnchor `json:"anchors" yaml:"anchors"`
+}
+
+// DefaultLayoutProfile 默认布局
+// 对应 GameWindowWidth x GameWindowHeight 窗口（客户区 841x554，UI缩放 1.0）
+var DefaultLayoutProfile = LayoutProfile{
+	Name:    "default",
+	Width:   841,
+	Height:  554,
+	UIScale: 1,
+	Window: &LayoutWindow{
+		X:      GameWindowX,
+		Y:      GameWindowY,
+		Width:  GameWindowWidth,
+		Height: GameWindowHeight,
+	},
+	Anchors: map[string]LayoutAnchor{
+		// 加载界面白色图标探测点
+		_const.AnchorLoadingProbe1: {RelX: 427.0 / 841, RelY: 142.0 / 554, Color: "FFFFFF"},
+		_const.AnchorLoadingProbe2: {RelX: 438.0 / 841, RelY: 153.0 / 554, Color: "FFFFFF"},
+		// 登录界面机器人模式标识
+		_const.AnchorBotMode: {RelX: 97.0 / 841, RelY: 142.0 / 554, Color: "FFFFFF"},
+		// 聊天输入框（原屏幕坐标 82,319 换算为客户区坐标）
+		_const.AnchorChatInput: {RelX: 66.0 / 841, RelY: 257.0 / 554},
+		// 聊天模式颜色探测点（输入框背景色）
+		_const.AnchorChatModeProbe: {Ref: "MUTE", RefPoint: _const.RefPointTopRight, OffsetX: 100, OffsetY: 5},
+		// 聊天记录区域（输入框上方的消息列表，用于识别指令响应）
+		_const.AnchorChatLogTopLeft:     {RelX: 10.0 / 841, RelY: 60.0 / 554},
+		_const.AnchorChatLogBottomRight: {RelX: 420.0 / 841, RelY: 245.0 / 554},
+	},
+}
@@ -0,0 +1,32 @@
+package global
+
+// UIMatchPolicy 界面文本匹配策略
+// 决定一个 OCR 识别结果能否被认定为某个界面文本，数值为 0 的限制项表示不限制
+type UIMatchPolicy struct {
+	MinConfidence   float64 `json:"min_confidence" yaml:"min_confidence"`       // 最低识别置信度 (0~1)
+	MinLength       int     `json:"min_length" yaml:"min_length"`               // 识别文本规范化后的最少字符数
+	MaxEditDistance *int    `json:"max_edit_distance" yaml:"max_edit_distance"` // 允许的最大编辑距离，为空时按文本长度自动计算
+	MinWidth        int     `json:"min_width" yaml:"min_width"`                 // 文本区域最小宽度（像素）
+	MaxWidth        int     `json:"max_width" yaml:"max_width"`                 // 文本区域最大宽度（像素）
+	MinHeight       int     `json:"min_height" yaml:"min_height"`               // 文本区域最小高度（像素）
+	MaxHeight       int     `json:"max_height" yaml:"max_height"`               // 文本区域最大高度（像素）
+
+	Preprocess *OCRPreprocess `json:"preprocess" yaml:"preprocess"` // OCR 服务端预处理开关，为空时由服务端全部启用
+}
+
+// DefaultUIMatchPolicy 未单独配置的文本key使用的匹配策略
+var DefaultUIMatchPolicy = UIMatchPolicy{
+	MinConfidence: 0.6,
+	MinLength:     2,
+	MinHeight:     6,
+	MaxHeight:     80,
+}
+
+// DefaultUIMatchPolicies 内置的按文本key匹配策略
+// 配置文件 match_policies 中同名的策略会整体替换内置策略
+var DefaultUIMatchPolicies = map[string]UIMatchPolicy{
+	"MUTE":     {MinConfidence: 0.7, MinLength: 2, MinHeight: 6, MaxHeight: 60, MaxWidth: 200},
+	"GLOBAL":   {MinConfidence: 0.7, MinLength: 2, MinHeight: 6, MaxHeight: 60, MaxWidth: 250},
+	"LOCAL":    {MinConfidence: 0.7, MinLength: 2, MinHeight: 6, MaxHeight: 60, MaxWidth: 250},
+	"CONTINUE": {MinConfidence: 0.7, MinLength: 2, MinHeight: 6, MaxHeight: 80, MaxWidth: 400},
+}
@@ -0,0 +1,68 @@
+package global
+
+import "fmt"
+
+// DefaultWindowTitle SCUM 游戏窗口标题（类名为 UnrealWindow）
+const DefaultWindowTitle = "SCUM  "
+
+// DefaultClientToken 未配置 token 时使用的认证令牌
+const DefaultClientToken = "scum_client_token"
+
+// ServerProfile 一个游戏实例：后端服务器身份、对应的游戏窗口和窗口位置
+type ServerProfile struct {
+	Name        string        `json:"name" yaml:"name"`                   // 实例名称（日志前缀和状态上报），为空时为 server_<server_id>
+	ServerID    uint          `json:"server_id" yaml:"server_id"`         // 后端服务器 ID
+	ServerUrl   string        `json:"server_url" yaml:"server_url"`       // 后端地址，为空时使用顶层 server_url
+	Token       string        `json:"token" yaml:"token"`                 // WebSocket 认证令牌，为空时使用默认令牌
+	FtpProvider int           `json:"ftp_provider" yaml:"ftp_provider"`   // FTP提供商类型，认证成功后由后端更新
+	WindowPID   int32         `json:"window_pid" yaml:"window_pid"`       // 按进程号匹配游戏窗口，为 0 时按标题匹配
+	WindowTitle string        `json:"window_title" yaml:"window_title"`   // 按标题匹配游戏窗口，为空时为 "SCUM  "
+	Window      *LayoutWindow `json:"window" yaml:"window"`               // 窗口位置槽位，为空时使用布局中的窗口位置
+	GameLogPath string        `json:"game_log_path" yaml:"game_log_path"` // 该实例的游戏日志（游戏以 -log 参数指定了日志文件时），为空时使用顶层 game_log_path
+}
+
+// Profiles 配置中的游戏实例
+// @description: 未配置 servers 时使用顶层 server_id/server_url/ftp_provider 作为唯一实例（名称为空，日志不加前缀）；缺省字段填入默认值
+// @return: []ServerProfile, error 多个实例匹配同一个游戏窗口（相同的 window_pid，或都未配置 window_pid 且标题相同）
+func (c *Config) Profiles() ([]ServerProfile, error) {
+	if len(c.Servers) == 0 {
+		return []ServerProfile{{
+			ServerID:    c.ServerID,
+			ServerUrl:   c.ServerUrl,
+			Token:       DefaultClientToken,
+			FtpProvider: c.FtpProvider,
+			WindowTitle: DefaultWindowTitle,
+			GameLogPath: c.GameLogPath,
+		}}, nil
+	}
+	profiles := make([]ServerProfile, len(c.Servers))
+	windows := make(map[string]string) // 窗口匹配条件 -> 实例名称
+	for i, profile := range c.Servers {
+		if profile.Name == "" {
+			profile.Name = fmt.Sprintf("server_%d", profile.ServerID)
+		}
+		if profile.ServerUrl == "" {
+			profile.ServerUrl = c.ServerUrl
+		}
+		if profile.Token == "" {
+			profile.Token = DefaultClientToken
+		}
+		if profile.WindowTitle == "" {
+			profile.WindowTitle = DefaultWindowTitle
+		}
+		if profile.GameLogPath == "" {
+			profile.GameLogPath = c.GameLogPath
+		}
+
+		window := fmt.Sprintf("window_title %q", profile.WindowTitle)
+		if profile.WindowPID > 0 {
+			window = fmt.Sprintf("window_pid %d", profile.WindowPID)
+		}
+		if other, ok := windows[window]; ok {
+			return nil, fmt.Errorf("实例 %s 与 %s 匹配同一个游戏窗口（%s），请为每个实例配置不同的 window_pid 或 window_title", profile.Name, other, window)
+		}
+		windows[window] = profile.Name
+		profiles[i] = profile
+	}
+	return profiles, nil
+}
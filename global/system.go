@@ -6,12 +6,65 @@ type Config struct {
 	ServerID    uint   `json:"server_id" yaml:"server_id"`
 	ServerUrl   string `json:"server_url" yaml:"server_url"`
 	FtpProvider int    `json:"ftp_provider" yaml:"ftp_provider"` // FTP提供商类型: 1=GPORTAL, 2=PingPerfect, 3=自建服务器, 4=命令行服务器
+
+	UILanguage          string   `json:"ui_language" yaml:"ui_language"`                     // 游戏界面语言（如 en、zh、ru），为空时尝试所有语言
+	UIFallbackLanguages []string `json:"ui_fallback_languages" yaml:"ui_fallback_languages"` // 界面语言回退链，为空时回退到 en
+
+	MatchPolicies map[string]UIMatchPolicy `json:"match_policies" yaml:"match_policies"` // 按文本key配置的匹配策略，"default" 为默认策略
+
+	Layout  string          `json:"layout" yaml:"layout"`   // 指定使用的布局名称，为空时按客户区大小自动选择
+	Layouts []LayoutProfile `json:"layouts" yaml:"layouts"` // 自定义布局列表
+
+	OCRLanguage string `json:"ocr_language" yaml:"ocr_language"` // OCR 语言提示（PaddleOCR 语言代码，如 en、korean），为空时使用服务默认模型
+	OCRHost     string `json:"ocr_host" yaml:"ocr_host"`         // OCR 服务地址，为空时使用本机；非本机地址时不启动本地 OCR 服务
+	OCRPort     int    `json:"ocr_port" yaml:"ocr_port"`         // OCR 服务端口，为 0 时使用 1224
+	// 使用旧的批处理脚本安装 OCR 环境（联网安装，不校验哈希），默认按 ocr_manifest.json 安装并校验
+	OCRLegacySetup bool `json:"ocr_legacy_setup" yaml:"ocr_legacy_setup"`
+
+	LogLevel string `json:"log_level" yaml:"log_level"` // 启动时的日志级别 debug/info/error，为空时为 debug（服务端远程修改后以 control.LogLevelName 为准）
+
+	ResponseCapture string `json:"response_capture" yaml:"response_capture"` // 指令响应获取方式 log/ocr/both/off，为空时自动选择
+	GameLogPath     string `json:"game_log_path" yaml:"game_log_path"`       // 游戏日志路径，为空时为 %LOCALAPPDATA%\SCUM\Saved\Logs\SCUM.log
+	// 游戏日志中管理员指令回显和输出所在的日志分类（如 LogSCUM），为空时为 LogSCUM
+	GameLogCategories []string `json:"game_log_categories" yaml:"game_log_categories"`
+
+	Servers []ServerProfile `json:"servers" yaml:"servers"` // 同一进程驱动的多个游戏实例，为空时使用 server_id/server_url
+}
+
+// OCRRect OCR 请求中的矩形区域（窗口客户区坐标）
+type OCRRect struct {
+	Left   int `json:"left"`
+	Top    int `json:"top"`
+	Right  int `json:"right"`
+	Bottom int `json:"bottom"`
+}
+
+// OCRPreprocess 服务端预处理开关，请求中未指定时全部启用
+type OCRPreprocess struct {
+	Sharpen     bool `json:"sharpen" yaml:"sharpen"`           // 锐化（UnsharpMask）
+	Contrast    bool `json:"contrast" yaml:"contrast"`         // 对比度增强
+	EdgeEnhance bool `json:"edge_enhance" yaml:"edge_enhance"` // 边缘增强
+}
+
+// OCRQuery 在同一帧识别结果上执行的查询
+type OCRQuery struct {
+	ID              string   `json:"id"`
+	TargetText      []string `json:"target_text,omitempty"`       // 目标文字，任一匹配即可
+	Crop            *OCRRect `json:"crop,omitempty"`              // 只考虑中心点在该区域内的文本块
+	MinConfidence   *float64 `json:"min_confidence,omitempty"`    // 为空时使用请求的设置
+	MaxEditDistance *int     `json:"max_edit_distance,omitempty"` // 为空时使用请求的设置
 }
 
-// OCRRequest 定义请求结构
+// OCRRequest 定义请求结构（图片之外的字段均可选）
 type OCRRequest struct {
-	Base64  string                 `json:"base64"`
-	Options map[string]interface{} `json:"options"`
+	Image           string         `json:"image"`                       // Base64 图片
+	TargetText      []string       `json:"target_text,omitempty"`       // 目标文字，由服务端匹配，任一匹配即 code=100
+	Crop            *OCRRect       `json:"crop,omitempty"`              // 服务端只识别该区域，返回坐标仍为整帧坐标
+	Lang            string         `json:"lang,omitempty"`              // 语言提示（PaddleOCR 语言代码）
+	Preprocess      *OCRPreprocess `json:"preprocess,omitempty"`        // 预处理开关
+	MinConfidence   float64        `json:"min_confidence,omitempty"`    // 服务端匹配的最低置信度
+	MaxEditDistance int            `json:"max_edit_distance,omitempty"` // 服务端匹配允许的编辑距离
+	Queries         []OCRQuery     `json:"queries,omitempty"`           // 同一帧上的多个查询
 }
 
 // OCRResponse 定义响应结构
@@ -0,0 +1,9 @@
+package global
+
+// 构建信息，发布时通过 -ldflags 注入：
+//
+//	go build -ldflags "-X qq_client/global.Version=1.4.0 -X qq_client/global.UpdatePublicKey=<base64>"
+var (
+	Version         = "0.0.0-dev" // 程序版本（语义化版本）
+	UpdatePublicKey = ""          // 更新签名公钥（ed25519，base64），为空时拒绝所有自动更新
+)
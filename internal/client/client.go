@@ -5,10 +5,14 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"path/filepath"
 	"qq_client/global"
+	_const "qq_client/internal/const"
+	"qq_client/internal/control"
+	"qq_client/internal/selfupdate"
+	"qq_client/internal/status"
 	"qq_client/internal/websocket_client"
 	"qq_client/model/request"
-	"qq_client/util"
 	"sync"
 	"time"
 )
@@ -16,29 +20,34 @@ import (
 // Client represents the SCUM Client
 type Client struct {
 	config   *global.Config
+	profile  *global.ServerProfile // 实例配置，多实例时每个实例一个 Client
+	status   *status.Tracker
+	control  *control.Controls
 	wsClient *websocket_client.Client
+	handlers *websocket_client.Handlers
 	ctx      context.Context
 	cancel   context.CancelFunc
 	wg       sync.WaitGroup
 }
 
-// Message types for WebSocket communication
-const (
-	MsgTypeAuth         = "client_auth"      // 与后端保持一致
-	MsgTypeHeartbeat    = "client_heartbeat" // 与后端保持一致
-	MsgTypeClientUpdate = "client_update"
-	MsgTypeClientStatus = "client_status"
-)
-
 // New creates a new SCUM Client
-func New(cfg *global.Config) *Client {
+// @param: cfg *global.Config 全局配置
+// @param: profile *global.ServerProfile 实例配置（后端身份和游戏窗口，与主循环共用）
+// @param: tracker *status.Tracker 实例状态，由 client_status 上报
+// @param: controls *control.Controls 实例的远程控制
+func New(cfg *global.Config, profile *global.ServerProfile, tracker *status.Tracker, controls *control.Controls) *Client {
 	ctx, cancel := context.WithCancel(context.Background())
 
 	client := &Client{
-		config: cfg,
-		ctx:    ctx,
-		cancel: cancel,
+		config:   cfg,
+		profile:  profile,
+		status:   tracker,
+		control:  controls,
+		handlers: websocket_client.NewHandlers(),
+		ctx:      ctx,
+		cancel:   cancel,
 	}
+	client.registerHandlers()
 
 	return client
 }
@@ -46,7 +55,7 @@ func New(cfg *global.Config) *Client {
 // Start starts the client
 func (c *Client) Start() error {
 	// Connect to WebSocket server
-	u, err := url.Parse(c.config.ServerUrl)
+	u, err := url.Parse(c.profile.ServerUrl)
 	if err != nil {
 		return fmt.Errorf("invalid server address: %w", err)
 	}
@@ -62,18 +71,21 @@ func (c *Client) Start() error {
 	// 创建WebSocket客户端（使用简化的logger）
 	wsClient := websocket_client.New(u.String(), nil)
 
-	// 设置重连回调
+	// 设置连接回调（每次连接成功，包括重连，都会调用 onConnect）
 	wsClient.SetCallbacks(
 		func() {
 			// 连接成功后自动发送认证
-			authMsg := request.WebSocketMessage{
-				Type: MsgTypeAuth,
-				Data: map[string]interface{}{
-					"server_id": c.config.ServerID,
-					"token":     "scum_client_token", // 这里应该使用实际的token
-				},
+			auth := request.AuthRequest{
+				ServerID: c.profile.ServerID,
+				Token:    c.profile.Token,
+				Version:  global.Version,
 			}
-			if err = wsClient.SendMessage(authMsg); err != nil {
+			// 认证只对当前连接有效，不经过发送队列；认证成功后才发送队列中的消息
+			msg, err := request.NewWebSocketMessage(request.MsgTypeAuth, auth, "")
+			if err == nil {
+				err = wsClient.SendDirect(msg)
+			}
+			if err != nil {
 				fmt.Printf("Failed to send authentication: %v\n", err)
 			}
 		},
@@ -81,17 +93,8 @@ func (c *Client) Start() error {
 			fmt.Println("WebSocket disconnected")
 		},
 		func() {
-			// 重连成功后重新发送认证
-			authMsg := request.WebSocketMessage{
-				Type: MsgTypeAuth,
-				Data: map[string]interface{}{
-					"server_id": c.config.ServerID,
-					"token":     "scum_client_token", // 这里应该使用实际的token
-				},
-			}
-			if err = wsClient.SendMessage(authMsg); err != nil {
-				fmt.Printf("Failed to send re-authentication: %v\n", err)
-			}
+			fmt.Println("WebSocket reconnected")
+			c.status.AddRestart(status.RestartWebSocket)
 		},
 	)
 
@@ -103,12 +106,18 @@ func (c *Client) Start() error {
 	c.wsClient = wsClient
 
 	// Start message handler
-	c.wg.Add(1)
+	c.wg.Add(2)
 	go c.handleMessages()
+	go c.reportStatus()
 
 	return nil
 }
 
+// ReportUpdateCompleted 上报更新完成（更新后的试运行全部检查通过时调用；新进程中已没有原始请求，作为独立消息上报）
+func (c *Client) ReportUpdateCompleted() {
+	c.sendUpdateStatus(request.WebSocketMessage{Type: request.MsgTypeClientUpdate}, selfupdate.Release{Version: global.Version}, _const.UpdateStatusCompleted, "Update completed, health checks passed", "")
+}
+
 // Stop stops the client
 func (c *Client) Stop() {
 	fmt.Println("Stopping SCUM Client...")
@@ -129,56 +138,49 @@ func (c *Client) handleMessages() {
 	defer c.wg.Done()
 
 	for {
-		select {
-		case <-c.ctx.Done():
+		// 断线重连期间 ReadMessage 继续等待，客户端关闭后返回错误
+		msg, err := c.wsClient.ReadMessage()
+		if err != nil {
 			return
-		default:
-			// 检查WebSocket客户端是否仍然连接
-			if !c.wsClient.IsConnected() {
-				time.Sleep(2 * time.Second)
-				continue
-			}
-
-			var msg request.WebSocketMessage
-			if err := c.wsClient.ReadMessage(&msg); err != nil {
-				// 连接断开，等待重连
-				time.Sleep(2 * time.Second)
-				continue
-			}
-
-			c.handleMessage(msg)
 		}
+		c.handleMessage(msg)
 	}
 }
 
+// registerHandlers 注册服务端消息的处理函数（载荷类型见 model/request 消息目录）
+func (c *Client) registerHandlers() {
+	websocket_client.Handle(c.handlers, request.MsgTypeAuth, c.handleAuthResponse)
+	websocket_client.Handle(c.handlers, request.MsgTypeHeartbeat, c.handleHeartbeat)
+	websocket_client.Handle(c.handlers, request.MsgTypeClientUpdate, c.handleClientUpdate)
+	websocket_client.Handle(c.handlers, request.MsgTypeScreenshot, c.handleScreenshot)
+	websocket_client.Handle(c.handlers, request.MsgTypeLogTail, c.handleLogTail)
+	websocket_client.Handle(c.handlers, request.MsgTypeConfig, c.handleConfigDump)
+	websocket_client.Handle(c.handlers, request.MsgTypeTextCache, c.handleTextCacheDump)
+	websocket_client.Handle(c.handlers, request.MsgTypeControl, c.handleControl)
+}
+
 // handleMessage handles a single WebSocket message
 func (c *Client) handleMessage(msg request.WebSocketMessage) {
-	fmt.Printf("Received WebSocket message: type=%s\n", msg.Type)
-
-	switch msg.Type {
-	case MsgTypeAuth:
-		c.handleAuthResponse(msg)
-	case MsgTypeHeartbeat:
-		// Heartbeat messages from server are handled silently
-		c.handleHeartbeat(msg)
-	case MsgTypeClientUpdate:
-		c.handleClientUpdate(msg.Data)
-	default:
-		fmt.Printf("Unknown message type: %s\n", msg.Type)
+	fmt.Printf("Received WebSocket message: type=%s id=%s\n", msg.Type, msg.ID)
+
+	if err := c.handlers.Dispatch(msg); err != nil {
+		fmt.Printf("Failed to handle message: %v\n", err)
 	}
 }
 
 // handleAuthResponse handles authentication response
-func (c *Client) handleAuthResponse(msg request.WebSocketMessage) {
+func (c *Client) handleAuthResponse(msg request.WebSocketMessage, data request.AuthResponse) {
 	if msg.Success {
 		fmt.Println("Authentication successful")
+		// 开始发送（补发）发送队列中的消息
+		c.wsClient.MarkReady()
+		c.sendStatus()
+		selfupdate.MarkHealthy(selfupdate.CheckAuth)
 
 		// 从响应中获取服务器类型并保存到配置
-		if data, ok := msg.Data.(map[string]interface{}); ok {
-			if ftpProvider, ok := data["ftp_provider"].(float64); ok {
-				c.config.FtpProvider = int(ftpProvider)
-				fmt.Printf("Server FTP Provider type saved: %d\n", c.config.FtpProvider)
-			}
+		if data.FtpProvider != 0 {
+			c.profile.FtpProvider = data.FtpProvider
+			fmt.Printf("Server FTP Provider type saved: %d\n", c.profile.FtpProvider)
 		}
 	} else {
 		fmt.Printf("Authentication failed: %s\n", msg.Error)
@@ -186,123 +188,97 @@ func (c *Client) handleAuthResponse(msg request.WebSocketMessage) {
 }
 
 // handleHeartbeat handles heartbeat message
-func (c *Client) handleHeartbeat(msg request.WebSocketMessage) {
+func (c *Client) handleHeartbeat(msg request.WebSocketMessage, _ request.Heartbeat) {
 	// 回应心跳
-	response := request.WebSocketMessage{
-		Type:    MsgTypeHeartbeat,
-		Success: true,
-		Data: map[string]interface{}{
-			"timestamp": time.Now().Unix(),
-		},
-	}
-	c.wsClient.SendMessage(response)
+	c.reply(msg, request.Heartbeat{Timestamp: time.Now().Unix()}, "", websocket_client.SendOptions{
+		Priority: websocket_client.PriorityLow,
+		TTL:      _const.HeartbeatInterval,
+	})
 }
 
 // handleClientUpdate handles client update request
-func (c *Client) handleClientUpdate(data interface{}) {
+// 更新状态都回复到该请求（reply_to 为请求的 id），服务端据此区分不同的更新请求
+func (c *Client) handleClientUpdate(msg request.WebSocketMessage, updateData request.ClientUpdateRequest) {
 	fmt.Println("🔄 Received update request")
 
-	updateData, ok := data.(map[string]interface{})
-	if !ok {
-		fmt.Println("❌ Invalid update request data format")
-		return
+	action, updateType := updateData.Action, updateData.Type
+	release := selfupdate.Release{
+		Version:     updateData.Version,
+		DownloadURL: updateData.DownloadURL,
+		SHA256:      updateData.SHA256,
+		Signature:   updateData.Signature,
 	}
 
-	action, _ := updateData["action"].(string)
-	updateType, _ := updateData["type"].(string)
-	downloadURL, _ := updateData["download_url"].(string)
-
-	fmt.Printf("📋 Update request details - Action: %s, Type: %s, DownloadURL: %s\n", action, updateType, downloadURL)
+	fmt.Printf("📋 Update request details - Action: %s, Type: %s, Version: %s, DownloadURL: %s\n", action, updateType, release.Version, release.DownloadURL)
 
 	if action == "update" && updateType == "self_update" {
 		fmt.Println("✅ Starting self-update process...")
 
 		// 发送更新开始状态
-		c.sendResponse(MsgTypeClientUpdate, map[string]interface{}{
-			"type":   "self_update",
-			"status": "starting",
-		}, "")
+		c.sendUpdateStatus(msg, release, _const.UpdateStatusStarting, "", "")
 
 		// 启动自我更新流程
-		go c.performSelfUpdate(updateData)
+		go c.performSelfUpdate(msg, release)
 	} else {
 		fmt.Printf("⚠️ Invalid update request - Action: %s, Type: %s\n", action, updateType)
 	}
 }
 
 // performSelfUpdate performs the self-update process
-func (c *Client) performSelfUpdate(updateData map[string]interface{}) {
+// 版本必须高于当前版本，更新文件必须带有内置公钥可验证的签名，下载后校验 SHA-256，任一步失败都拒绝更新
+func (c *Client) performSelfUpdate(update request.WebSocketMessage, release selfupdate.Release) {
 	fmt.Println("🚀 Performing self-update...")
 
-	// 发送更新状态
-	c.sendResponse(MsgTypeClientUpdate, map[string]interface{}{
-		"type":    "self_update",
-		"status":  "checking",
-		"message": "Checking for updates...",
-	}, "")
-
-	// 获取下载链接
-	downloadURL, _ := updateData["download_url"].(string)
-
-	if downloadURL == "" {
-		fmt.Println("❌ No download URL provided in update request")
-		c.sendResponse(MsgTypeClientUpdate, map[string]interface{}{
-			"type":    "self_update",
-			"status":  "no_update",
-			"message": "No download URL provided",
-		}, "")
+	// 检查版本和签名
+	c.sendUpdateStatus(update, release, _const.UpdateStatusChecking, "Checking version and signature...", "")
+	version, err := release.Check(global.Version, global.UpdatePublicKey)
+	if err != nil {
+		c.rejectUpdate(update, release, err)
 		return
 	}
 
-	fmt.Printf("📥 Download URL: %s\n", downloadURL)
+	// 下载并校验哈希
+	c.sendUpdateStatus(update, release, _const.UpdateStatusDownloading, "Downloading update...", "")
+	updateFile, err := release.Download(c.ctx, version, _const.UpdateTempDir)
+	if err != nil {
+		c.rejectUpdate(update, release, err)
+		return
+	}
+	c.sendUpdateStatus(update, release, _const.UpdateStatusVerifying, "Checksum and signature verified", "")
+	fmt.Printf("✅ Update %s verified: %s\n", version, updateFile)
 
-	// 准备外部更新器
+	// 准备更新器
 	currentExe, err := os.Executable()
+	if err == nil {
+		updateFile, err = filepath.Abs(updateFile)
+	}
 	if err != nil {
 		fmt.Printf("❌ Failed to get executable path: %v\n", err)
-		c.sendResponse(MsgTypeClientUpdate, map[string]interface{}{
-			"type":    "self_update",
-			"status":  "failed",
-			"message": fmt.Sprintf("Failed to get executable path: %v", err),
-		}, "")
+		c.rejectUpdate(update, release, err)
 		return
 	}
 
 	fmt.Printf("📂 Current executable path: %s\n", currentExe)
 
-	updateConfig := util.ExternalUpdaterConfig{
-		CurrentExePath: currentExe,
-		UpdateURL:      downloadURL,
-		Args:           []string{}, // 可以从配置中获取启动参数
-	}
-
-	// 发送更新状态并启动外部更新器
-	c.sendResponse(MsgTypeClientUpdate, map[string]interface{}{
-		"type":    "self_update",
-		"status":  "downloading",
-		"message": "Starting external updater...",
-	}, "")
-
-	// 启动外部更新器
-	fmt.Println("🔧 Starting external updater...")
-	if err := util.ExecuteExternalUpdate(updateConfig); err != nil {
-		fmt.Printf("❌ Failed to start external updater: %v\n", err)
-		c.sendResponse(MsgTypeClientUpdate, map[string]interface{}{
-			"type":    "self_update",
-			"status":  "failed",
-			"message": fmt.Sprintf("Failed to start updater: %v", err),
-		}, "")
+	// 启动更新器（当前程序的副本，在本进程退出后替换程序并重启）
+	fmt.Println("🔧 Starting updater...")
+	if err := selfupdate.LaunchApply(selfupdate.ApplyOptions{
+		Target:      currentExe,
+		Source:      updateFile,
+		SHA256:      release.SHA256,
+		Version:     version.String(),
+		FromVersion: global.Version,
+		Args:        os.Args[1:],
+	}); err != nil {
+		fmt.Printf("❌ Failed to start updater: %v\n", err)
+		c.rejectUpdate(update, release, fmt.Errorf("failed to start updater: %w", err))
 		return
 	}
 
-	fmt.Println("✅ External updater started successfully, shutting down current process...")
+	fmt.Println("✅ Updater started successfully, shutting down current process...")
 
 	// 发送最终状态
-	c.sendResponse(MsgTypeClientUpdate, map[string]interface{}{
-		"type":    "self_update",
-		"status":  "installing",
-		"message": "Updater started, shutting down for update...",
-	}, "")
+	c.sendUpdateStatus(update, release, _const.UpdateStatusInstalling, "Updater started, shutting down for update...", "")
 
 	// 延迟一段时间让消息发送完成，然后退出让更新器接管
 	go func() {
@@ -325,16 +301,115 @@ func (c *Client) performSelfUpdate(updateData map[string]interface{}) {
 	}()
 }
 
-// sendResponse sends a response message
-func (c *Client) sendResponse(msgType string, data interface{}, errorMsg string) {
-	msg := request.WebSocketMessage{
-		Type:    msgType,
-		Data:    data,
-		Success: errorMsg == "",
-		Error:   errorMsg,
+// reportStatus 定时上报 client_status，状态变化时立即上报（两次上报间隔不少于 StatusMinInterval）
+func (c *Client) reportStatus() {
+	defer c.wg.Done()
+
+	ticker := time.NewTicker(_const.StatusReportInterval)
+	defer ticker.Stop()
+	var lastReport time.Time
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		case <-c.status.Changes():
+			if wait := _const.StatusMinInterval - time.Since(lastReport); wait > 0 {
+				select {
+				case <-c.ctx.Done():
+					return
+				case <-time.After(wait):
+				}
+			}
+		}
+		c.sendStatus()
+		lastReport = time.Now()
+	}
+}
+
+// sendStatus 发送 client_status（断线期间进入发送队列，过期的状态不再补发）
+func (c *Client) sendStatus() {
+	snapshot := c.status.Snapshot()
+	stats := c.wsClient.QueueStats()
+	snapshot.SendQueue = request.StatusQueue{Queued: stats.Queued, Dropped: stats.Dropped, Expired: stats.Expired}
+
+	msg, err := request.NewWebSocketMessage(request.MsgTypeClientStatus, snapshot, "")
+	if err == nil {
+		err = c.wsClient.QueueMessage(msg, websocket_client.SendOptions{
+			Priority: websocket_client.PriorityLow,
+			TTL:      _const.StatusReportInterval,
+		})
+	}
+	if err != nil {
+		fmt.Printf("Failed to send client status: %v\n", err)
+	}
+}
+
+// ReportUpdateResult 上报上一次更新的结果（如新版本未通过健康检查被回滚）
+// @description: 放入发送队列，认证成功后发送；结果文件由调用方在所有客户端都放入队列后删除
+// @param: result *selfupdate.Result 待上报的结果
+// @return: error 无法放入发送队列
+func (c *Client) ReportUpdateResult(result *selfupdate.Result) error {
+	fmt.Printf("⚠️ Reporting update result: %s (%s -> %s): %s\n", result.Status, result.FromVersion, result.Version, result.Reason)
+	status := request.ClientUpdateStatus{
+		Type:           "self_update",
+		Status:         result.Status,
+		Version:        result.Version,
+		CurrentVersion: global.Version,
+		Reason:         result.Reason,
+		Time:           result.Time.Unix(),
+	}
+	errorMsg := ""
+	if result.Status == _const.UpdateStatusRolledBack {
+		errorMsg = result.Reason
 	}
+	msg, err := request.NewWebSocketMessage(request.MsgTypeClientUpdate, status, errorMsg)
+	if err == nil {
+		err = c.wsClient.QueueMessage(msg, updateStatusOptions)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to report update result: %w", err)
+	}
+	return nil
+}
+
+// rejectUpdate 上报更新失败：校验未通过为 rejected（附带失败原因），其他错误为 failed
+func (c *Client) rejectUpdate(update request.WebSocketMessage, release selfupdate.Release, err error) {
+	status := _const.UpdateStatusFailed
+	reason := selfupdate.Reason(err)
+	if reason != "" && reason != selfupdate.ReasonDownloadFailed {
+		status = _const.UpdateStatusRejected
+	}
+	fmt.Printf("❌ Self-update %s: %v\n", status, err)
+	c.sendUpdateStatus(update, release, status, err.Error(), reason)
+}
+
+// sendUpdateStatus 发送 client_update 状态消息（回复 update 请求）
+func (c *Client) sendUpdateStatus(update request.WebSocketMessage, release selfupdate.Release, status, message, reason string) {
+	data := request.ClientUpdateStatus{
+		Type:           "self_update",
+		Status:         status,
+		Version:        release.Version,
+		CurrentVersion: global.Version,
+		Message:        message,
+		Reason:         reason,
+	}
+	errorMsg := ""
+	if status == _const.UpdateStatusFailed || status == _const.UpdateStatusRejected {
+		errorMsg = message
+	}
+	c.reply(update, data, errorMsg, updateStatusOptions)
+}
+
+// updateStatusOptions 更新状态优先发送，断线期间保留到重新认证后补发
+var updateStatusOptions = websocket_client.SendOptions{
+	Priority: websocket_client.PriorityHigh,
+	TTL:      _const.UpdateStatusTTL,
+}
 
-	if err := c.wsClient.SendMessage(msg); err != nil {
+// reply 回复服务端消息（经发送队列发送）
+func (c *Client) reply(to request.WebSocketMessage, data interface{}, errorMsg string, opts websocket_client.SendOptions) {
+	if err := c.wsClient.Reply(to, data, errorMsg, opts); err != nil {
 		fmt.Printf("Failed to send response: %v\n", err)
 	}
 }
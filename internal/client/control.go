@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	_const "qq_client/internal/const"
+	"qq_client/internal/control"
+	"qq_client/internal/websocket_client"
+	"qq_client/model/request"
+	"qq_client/util"
+)
+
+// controlOptions 控制结果优先发送，过期后不再补发
+var controlOptions = websocket_client.SendOptions{
+	Priority: websocket_client.PriorityHigh,
+	TTL:      _const.ControlResultTTL,
+}
+
+// handleControl handles remote control request
+// 在后台执行（重启游戏需要等待主循环，重启 OCR 服务需要等待服务就绪），完成后回复执行结果
+func (c *Client) handleControl(msg request.WebSocketMessage, req request.ControlRequest) {
+	fmt.Printf("🎛️ Received control request: %s\n", req.Action)
+	go func() {
+		message, err := c.performControl(req)
+		result := request.ControlResult{Action: req.Action, Message: message}
+		errorMsg := ""
+		if err != nil {
+			fmt.Printf("❌ Control %s failed: %v\n", req.Action, err)
+			errorMsg = err.Error()
+		} else {
+			fmt.Printf("✅ Control %s: %s\n", req.Action, message)
+		}
+		c.reply(msg, result, errorMsg, controlOptions)
+	}()
+}
+
+// performControl 执行控制操作
+// @return: string 执行结果说明, error
+func (c *Client) performControl(req request.ControlRequest) (string, error) {
+	switch req.Action {
+	case _const.ControlPauseChat:
+		wasPaused := c.control.Pause()
+		c.status.SetPaused(true)
+		if wasPaused {
+			return "Chat monitor already paused", nil
+		}
+		return "Chat monitor paused", nil
+
+	case _const.ControlResumeChat:
+		wasPaused := c.control.Resume()
+		c.status.SetPaused(false)
+		if !wasPaused {
+			return "Chat monitor was not paused", nil
+		}
+		return "Chat monitor resumed", nil
+
+	case _const.ControlRestartGame:
+		ctx, cancel := context.WithTimeout(c.ctx, _const.ControlRestartTimeout)
+		defer cancel()
+		if err := c.control.RequestGameRestart(ctx); err != nil {
+			return "", err
+		}
+		return "Game process terminated, the main loop will relaunch it", nil
+
+	case _const.ControlRestartOCR:
+		if err := util.RestartOCRService(); err != nil {
+			return "", err
+		}
+		return "OCR service restarted", nil
+
+	case _const.ControlClearTextCache:
+		util.ClearTextPositionCache()
+		if err := util.SaveTextPositionCache(); err != nil {
+			return "", fmt.Errorf("text position cache cleared but not saved: %w", err)
+		}
+		return "Text position cache cleared", nil
+
+	case _const.ControlReplaceGameConfig:
+		if err := util.ReplaceSCUMConfig(); err != nil {
+			return "", err
+		}
+		return "SCUM config replaced, takes effect after the game restarts", nil
+
+	case _const.ControlSetLogLevel:
+		if err := control.SetLogLevel(req.Level); err != nil {
+			return "", err
+		}
+		return "Log level set to " + control.LogLevelName(), nil
+
+	default:
+		return "", fmt.Errorf("unknown control action: %s", req.Action)
+	}
+}
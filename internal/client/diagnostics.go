@@ -0,0 +1,288 @@
+package client
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"qq_client/global"
+	_const "qq_client/internal/const"
+	"qq_client/internal/websocket_client"
+	"qq_client/model/request"
+	"qq_client/util"
+	"strings"
+)
+
+// diagnosticsOptions 诊断结果过期后不再补发（服务端已不再等待）
+var diagnosticsOptions = websocket_client.SendOptions{
+	Priority: websocket_client.PriorityNormal,
+	TTL:      _const.DiagnosticsTTL,
+}
+
+// secretKeyParts 配置字段名包含这些文本时视为敏感值
+var secretKeyParts = []string{"token", "secret", "password", "passwd", "credential", "api_key", "apikey", "private_key"}
+
+// diagnosticsResult 诊断结果
+type diagnosticsResult struct {
+	name        string
+	contentType string
+	data        []byte
+}
+
+// handleScreenshot 截取游戏窗口
+func (c *Client) handleScreenshot(msg request.WebSocketMessage, _ struct{}) {
+	c.runDiagnostics(msg, func() (diagnosticsResult, error) {
+		hand := util.FindGameWindow(c.profile.WindowPID, c.profile.WindowTitle)
+		if hand == 0 {
+			return diagnosticsResult{}, errors.New("game window not found")
+		}
+		data, err := util.CaptureWindowCompressedPNG(hand)
+		return diagnosticsResult{name: "screenshot.png", contentType: "image/png", data: data}, err
+	})
+}
+
+// handleLogTail 读取日志末尾若干行
+func (c *Client) handleLogTail(msg request.WebSocketMessage, req request.LogTailRequest) {
+	c.runDiagnostics(msg, func() (diagnosticsResult, error) {
+		return tailLog(req)
+	})
+}
+
+// handleConfigDump 导出生效的配置（敏感值已替换）
+func (c *Client) handleConfigDump(msg request.WebSocketMessage, _ struct{}) {
+	c.runDiagnostics(msg, func() (diagnosticsResult, error) {
+		data, err := dumpConfig(c.config, c.profile)
+		return diagnosticsResult{name: "config.json", contentType: "application/json", data: data}, err
+	})
+}
+
+// handleTextCacheDump 导出文本位置缓存
+func (c *Client) handleTextCacheDump(msg request.WebSocketMessage, _ struct{}) {
+	c.runDiagnostics(msg, func() (diagnosticsResult, error) {
+		data, err := util.DumpTextPositionCache()
+		return diagnosticsResult{name: filepath.Base(_const.TextCacheFile), contentType: "application/json", data: data}, err
+	})
+}
+
+// runDiagnostics 在后台收集诊断结果并分片回复（截图、读取日志可能较慢，不阻塞消息处理）
+func (c *Client) runDiagnostics(msg request.WebSocketMessage, collect func() (diagnosticsResult, error)) {
+	fmt.Printf("Received diagnostics request: %s\n", msg.Type)
+	go func() {
+		result, err := collect()
+		if err != nil {
+			fmt.Printf("Diagnostics %s failed: %v\n", msg.Type, err)
+			c.reply(msg, nil, err.Error(), diagnosticsOptions)
+			return
+		}
+		if err = c.sendChunks(msg, result); err != nil {
+			fmt.Printf("Failed to send diagnostics %s: %v\n", msg.Type, err)
+		}
+	}()
+}
+
+// sendChunks 按 DiagnosticsChunkSize 分片回复诊断结果（空内容也回复一个分片）
+func (c *Client) sendChunks(to request.WebSocketMessage, result diagnosticsResult) error {
+	sum := sha256.Sum256(result.data)
+	chunk := request.DiagnosticsChunk{
+		TransferID:  newTransferID(),
+		Total:       (len(result.data) + _const.DiagnosticsChunkSize - 1) / _const.DiagnosticsChunkSize,
+		Name:        result.name,
+		ContentType: result.contentType,
+		Size:        len(result.data),
+		SHA256:      hex.EncodeToString(sum[:]),
+	}
+	if chunk.Total == 0 {
+		chunk.Total = 1
+	}
+	for chunk.Index = 0; chunk.Index < chunk.Total; chunk.Index++ {
+		start := chunk.Index * _const.DiagnosticsChunkSize
+		end := min(start+_const.DiagnosticsChunkSize, len(result.data))
+		chunk.Data = result.data[start:end]
+		// 任一分片无法入队时整个结果已不完整，不再发送后续分片
+		if err := c.wsClient.Reply(to, chunk, "", diagnosticsOptions); err != nil {
+			return fmt.Errorf("chunk %d/%d: %w", chunk.Index+1, chunk.Total, err)
+		}
+	}
+	fmt.Printf("Diagnostics %s sent: %d bytes in %d chunk(s)\n", to.Type, chunk.Size, chunk.Total)
+	return nil
+}
+
+// newTransferID 生成随机分片传输 ID
+func newTransferID() string {
+	var b [8]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// tailLog 读取 logs 目录中日志文件的最后若干行
+func tailLog(req request.LogTailRequest) (diagnosticsResult, error) {
+	lines := req.Lines
+	if lines <= 0 {
+		lines = _const.DiagnosticsLogLines
+	}
+	lines = min(lines, _const.DiagnosticsMaxLogLines)
+
+	name := req.File
+	if name == "" {
+		var err error
+		if name, err = latestClientLog(); err != nil {
+			return diagnosticsResult{}, err
+		}
+	}
+	// 只允许读取 logs 目录中的文件
+	if strings.ContainsAny(name, `/\:`) || name == "." || name == ".." {
+		return diagnosticsResult{}, fmt.Errorf("invalid log file: %s", name)
+	}
+
+	data, err := tailFile(filepath.Join(_const.DiagnosticsLogDir, name), lines)
+	if err != nil {
+		return diagnosticsResult{}, err
+	}
+	return diagnosticsResult{name: name, contentType: "text/plain; charset=utf-8", data: data}, nil
+}
+
+// latestClientLog 最新的客户端日志文件名（日志按日期命名，文件名最大的即为最新）
+func latestClientLog() (string, error) {
+	entries, err := os.ReadDir(_const.DiagnosticsLogDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read log directory: %w", err)
+	}
+	latest := ""
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, _const.DiagnosticsLogPrefix) && strings.HasSuffix(name, ".log") && name > latest {
+			latest = name
+		}
+	}
+	if latest == "" {
+		return "", errors.New("no client log found")
+	}
+	return latest, nil
+}
+
+// tailFile 从文件末尾向前按块读取，直到包含足够的行数
+func tailFile(path string, lines int) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	const blockSize = 64 * 1024
+	offset := info.Size()
+	var data []byte
+	for offset > 0 && bytes.Count(data, []byte{'\n'}) <= lines {
+		n := min(int64(blockSize), offset)
+		offset -= n
+		block := make([]byte, int(n)+len(data))
+		if _, err = file.ReadAt(block[:n], offset); err != nil {
+			return nil, err
+		}
+		copy(block[n:], data)
+		data = block
+	}
+
+	// 忽略末尾的换行，从后向前找到第 lines 个换行
+	end := len(data)
+	if end > 0 && data[end-1] == '\n' {
+		end--
+	}
+	start := end
+	for n := 0; n < lines && start >= 0; n++ {
+		start = bytes.LastIndexByte(data[:start], '\n')
+	}
+	return data[start+1 : end], nil
+}
+
+// dumpConfig 导出生效的配置和当前实例的配置：运行中修改过的字段（如 ftp_provider）为当前值，敏感值已替换
+func dumpConfig(cfg *global.Config, profile *global.ServerProfile) ([]byte, error) {
+	fields, err := redactedFields(cfg)
+	if err != nil {
+		return nil, err
+	}
+	instance, err := redactedFields(profile)
+	if err != nil {
+		return nil, err
+	}
+	dump := map[string]interface{}{
+		"version":     global.Version,
+		"config":      fields,
+		"instance":    instance,
+		"ocr_service": fmt.Sprintf("%s:%d", global.OCRServiceHost, global.OCRServicePort),
+	}
+	return json.MarshalIndent(dump, "", "  ")
+}
+
+// redactedFields 按 JSON 字段导出并替换敏感值
+func redactedFields(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]interface{}
+	if err = json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return redactValue("", fields), nil
+}
+
+// redactValue 替换敏感值：字段名包含 secretKeyParts 的值，以及 URL 中的用户信息和查询参数值
+func redactValue(key string, value interface{}) interface{} {
+	if isSecretKey(key) && value != nil && value != "" {
+		return _const.DiagnosticsRedacted
+	}
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for k, item := range v {
+			v[k] = redactValue(k, item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactValue(key, item)
+		}
+	case string:
+		return redactURL(v)
+	}
+	return value
+}
+
+// isSecretKey 字段名是否表示敏感值
+func isSecretKey(key string) bool {
+	key = strings.ToLower(key)
+	for _, part := range secretKeyParts {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL 替换 URL 中的用户信息和查询参数值，非 URL 原样返回
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil || u.Scheme == "" || u.Host == "" || (u.User == nil && u.RawQuery == "") {
+		return value
+	}
+	if u.User != nil {
+		u.User = url.User(_const.DiagnosticsRedacted)
+	}
+	if u.RawQuery != "" {
+		params := make([]string, 0)
+		for _, param := range strings.Split(u.RawQuery, "&") {
+			name, _, _ := strings.Cut(param, "=")
+			params = append(params, name+"="+_const.DiagnosticsRedacted)
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+	return u.String()
+}
@@ -0,0 +1,11 @@
+package _const
+
+// 嵌入文件提取相关常量
+const (
+	// AssetsManifestFile 嵌入文件提取记录（每个文件提取时的 SHA-256 和时间），用于判断文件是否过期或被用户修改
+	AssetsManifestFile = "assets_manifest.json"
+	// AssetsBackupTimeFormat 用户修改过的文件被替换前备份的时间后缀格式（如 ocr_server.py.20240101-150405.bak）
+	AssetsBackupTimeFormat = "20060102-150405"
+	// AssetsUserEditableDir 允许用户修改的嵌入目录，目录中被修改的文件不会被新版本覆盖
+	AssetsUserEditableDir = "locales"
+)
@@ -0,0 +1,25 @@
+package _const
+
+import "time"
+
+// 文本位置缓存相关常量
+const (
+	// TextCacheFile 文本位置缓存文件路径
+	TextCacheFile = "cache/text_position_cache.json"
+	// TextCacheSaveInterval 缓存落盘最小间隔（仅在缓存有变化时写入）
+	TextCacheSaveInterval = 30 * time.Second
+	// TextCacheMaxAge 缓存条目最长有效期（超过该时间未验证的条目在加载时丢弃）
+	TextCacheMaxAge = 7 * 24 * time.Hour
+	// TextVerifyMargin OCR 验证缓存位置时，截图区域向四周扩展的像素数（只上传该区域，避免文字贴边影响识别）
+	TextVerifyMargin = 24
+	// TextVerifyAttempts OCR 验证缓存位置的最多截图次数
+	TextVerifyAttempts = 3
+)
+
+// Steam 相关常量
+const (
+	// SCUMAppID SCUM 在 Steam 上的应用ID
+	SCUMAppID = "513710"
+	// DefaultSteamPath Steam 默认安装路径（注册表读取失败时使用）
+	DefaultSteamPath = `C:\Program Files (x86)\Steam`
+)
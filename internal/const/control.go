@@ -0,0 +1,12 @@
+package _const
+
+// 远程控制（client_control）的操作
+const (
+	ControlPauseChat         = "pause_chat"          // 暂停聊天监控
+	ControlResumeChat        = "resume_chat"         // 恢复聊天监控
+	ControlRestartGame       = "restart_game"        // 结束游戏进程，由主循环重新启动游戏
+	ControlRestartOCR        = "restart_ocr"         // 重启 OCR 服务
+	ControlClearTextCache    = "clear_text_cache"    // 清空文本位置缓存
+	ControlReplaceGameConfig = "replace_game_config" // 重新替换 SCUM 配置文件
+	ControlSetLogLevel       = "set_log_level"       // 修改日志级别
+)
@@ -0,0 +1,21 @@
+package _const
+
+import "time"
+
+// 远程诊断相关常量
+const (
+	// DiagnosticsChunkSize 诊断结果单个分片的最大字节数（base64 编码后约 1.33 倍，加上信封仍小于 MaxMessageSize）
+	DiagnosticsChunkSize = 1024 * 1024
+	// DiagnosticsTTL 诊断结果在发送队列中的有效期
+	DiagnosticsTTL = 5 * time.Minute
+	// DiagnosticsLogDir 日志目录（日志读取限制在该目录内）
+	DiagnosticsLogDir = "logs"
+	// DiagnosticsLogPrefix 客户端日志文件名前缀（未指定文件时读取最新的客户端日志）
+	DiagnosticsLogPrefix = "scum_client_"
+	// DiagnosticsLogLines 未指定行数时返回的日志行数
+	DiagnosticsLogLines = 200
+	// DiagnosticsMaxLogLines 最多返回的日志行数
+	DiagnosticsMaxLogLines = 10000
+	// DiagnosticsRedacted 配置导出时替换敏感值的文本
+	DiagnosticsRedacted = "REDACTED"
+)
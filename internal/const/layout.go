@@ -0,0 +1,28 @@
+package _const
+
+// 界面布局锚点名称
+const (
+	// AnchorLoadingProbe1 加载界面探测点1
+	AnchorLoadingProbe1 = "loading_probe_1"
+	// AnchorLoadingProbe2 加载界面探测点2
+	AnchorLoadingProbe2 = "loading_probe_2"
+	// AnchorBotMode 登录界面机器人模式标识
+	AnchorBotMode = "bot_mode"
+	// AnchorChatInput 聊天输入框
+	AnchorChatInput = "chat_input"
+	// AnchorChatModeProbe 聊天模式颜色探测点
+	AnchorChatModeProbe = "chat_mode_probe"
+	// AnchorChatLogTopLeft 聊天记录区域左上角
+	AnchorChatLogTopLeft = "chat_log_top_left"
+	// AnchorChatLogBottomRight 聊天记录区域右下角
+	AnchorChatLogBottomRight = "chat_log_bottom_right"
+)
+
+// 锚点参照点
+const (
+	RefPointTopLeft     = "top_left"
+	RefPointTopRight    = "top_right"
+	RefPointBottomLeft  = "bottom_left"
+	RefPointBottomRight = "bottom_right"
+	RefPointCenter      = "center"
+)
@@ -0,0 +1,9 @@
+package _const
+
+// 界面文本字典相关常量
+const (
+	// LocaleDir 界面文本字典目录，每种语言一个文件（如 locales/en.yaml）
+	LocaleDir = "locales"
+	// DefaultUILanguage 默认回退语言
+	DefaultUILanguage = "en"
+)
@@ -0,0 +1,17 @@
+package _const
+
+import "time"
+
+// OCR 环境安装相关常量
+const (
+	// OCRManifestFile OCR 环境清单（下载地址、SHA-256、pip 锁文件）
+	OCRManifestFile = "ocr_manifest.json"
+	// OCRWheelhouseDir 本地离线安装目录，存在时优先从这里取 Python 安装包、get-pip.py 和所有 wheel，不访问网络
+	OCRWheelhouseDir = "wheelhouse"
+	// OCRBundleFile 离线安装包（由 cmd/ocr_bundle 生成），存在且本地离线目录不存在时解压到 OCRWheelhouseDir
+	OCRBundleFile = "ocr_bundle.zip"
+	// OCRDownloadPartSuffix 未完成下载的临时文件后缀（再次下载时断点续传）
+	OCRDownloadPartSuffix = ".part"
+	// OCRDownloadHeaderTimeout 下载请求等待响应头的超时时间（下载本身不限时）
+	OCRDownloadHeaderTimeout = 30 * time.Second
+)
@@ -0,0 +1,47 @@
+package _const
+
+// 指令响应获取方式（response_capture 配置）
+const (
+	// ResponseCaptureAuto 游戏日志可读时读取日志，否则识别聊天记录
+	ResponseCaptureAuto = ""
+	// ResponseCaptureLog 只读取游戏日志
+	ResponseCaptureLog = "log"
+	// ResponseCaptureOCR 只识别聊天记录区域
+	ResponseCaptureOCR = "ocr"
+	// ResponseCaptureBoth 读取游戏日志，日志中没有结果时识别聊天记录
+	ResponseCaptureBoth = "both"
+	// ResponseCaptureOff 不获取响应（只有复制到剪贴板的指令有结果）
+	ResponseCaptureOff = "off"
+)
+
+// 指令执行结果
+const (
+	// CommandOutcomeSuccess 指令有输出且不是错误
+	CommandOutcomeSuccess = "success"
+	// CommandOutcomeError 游戏返回了错误文本
+	CommandOutcomeError = "error"
+	// CommandOutcomeNoOutput 在等待时间内没有获取到任何输出
+	CommandOutcomeNoOutput = "no_output"
+	// CommandOutcomeNotSent 指令未能发送到游戏（聊天框无法激活、剪贴板写入失败等）
+	CommandOutcomeNotSent = "not_sent"
+)
+
+// 指令结果来源
+const (
+	// ResponseSourceClipboard 游戏复制到剪贴板的输出（列表类指令）
+	ResponseSourceClipboard = "clipboard"
+	// ResponseSourceGameLog 游戏本地日志
+	ResponseSourceGameLog = "game_log"
+	// ResponseSourceChatOCR 聊天记录区域的 OCR 识别
+	ResponseSourceChatOCR = "chat_ocr"
+)
+
+// 游戏日志
+const (
+	// GameLogRelativePath 游戏客户端日志相对 %LOCALAPPDATA% 的路径
+	GameLogRelativePath = `SCUM\Saved\Logs\SCUM.log`
+	// GameLogMaxReadSize 单次最多读取的新增日志字节数（超出时只读取末尾）
+	GameLogMaxReadSize = 256 * 1024
+	// GameLogDefaultCategory 管理员指令回显和输出默认所在的日志分类
+	GameLogDefaultCategory = "LogSCUM"
+)
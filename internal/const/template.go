@@ -0,0 +1,15 @@
+package _const
+
+// 模板匹配相关常量
+const (
+	// TemplateDir 模板根目录，结构为 templates/<语言>/<宽>x<高>/<KEY>.png
+	TemplateDir = "templates"
+	// TemplateMatchThreshold 归一化互相关（NCC）匹配阈值，取值范围 [-1, 1]
+	TemplateMatchThreshold = 0.85
+	// TemplateCoarseStep 粗搜索步长（像素），粗搜索后在最佳位置附近逐像素精修
+	TemplateCoarseStep = 2
+	// TemplateCoarseSlack 粗搜索阶段相对匹配阈值的放宽量，避免步长跳过峰值
+	TemplateCoarseSlack = 0.15
+	// TemplateVerifyMargin 使用缓存位置验证时，搜索区域向四周扩展的像素数
+	TemplateVerifyMargin = 12
+)
@@ -15,14 +15,34 @@ const (
 	// 连接相关常量
 	ConnectionTimeout = 60 * time.Second // 连接超时时间
 	HeartbeatInterval = 40 * time.Second // 心跳间隔
-	HeartbeatTimeout  = 5 * time.Minute  // 心跳超时
+	PingInterval      = 30 * time.Second // WebSocket ping 间隔
+	PongWait          = 90 * time.Second // 读取超时：超过该时间未收到任何数据（包括 pong）视为连接已断开
+	WriteWait         = 10 * time.Second // 单次写入超时
 	RetryInterval     = 5 * time.Second  // 重试间隔
 	MaxRetryInterval  = 60 * time.Second // 最大重试间隔
+	CallTimeout       = 30 * time.Second // 等待请求回复的默认超时时间（ctx 未设置截止时间时）
+	OutboxTTL         = 10 * time.Minute // 发送队列中消息的默认有效期
+
+	// 客户端状态上报（client_status）
+	StatusReportInterval = 30 * time.Second // 定时上报间隔
+	StatusMinInterval    = 2 * time.Second  // 状态变化时两次上报的最小间隔
+
+	// 远程控制（client_control）
+	ControlPauseWaitTime  = 2 * time.Second // 聊天监控暂停期间主循环的等待时间
+	ControlRestartTimeout = 2 * time.Minute // 等待主循环重启游戏的时间
+	ControlResultTTL      = 5 * time.Minute // 执行结果在发送队列中的有效期
+
+	// 指令响应获取（游戏日志/聊天记录 OCR）
+	ResponseCaptureTimeout  = 1500 * time.Millisecond // 发送指令后等待输出的时间
+	ResponseLogPollInterval = 150 * time.Millisecond  // 读取游戏日志的间隔
+	ResponseOCRPollInterval = 500 * time.Millisecond  // 识别聊天记录的间隔
 
 	// 缓冲区大小常量
 	ReadBufferSize  = 128 * 1024      // 读取缓冲区大小
 	WriteBufferSize = 128 * 1024      // 写入缓冲区大小
 	MaxMessageSize  = 2 * 1024 * 1024 // 最大消息大小
+	SendQueueSize   = 64              // WebSocket 直接发送队列长度（每个连接）
+	OutboxSize      = 256             // WebSocket 发送队列长度（跨连接保留，断线期间的消息重新认证后补发）
 
 	// OCR 服务相关时间常量
 	OCRServiceMaxWaitTime        = 120 * time.Second // OCR 服务最大等待时间
@@ -30,6 +50,19 @@ const (
 	OCRServiceHealthCheckTimeout = 3 * time.Second   // OCR 服务健康检查超时时间
 	OCRServiceRestartWaitTime    = 2 * time.Second   // OCR 服务重启等待时间
 	OCRServiceAPITimeout         = 10 * time.Second  // OCR 服务 API 请求超时时间
+	OCRCriticalTimeout           = 5 * time.Second   // 状态判断相关 OCR 请求的调用超时时间
+	OCRWorkerCount               = 2                 // OCR 请求并发数（服务端识别串行执行，另一个请求的上传和预处理与之重叠）
+
+	// OCR 服务监控相关常量
+	OCRSupervisorProbeInterval    = 5 * time.Second  // 健康检查间隔
+	OCRSupervisorFailureThreshold = 3                // 连续健康检查失败多少次后重启
+	OCRSupervisorBackoffBase      = 2 * time.Second  // 重启退避基础时间（每次失败翻倍）
+	OCRSupervisorBackoffMax       = 2 * time.Minute  // 重启退避最大时间
+	OCRSupervisorStableTime       = 5 * time.Minute  // 持续健康多久后重置退避
+	OCRSupervisorCrashLoopWindow  = 10 * time.Minute // 崩溃循环统计窗口
+	OCRSupervisorCrashLoopLimit   = 5                // 统计窗口内最多自动重启次数，超过后停止自动重启
+	OCRSupervisorHistorySize      = 20               // 保留的重启记录条数
+	OCRUnavailableWaitTime        = 5 * time.Second  // OCR 不可用时主循环等待时间
 
 	// 截图相关常量
 	ScreenshotMaxRetries = 3                      // 截图最大重试次数
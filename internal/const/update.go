@@ -0,0 +1,39 @@
+package _const
+
+import "time"
+
+// 自我更新相关常量
+const (
+	UpdateTempDir      = "temp_update" // 临时更新目录
+	UpdateBackupSuffix = ".backup"     // 备份文件后缀
+	UpdateTimeout      = 5 * time.Minute
+	UpdateMaxSize      = 256 << 20        // 更新文件最大大小（字节）
+	UpdateStatusTTL    = 30 * time.Minute // 更新状态在发送队列中的有效期（断线期间保留）
+
+	// 更新器（同一程序以 --apply-update 启动）
+	UpdateApplyFlag         = "--apply-update"      // 更新器模式的命令行参数
+	UpdateHelperName        = "scum_client_updater" // 更新器副本文件名（从副本运行，避免锁定被替换的程序）
+	UpdateLogFile           = "logs/update.log"     // 更新器日志
+	UpdateParentExitTimeout = 60 * time.Second      // 等待主程序退出的时间
+	UpdateRenameRetries     = 10                    // 替换文件失败时的重试次数（杀毒软件等可能短暂占用文件）
+	UpdateRenameRetryDelay  = 500 * time.Millisecond
+
+	// 更新后的健康检查
+	UpdateProbationFlag   = "--probation"        // 新版本的试运行参数（--probation=<健康状态文件>）
+	UpdateHealthFile      = "update_health.json" // 新版本写入的健康状态（位于 UpdateTempDir）
+	UpdateResultFile      = "update_result.json" // 更新器写入的更新结果，客户端认证后上报（位于 UpdateTempDir）
+	UpdateProbationWindow = 5 * time.Minute      // 新版本需要在此时间内通过全部健康检查，否则回滚
+	UpdateProbationPoll   = 1 * time.Second      // 更新器检查健康状态的间隔
+
+	// 更新状态（client_update 消息的 status 字段）
+	UpdateStatusStarting    = "starting"    // 收到更新请求
+	UpdateStatusChecking    = "checking"    // 检查版本
+	UpdateStatusDownloading = "downloading" // 下载中
+	UpdateStatusVerifying   = "verifying"   // 校验哈希和签名
+	UpdateStatusInstalling  = "installing"  // 安装中
+	UpdateStatusCompleted   = "completed"   // 更新完成
+	UpdateStatusFailed      = "failed"      // 更新失败（下载、启动更新器等错误）
+	UpdateStatusRejected    = "rejected"    // 校验未通过，拒绝更新
+	UpdateStatusNoUpdate    = "no_update"   // 无需更新
+	UpdateStatusRolledBack  = "rolled_back" // 新版本未通过健康检查，已恢复旧版本
+)
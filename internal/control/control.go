@@ -0,0 +1,132 @@
+// Package control 远程控制：由 WebSocket 客户端设置（client_control 消息），主循环和日志函数读取
+// 暂停和重启游戏按实例控制，日志级别对整个进程生效
+package control
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// 日志级别，低于当前级别的日志不输出
+const (
+	LogDebug = iota
+	LogInfo
+	LogError
+)
+
+// logLevelNames 日志级别名称
+var logLevelNames = []string{"debug", "info", "error"}
+
+// logLevel 当前日志级别（进程级），默认 LogDebug，输出全部日志
+var logLevel atomic.Int32
+
+// Controls 一个游戏实例的远程控制状态
+type Controls struct {
+	paused atomic.Bool
+
+	restartMutex     sync.Mutex
+	restartRequested bool
+	restartWaiters   []chan error
+}
+
+// New 创建实例的控制状态
+func New() *Controls {
+	return &Controls{}
+}
+
+// Pause 暂停聊天监控（正在执行的指令完成后退出监控循环，暂停期间不执行服务器指令）
+// @return: bool 调用前是否已暂停
+func (c *Controls) Pause() bool {
+	return c.paused.Swap(true)
+}
+
+// Resume 恢复聊天监控
+// @return: bool 调用前是否处于暂停状态
+func (c *Controls) Resume() bool {
+	return c.paused.Swap(false)
+}
+
+// Paused 聊天监控是否已暂停
+func (c *Controls) Paused() bool {
+	return c.paused.Load()
+}
+
+// Interrupted 聊天监控是否需要退出（已暂停或请求了重启游戏）
+func (c *Controls) Interrupted() bool {
+	return c.Paused() || c.GameRestartRequested()
+}
+
+// RequestGameRestart 请求主循环重启游戏，并等待重启完成
+// @description: 主循环在下一次 ErrorReboot 时结束游戏进程；ctx 结束时返回错误，但请求保留，主循环仍会执行
+// @param: ctx context.Context
+// @return: error 结束游戏进程的结果
+func (c *Controls) RequestGameRestart(ctx context.Context) error {
+	done := make(chan error, 1)
+	c.restartMutex.Lock()
+	c.restartRequested = true
+	c.restartWaiters = append(c.restartWaiters, done)
+	c.restartMutex.Unlock()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		c.restartMutex.Lock()
+		for i, waiter := range c.restartWaiters {
+			if waiter == done {
+				c.restartWaiters = append(c.restartWaiters[:i], c.restartWaiters[i+1:]...)
+				break
+			}
+		}
+		c.restartMutex.Unlock()
+		return fmt.Errorf("game restart still pending: %w", ctx.Err())
+	}
+}
+
+// GameRestartRequested 是否有待执行的重启游戏请求
+func (c *Controls) GameRestartRequested() bool {
+	c.restartMutex.Lock()
+	defer c.restartMutex.Unlock()
+	return c.restartRequested
+}
+
+// CompleteGameRestart 主循环执行重启后调用，通知所有等待中的请求
+// @param: err error 结束游戏进程的结果
+func (c *Controls) CompleteGameRestart(err error) {
+	c.restartMutex.Lock()
+	defer c.restartMutex.Unlock()
+	c.restartRequested = false
+	for _, waiter := range c.restartWaiters {
+		waiter <- err
+	}
+	c.restartWaiters = nil
+}
+
+// SetLogLevel 设置日志级别
+// @param: name string debug/info/error（不区分大小写），为空时为 debug
+// @return: error 级别名称无效
+func SetLogLevel(name string) error {
+	if name == "" {
+		name = logLevelNames[LogDebug]
+	}
+	for level, levelName := range logLevelNames {
+		if strings.EqualFold(name, levelName) {
+			logLevel.Store(int32(level))
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid log level %q (expected %s)", name, strings.Join(logLevelNames, "/"))
+}
+
+// LogLevelName 当前日志级别名称
+func LogLevelName() string {
+	return logLevelNames[logLevel.Load()]
+}
+
+// LogEnabled 该级别的日志是否输出
+func LogEnabled(level int) bool {
+	return int32(level) >= logLevel.Load()
+}
@@ -0,0 +1,339 @@
+package ocrenv
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	_const "qq_client/internal/const"
+	"runtime"
+	"strings"
+)
+
+// PinOptions 固定清单哈希时的选项
+type PinOptions struct {
+	Python        string // 用于运行 pip download 的 Python
+	Wheelhouse    string // 下载目录
+	Platform      string // 目标平台（如 win_amd64、manylinux2014_x86_64），为空表示当前平台
+	PythonVersion string // 目标 Python 版本（如 3.12）
+	Index         string // pip 索引，为空时使用清单中的第一个索引
+
+	LinuxPlatform      string // 同时生成 Linux 锁文件的平台（如 manylinux2014_x86_64），为空或目标平台已是 Linux 时不生成
+	LinuxPythonVersion string // Linux 主机的 Python 版本，为空时与 PythonVersion 相同
+}
+
+// PinManifest 下载清单中的全部文件到 wheelhouse 并固定哈希
+// @description: 嵌入式 Python、get-pip.py 和模型写入 SHA-256 与大小，依赖下载后生成锁文件；已固定的哈希会被校验，两个系统的锁文件都固定后开启 require_hashes
+// @param: manifestPath string 清单文件路径（结果写回该文件）
+// @param: opts PinOptions
+// @return: *Manifest, error
+func PinManifest(manifestPath string, opts PinOptions) (*Manifest, error) {
+	manifest, err := LoadManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(opts.Wheelhouse, 0755); err != nil {
+		return nil, err
+	}
+
+	artifacts := []*Artifact{&manifest.Python, &manifest.GetPip}
+	for i := range manifest.Models {
+		artifacts = append(artifacts, &manifest.Models[i].Artifact)
+	}
+	skip := make([]string, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		if err = pinArtifact(artifact, opts.Wheelhouse); err != nil {
+			return nil, err
+		}
+		skip = append(skip, artifact.File)
+	}
+
+	index := opts.Index
+	if index == "" && len(manifest.IndexURLs) > 0 {
+		index = manifest.IndexURLs[0]
+	}
+	if err = DownloadWheels(opts.Python, manifest.Path(manifest.RequirementsIn), opts.Wheelhouse, index, opts.Platform, opts.PythonVersion); err != nil {
+		return nil, err
+	}
+	lockPath := manifest.LockFor(opts.targetOS())
+	count, err := GenerateLock(opts.Wheelhouse, lockPath, skip)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("已固定 %d 个依赖: %s\n", count, lockPath)
+	if err = pinLinuxLock(manifest, opts, index); err != nil {
+		return nil, err
+	}
+
+	// 全部文件和两个系统的依赖都已固定后，之后的安装拒绝任何未固定哈希的文件
+	manifest.RequireHashes = locksHashed(manifest)
+	if err = manifest.Save(manifestPath); err != nil {
+		return nil, err
+	}
+	fmt.Printf("已更新清单: %s (require_hashes=%v)\n", manifestPath, manifest.RequireHashes)
+	return manifest, nil
+}
+
+// pinLinuxLock 将 Linux 依赖下载到 wheelhouse 的 linux 子目录并生成 Linux 锁文件
+// @description: 与目标平台的依赖分开存放，避免两个平台的 wheel 混入同一个锁文件
+func pinLinuxLock(manifest *Manifest, opts PinOptions, index string) error {
+	if opts.LinuxPlatform == "" || opts.targetOS() == "linux" || manifest.RequirementsLinux == "" {
+		return nil
+	}
+	pythonVersion := opts.LinuxPythonVersion
+	if pythonVersion == "" {
+		pythonVersion = opts.PythonVersion
+	}
+	wheelhouse := filepath.Join(opts.Wheelhouse, "linux")
+	if err := os.MkdirAll(wheelhouse, 0755); err != nil {
+		return err
+	}
+	if err := DownloadWheels(opts.Python, manifest.Path(manifest.RequirementsIn), wheelhouse, index, opts.LinuxPlatform, pythonVersion); err != nil {
+		return err
+	}
+	lockPath := manifest.LockFor("linux")
+	count, err := GenerateLock(wheelhouse, lockPath, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已固定 %d 个依赖: %s (Python %s)\n", count, lockPath, pythonVersion)
+	return nil
+}
+
+// locksHashed 清单中的文件都已固定哈希，且 Windows 和 Linux 的锁文件都存在并包含哈希
+func locksHashed(manifest *Manifest) bool {
+	artifacts := []Artifact{manifest.Python, manifest.GetPip}
+	for _, model := range manifest.Models {
+		artifacts = append(artifacts, model.Artifact)
+	}
+	for _, artifact := range artifacts {
+		if artifact.SHA256 == "" {
+			return false
+		}
+	}
+	for _, goos := range []string{"windows", "linux"} {
+		if hashed, err := lockHasHashes(manifest.LockFor(goos)); err != nil || !hashed {
+			fmt.Printf("[WARN] %s 锁文件 %s 未固定哈希，require_hashes 保持 false\n", goos, manifest.LockFor(goos))
+			return false
+		}
+	}
+	return true
+}
+
+// targetOS 根据目标平台判断锁文件对应的系统
+func (opts PinOptions) targetOS() string {
+	switch {
+	case strings.HasPrefix(opts.Platform, "win"):
+		return "windows"
+	case strings.Contains(opts.Platform, "linux"):
+		return "linux"
+	}
+	return runtime.GOOS
+}
+
+// pinArtifact 下载文件并写入哈希和大小
+func pinArtifact(artifact *Artifact, dir string) error {
+	path, err := Fetch(*artifact, dir, "", false)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	sum, err := FileSHA256(path)
+	if err != nil {
+		return err
+	}
+	artifact.SHA256, artifact.Size = sum, info.Size()
+	fmt.Printf("%s: sha256=%s size=%d\n", artifact.File, sum, info.Size())
+	return nil
+}
+
+// BuildBundle 生成离线安装包
+// @description: 固定清单哈希后，将 wheelhouse 目录（嵌入式 Python、get-pip.py、全部依赖、模型）连同清单和锁文件打包为一个 zip
+// @param: manifestPath string 清单文件路径
+// @param: out string 输出的 zip 文件路径
+// @param: opts PinOptions
+// @return: error
+func BuildBundle(manifestPath, out string, opts PinOptions) error {
+	manifest, err := PinManifest(manifestPath, opts)
+	if err != nil {
+		return err
+	}
+
+	// 安装包内的清单与锁文件放在根目录，与离线目录中的文件一起使用
+	lockPath := manifest.LockFor(opts.targetOS())
+	files := map[string]string{
+		filepath.Base(manifestPath): manifestPath,
+		filepath.Base(lockPath):     lockPath,
+	}
+	entries, err := os.ReadDir(opts.Wheelhouse)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), _const.OCRDownloadPartSuffix) {
+			continue
+		}
+		if _, exists := files[entry.Name()]; !exists {
+			files[entry.Name()] = filepath.Join(opts.Wheelhouse, entry.Name())
+		}
+	}
+
+	tmpPath := out + ".tmp"
+	if err = writeZip(tmpPath, files); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err = os.Rename(tmpPath, out); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("保存安装包失败: %v", err)
+	}
+	fmt.Printf("已生成离线安装包: %s (%d 个文件)\n", out, len(files))
+	return nil
+}
+
+// writeZip 将文件写入 zip（wheel 和 tar 本身已压缩，只存储不再压缩）
+func writeZip(path string, files map[string]string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	writer := zip.NewWriter(out)
+	for name, src := range files {
+		if err = addZipFile(writer, name, src); err != nil {
+			writer.Close()
+			return fmt.Errorf("写入 %s 失败: %v", name, err)
+		}
+	}
+	return writer.Close()
+}
+
+// addZipFile 向 zip 写入一个文件
+func addZipFile(writer *zip.Writer, name, src string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = name
+	header.Method = zip.Store
+	w, err := writer.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, in)
+	return err
+}
+
+// ExtractBundle 解压离线安装包到本地离线目录
+// @param: archive string 安装包路径
+// @param: dest string 本地离线目录
+// @return: error
+func ExtractBundle(archive, dest string) error {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("打开离线安装包失败: %v", err)
+	}
+	defer reader.Close()
+	if err = os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+
+	root := filepath.Clean(dest) + string(os.PathSeparator)
+	for _, file := range reader.File {
+		target := filepath.Join(dest, file.Name)
+		if !strings.HasPrefix(target, root) {
+			return fmt.Errorf("非法压缩条目路径: %s", file.Name)
+		}
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		if err = extractZipFile(file, target); err != nil {
+			return fmt.Errorf("解压 %s 失败: %v", file.Name, err)
+		}
+	}
+	return nil
+}
+
+// extractZipFile 解压 zip 中的一个文件
+func extractZipFile(file *zip.File, target string) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	in, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// LocalDir 获取本地离线安装目录（绝对路径），不存在时返回空
+// @description: 离线目录不存在但有离线安装包时，先将安装包解压到临时目录再改名，避免解压中断留下不完整的目录
+// @param: wheelhouse string 离线目录
+// @param: bundle string 离线安装包路径
+// @return: string
+func LocalDir(wheelhouse, bundle string) string {
+	if _, err := os.Stat(wheelhouse); os.IsNotExist(err) {
+		if _, err = os.Stat(bundle); err == nil {
+			fmt.Printf("检测到离线安装包 %s，正在解压...\n", bundle)
+			tmpDir := wheelhouse + ".tmp"
+			_ = os.RemoveAll(tmpDir)
+			if err = ExtractBundle(bundle, tmpDir); err != nil {
+				fmt.Printf("解压离线安装包失败: %v\n", err)
+				_ = os.RemoveAll(tmpDir)
+				return ""
+			}
+			if err = os.Rename(tmpDir, wheelhouse); err != nil {
+				fmt.Printf("保存离线安装目录失败: %v\n", err)
+				return ""
+			}
+		}
+	}
+
+	info, err := os.Stat(wheelhouse)
+	if err != nil || !info.IsDir() {
+		return ""
+	}
+	abs, err := filepath.Abs(wheelhouse)
+	if err != nil {
+		return ""
+	}
+	return abs
+}
+
+// LoadLocalManifest 读取 OCR 环境清单
+// @description: 本地离线目录中有清单时（离线安装包自带，哈希已固定）优先使用
+// @param: localDir string 本地离线目录（可为空）
+// @param: fallback string 默认清单路径
+// @return: *Manifest, error
+func LoadLocalManifest(localDir, fallback string) (*Manifest, error) {
+	if localDir != "" {
+		bundled := filepath.Join(localDir, filepath.Base(fallback))
+		if _, err := os.Stat(bundled); err == nil {
+			return LoadManifest(bundled)
+		}
+	}
+	return LoadManifest(fallback)
+}
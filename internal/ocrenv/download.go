@@ -0,0 +1,179 @@
+package ocrenv
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	_const "qq_client/internal/const"
+	"strings"
+)
+
+// downloadClient 下载使用的 HTTP 客户端（只限制等待响应头的时间，大文件下载本身不限时）
+var downloadClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: _const.OCRDownloadHeaderTimeout,
+	},
+}
+
+// FileSHA256 计算文件的 SHA-256
+// @param: path string 文件路径
+// @return: string 小写十六进制, error
+func FileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// verify 校验文件大小和 SHA-256
+// @return: string 实际的 SHA-256, error 不一致时返回原因
+func verify(path string, a Artifact) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if a.Size > 0 && info.Size() != a.Size {
+		return "", fmt.Errorf("%s 大小不一致: 期望 %d，实际 %d", a.File, a.Size, info.Size())
+	}
+	actual, err := FileSHA256(path)
+	if err != nil {
+		return "", fmt.Errorf("计算 %s 的哈希失败: %v", a.File, err)
+	}
+	if a.SHA256 != "" && !strings.EqualFold(actual, a.SHA256) {
+		return actual, fmt.Errorf("%s 哈希不一致: 期望 %s，实际 %s", a.File, a.SHA256, actual)
+	}
+	return actual, nil
+}
+
+// Fetch 获取清单中的文件
+// @description: 依次使用本地离线目录中的文件、目标目录中已下载的文件，最后按顺序从下载地址下载（断点续传），任何来源的文件都必须通过校验
+// @param: a Artifact 清单条目
+// @param: destDir string 下载目录
+// @param: localDir string 本地离线目录（为空或不存在时不使用）
+// @param: requireHash bool 为 true 时拒绝未固定哈希的条目
+// @return: string 文件路径, error
+func Fetch(a Artifact, destDir, localDir string, requireHash bool) (string, error) {
+	if a.SHA256 == "" && requireHash {
+		return "", fmt.Errorf("清单中 %s 未固定 SHA-256，拒绝使用", a.File)
+	}
+
+	// 本地离线目录中的文件校验失败时直接报错，不回退到网络
+	if localDir != "" {
+		local := filepath.Join(localDir, a.File)
+		if _, err := os.Stat(local); err == nil {
+			actual, err := verify(local, a)
+			if err != nil {
+				return "", fmt.Errorf("本地文件校验失败: %v", err)
+			}
+			warnUnpinned(a, actual)
+			fmt.Printf("使用本地文件: %s\n", local)
+			return local, nil
+		}
+	}
+
+	// 已下载且校验通过的文件直接使用
+	dest := filepath.Join(destDir, a.File)
+	if _, err := os.Stat(dest); err == nil {
+		if actual, err := verify(dest, a); err == nil {
+			warnUnpinned(a, actual)
+			return dest, nil
+		}
+		fmt.Printf("已下载的 %s 校验失败，重新下载\n", a.File)
+		_ = os.Remove(dest)
+	}
+
+	if len(a.URLs) == 0 {
+		return "", fmt.Errorf("清单中 %s 没有下载地址，且本地离线目录中不存在", a.File)
+	}
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return "", err
+	}
+
+	part := dest + _const.OCRDownloadPartSuffix
+	var errs []string
+	for _, url := range a.URLs {
+		fmt.Printf("正在下载 %s ...\n", url)
+		if err := downloadResume(url, part); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		actual, err := verify(part, a)
+		if err != nil {
+			// 内容错误（可能是镜像文件不一致或续传的数据来自不同文件），丢弃后尝试下一个地址
+			_ = os.Remove(part)
+			errs = append(errs, err.Error())
+			continue
+		}
+		if err = os.Rename(part, dest); err != nil {
+			return "", fmt.Errorf("保存 %s 失败: %v", a.File, err)
+		}
+		warnUnpinned(a, actual)
+		fmt.Printf("已下载并校验: %s (sha256: %s)\n", a.File, actual)
+		return dest, nil
+	}
+	return "", fmt.Errorf("下载 %s 失败: %s", a.File, strings.Join(errs, "; "))
+}
+
+// warnUnpinned 清单未固定哈希时输出实际哈希，便于写回清单
+func warnUnpinned(a Artifact, actual string) {
+	if a.SHA256 == "" {
+		fmt.Printf("[WARN] 清单中 %s 未固定 SHA-256，本次使用的文件哈希为 %s\n", a.File, actual)
+	}
+}
+
+// downloadResume 下载文件到 part，part 已存在时从断点继续
+func downloadResume(url, part string) error {
+	var offset int64
+	if info, err := os.Stat(part); err == nil {
+		offset = info.Size()
+	}
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("下载失败: %s -> %v", url, err)
+	}
+	defer resp.Body.Close()
+
+	flags := os.O_CREATE | os.O_WRONLY
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		fmt.Printf("从 %d 字节处继续下载\n", offset)
+		flags |= os.O_APPEND
+	case http.StatusOK:
+		// 服务器不支持断点续传，从头下载
+		flags |= os.O_TRUNC
+	case http.StatusRequestedRangeNotSatisfiable:
+		// 临时文件已完整，交给校验判断
+		return nil
+	default:
+		return fmt.Errorf("下载失败: %s -> http %d", url, resp.StatusCode)
+	}
+
+	out, err := os.OpenFile(part, flags, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		return fmt.Errorf("下载中断: %s -> %v（再次运行将继续下载）", url, err)
+	}
+	return out.Close()
+}
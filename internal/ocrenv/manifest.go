@@ -0,0 +1,87 @@
+package ocrenv
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Artifact 清单中的一个下载文件
+type Artifact struct {
+	File   string   `json:"file"`           // 保存的文件名，同时用于在本地离线目录中查找
+	URLs   []string `json:"urls"`           // 下载地址，按顺序尝试（官方源在前，镜像在后）
+	SHA256 string   `json:"sha256"`         // 期望的 SHA-256（小写十六进制），为空表示尚未固定
+	Size   int64    `json:"size,omitempty"` // 期望的文件大小（字节），为 0 时不检查
+}
+
+// Model 清单中的一个识别模型（tar 包）
+type Model struct {
+	Artifact
+	Dest string `json:"dest"` // 解压目录，"~/" 开头表示用户目录（PaddleOCR 默认模型缓存在 ~/.paddleocr/whl）
+}
+
+// Manifest OCR 环境清单
+// 下载地址的顺序即优先级，所有文件都按 SHA-256 校验，因此镜像与官方源同样可信
+type Manifest struct {
+	Version           int      `json:"version"`
+	RequireHashes     bool     `json:"require_hashes"`     // 为 true 时拒绝使用未固定哈希的文件和没有哈希的 pip 依赖，清单中未配置时为 true，PinManifest 固定全部哈希后写入 true
+	Python            Artifact `json:"python"`             // 嵌入式 Python 压缩包
+	GetPip            Artifact `json:"get_pip"`            // get-pip.py
+	Requirements      string   `json:"requirements"`       // pip 锁文件（含 --hash，Windows 嵌入式 Python），相对清单所在目录
+	RequirementsLinux string   `json:"requirements_linux"` // Linux 虚拟环境使用的 pip 锁文件
+	RequirementsIn    string   `json:"requirements_in"`    // 顶层依赖（生成锁文件的输入），锁文件不存在时回退使用
+	IndexURLs         []string `json:"index_urls"`         // pip 索引，按顺序尝试；使用本地离线目录时不访问
+	Models            []Model  `json:"models"`             // 识别模型，离线安装时解压到对应目录，联网时由 PaddleOCR 自行下载
+
+	dir string // 清单所在目录
+}
+
+// LoadManifest 读取清单文件
+// @param: path string 清单文件路径
+// @return: *Manifest, error
+func LoadManifest(path string) (*Manifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取清单 %s 失败: %v", path, err)
+	}
+	// 未配置 require_hashes 时默认要求哈希
+	manifest := Manifest{RequireHashes: true}
+	if err = json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析清单 %s 失败: %v", path, err)
+	}
+	if manifest.Python.File == "" || manifest.GetPip.File == "" {
+		return nil, fmt.Errorf("清单 %s 缺少 python 或 get_pip 条目", path)
+	}
+	manifest.dir = filepath.Dir(path)
+	return &manifest, nil
+}
+
+// Save 写回清单文件（用于固定哈希）
+// @param: path string 清单文件路径
+// @return: error
+func (m *Manifest) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化清单失败: %v", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LockFor 获取目标系统使用的 pip 锁文件路径
+// @param: goos string 目标系统（windows/linux）
+// @return: string
+func (m *Manifest) LockFor(goos string) string {
+	if goos == "windows" || m.RequirementsLinux == "" {
+		return m.Path(m.Requirements)
+	}
+	return m.Path(m.RequirementsLinux)
+}
+
+// Path 将清单中的相对路径转换为基于清单所在目录的路径
+func (m *Manifest) Path(name string) string {
+	if name == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(m.dir, name)
+}
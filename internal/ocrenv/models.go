@@ -0,0 +1,97 @@
+package ocrenv
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// modelDest 展开模型解压目录中的 "~/"
+func modelDest(dest string) (string, error) {
+	if dest == "~" || strings.HasPrefix(dest, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("获取用户目录失败: %v", err)
+		}
+		return filepath.Join(home, strings.TrimPrefix(strings.TrimPrefix(dest, "~"), "/")), nil
+	}
+	return dest, nil
+}
+
+// InstallModels 安装清单中的识别模型
+// @description: 模型目录已存在时跳过；tar 包按清单校验后解压
+// @param: localDir string 本地离线目录（为空时从清单地址下载）
+// @param: downloadDir string 下载目录
+// @return: error
+func (m *Manifest) InstallModels(localDir, downloadDir string) error {
+	for _, model := range m.Models {
+		dest, err := modelDest(model.Dest)
+		if err != nil {
+			return err
+		}
+		modelDir := filepath.Join(dest, strings.TrimSuffix(model.File, ".tar"))
+		if _, err = os.Stat(modelDir); err == nil {
+			fmt.Printf("模型已存在，跳过: %s\n", modelDir)
+			continue
+		}
+
+		archive, err := Fetch(model.Artifact, downloadDir, localDir, m.RequireHashes)
+		if err != nil {
+			return fmt.Errorf("获取模型 %s 失败: %v", model.File, err)
+		}
+		if err = extractTar(archive, dest); err != nil {
+			return fmt.Errorf("解压模型 %s 失败: %v", model.File, err)
+		}
+		fmt.Printf("已安装模型: %s\n", modelDir)
+	}
+	return nil
+}
+
+// extractTar 解压 tar 包（仅普通文件和目录）
+func extractTar(archive, dest string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	root := filepath.Clean(dest) + string(os.PathSeparator)
+	reader := tar.NewReader(f)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dest, header.Name)
+		if !strings.HasPrefix(target, root) {
+			return fmt.Errorf("非法压缩条目路径: %s", header.Name)
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err = os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err = os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			out, err := os.Create(target)
+			if err != nil {
+				return err
+			}
+			if _, err = io.Copy(out, reader); err != nil {
+				out.Close()
+				return err
+			}
+			if err = out.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package ocrenv
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// pipNameSeparator PEP 503 包名规范化
+var pipNameSeparator = regexp.MustCompile(`[-_.]+`)
+
+// RequirementsFile 获取 pip 安装使用的依赖文件
+// @description: 优先使用目标系统的锁文件；锁文件不存在时回退到顶层依赖（版本和哈希均未固定），清单要求哈希时拒绝回退
+// @param: goos string 目标系统（windows/linux）
+// @return: string 文件路径, bool 是否包含哈希（需要 --require-hashes）, error
+func (m *Manifest) RequirementsFile(goos string) (string, bool, error) {
+	lockPath := m.LockFor(goos)
+	if lockPath != "" {
+		if _, err := os.Stat(lockPath); err == nil {
+			hashed, err := lockHasHashes(lockPath)
+			if err != nil {
+				return "", false, err
+			}
+			if !hashed && m.RequireHashes {
+				return "", false, fmt.Errorf("锁文件 %s 中没有哈希，拒绝安装", lockPath)
+			}
+			return lockPath, hashed, nil
+		}
+	}
+
+	if m.RequireHashes {
+		return "", false, fmt.Errorf("未找到锁文件 %s，拒绝安装未固定哈希的依赖", lockPath)
+	}
+	inPath := m.Path(m.RequirementsIn)
+	if _, err := os.Stat(inPath); err != nil {
+		return "", false, fmt.Errorf("未找到依赖文件 %s 或 %s", lockPath, inPath)
+	}
+	fmt.Printf("[WARN] 未找到锁文件 %s，使用未固定哈希的依赖列表 %s\n", lockPath, inPath)
+	return inPath, false, nil
+}
+
+// IndexSources 获取依次尝试的 pip 索引
+// @description: 使用本地离线目录时只尝试一次且不访问网络；清单未配置索引时使用 pip 默认索引
+// @return: []string 为空字符串的元素表示不指定索引
+func (m *Manifest) IndexSources(localDir string) []string {
+	if localDir != "" || len(m.IndexURLs) == 0 {
+		return []string{""}
+	}
+	return m.IndexURLs
+}
+
+// PipInstallArgs 生成 pip install 参数（不含 python 可执行文件）
+// @param: reqFile string 依赖文件
+// @param: hashed bool 依赖文件是否包含哈希
+// @param: localDir string 本地离线目录（为空表示联网安装）
+// @param: indexURL string pip 索引（为空表示使用默认索引）
+// @return: []string
+func PipInstallArgs(reqFile string, hashed bool, localDir, indexURL string) []string {
+	args := []string{"-m", "pip", "install", "--no-warn-script-location", "-r", reqFile}
+	if hashed {
+		args = append(args, "--require-hashes")
+	}
+	if localDir != "" {
+		args = append(args, "--no-index", "--find-links", localDir)
+	} else if indexURL != "" {
+		args = append(args, "-i", indexURL)
+	}
+	return args
+}
+
+// lockHasHashes 锁文件中是否包含 --hash
+func lockHasHashes(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), "--hash=") {
+			return true, nil
+		}
+	}
+	return false, scanner.Err()
+}
+
+// DownloadWheels 将依赖及其全部传递依赖下载到 wheelhouse 目录
+// @param: python string 用于运行 pip 的 Python
+// @param: reqIn string 顶层依赖文件
+// @param: wheelhouse string 下载目录
+// @param: indexURL string pip 索引（为空表示默认索引）
+// @param: platform string 目标平台（如 win_amd64），为空表示当前平台
+// @param: pythonVersion string 目标 Python 版本（如 3.12），仅在指定平台时使用
+// @return: error
+func DownloadWheels(python, reqIn, wheelhouse, indexURL, platform, pythonVersion string) error {
+	args := []string{"-m", "pip", "download", "-r", reqIn, "-d", wheelhouse}
+	if indexURL != "" {
+		args = append(args, "-i", indexURL)
+	}
+	if platform != "" {
+		// 跨平台下载时 pip 只能使用二进制包
+		args = append(args, "--platform", platform, "--implementation", "cp", "--only-binary=:all:")
+		if pythonVersion != "" {
+			args = append(args, "--python-version", pythonVersion)
+		}
+	}
+	cmd := exec.Command(python, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("pip download 失败: %v", err)
+	}
+	return nil
+}
+
+// parsePackageFile 从 wheel/sdist 文件名解析包名和版本
+func parsePackageFile(name string) (string, string, bool) {
+	var base string
+	switch {
+	case strings.HasSuffix(name, ".whl"):
+		// {name}-{version}(-{build})?-{python}-{abi}-{platform}.whl
+		parts := strings.Split(strings.TrimSuffix(name, ".whl"), "-")
+		if len(parts) < 5 {
+			return "", "", false
+		}
+		return parts[0], parts[1], true
+	case strings.HasSuffix(name, ".tar.gz"):
+		base = strings.TrimSuffix(name, ".tar.gz")
+	case strings.HasSuffix(name, ".zip"):
+		base = strings.TrimSuffix(name, ".zip")
+	default:
+		return "", "", false
+	}
+	index := strings.LastIndex(base, "-")
+	if index <= 0 {
+		return "", "", false
+	}
+	return base[:index], base[index+1:], true
+}
+
+// GenerateLock 根据 wheelhouse 目录中的文件生成 pip 锁文件
+// @description: 每个包固定为 name==version，并列出目录中该版本所有文件的 SHA-256，配合 --require-hashes 使用
+// @param: wheelhouse string 包含全部依赖文件的目录
+// @param: out string 锁文件路径
+// @param: skip []string 需要忽略的文件名（如嵌入式 Python 压缩包）
+// @return: int 固定的包数量, error
+func GenerateLock(wheelhouse, out string, skip []string) (int, error) {
+	entries, err := os.ReadDir(wheelhouse)
+	if err != nil {
+		return 0, fmt.Errorf("读取目录 %s 失败: %v", wheelhouse, err)
+	}
+	skipped := make(map[string]bool, len(skip))
+	for _, name := range skip {
+		skipped[name] = true
+	}
+
+	hashes := make(map[string][]string) // name==version -> 哈希列表
+	for _, entry := range entries {
+		if entry.IsDir() || skipped[entry.Name()] {
+			continue
+		}
+		name, version, ok := parsePackageFile(entry.Name())
+		if !ok {
+			continue
+		}
+		sum, err := FileSHA256(filepath.Join(wheelhouse, entry.Name()))
+		if err != nil {
+			return 0, fmt.Errorf("计算 %s 的哈希失败: %v", entry.Name(), err)
+		}
+		pin := strings.ToLower(pipNameSeparator.ReplaceAllString(name, "-")) + "==" + version
+		hashes[pin] = append(hashes[pin], sum)
+	}
+	if len(hashes) == 0 {
+		return 0, fmt.Errorf("目录 %s 中没有 wheel 或源码包", wheelhouse)
+	}
+
+	pins := make([]string, 0, len(hashes))
+	for pin := range hashes {
+		pins = append(pins, pin)
+	}
+	sort.Strings(pins)
+
+	var builder strings.Builder
+	builder.WriteString("# 由 cmd/ocr_lock 生成，请勿手动修改\n")
+	builder.WriteString("# 安装: pip install --require-hashes -r " + filepath.Base(out) + "\n")
+	for _, pin := range pins {
+		sums := hashes[pin]
+		sort.Strings(sums)
+		builder.WriteString(pin)
+		for _, sum := range sums {
+			builder.WriteString(" \\\n    --hash=sha256:" + sum)
+		}
+		builder.WriteString("\n")
+	}
+	if err = os.WriteFile(out, []byte(builder.String()), 0644); err != nil {
+		return 0, fmt.Errorf("写入锁文件失败: %v", err)
+	}
+	return len(pins), nil
+}
@@ -0,0 +1,130 @@
+package ocrenv
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// serverStopTimeout 前台运行时收到退出信号后等待 OCR 服务自行退出的时间，超时后强制结束
+const serverStopTimeout = 10 * time.Second
+
+// VenvPython 获取虚拟环境中的 Python 路径
+// @param: venvDir string 虚拟环境目录
+// @return: string
+func VenvPython(venvDir string) string {
+	if runtime.GOOS == "windows" {
+		return filepath.Join(venvDir, "Scripts", "python.exe")
+	}
+	return filepath.Join(venvDir, "bin", "python")
+}
+
+// CheckDependencies 检查 Python 环境中是否已安装 OCR 服务依赖
+// @param: python string Python 可执行文件
+// @return: error
+func CheckDependencies(python string) error {
+	output, err := exec.Command(python, "-c", "import flask; import paddleocr").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("缺少 OCR 服务依赖: %v %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// EnsureVenv 创建虚拟环境并安装 OCR 服务依赖
+// @description: 依赖已就绪时直接返回；否则使用 basePython 创建虚拟环境，按当前系统的锁文件安装依赖（依次尝试清单中的索引）
+// @param: venvDir string 虚拟环境目录
+// @param: basePython string 用于创建虚拟环境的 Python（如 python3）
+// @param: localDir string 本地离线目录（为空表示联网安装）
+// @return: string 虚拟环境中的 Python 路径, error
+func (m *Manifest) EnsureVenv(venvDir, basePython, localDir string) (string, error) {
+	python := VenvPython(venvDir)
+	if _, err := os.Stat(python); err == nil && CheckDependencies(python) == nil {
+		return python, nil
+	}
+
+	if _, err := os.Stat(python); err != nil {
+		fmt.Printf("正在创建 Python 虚拟环境: %s\n", venvDir)
+		if err = runCommand(basePython, "-m", "venv", venvDir); err != nil {
+			return "", fmt.Errorf("创建虚拟环境失败（Debian/Ubuntu 需要安装 python3-venv）: %v", err)
+		}
+	}
+
+	reqFile, hashed, err := m.RequirementsFile(runtime.GOOS)
+	if err != nil {
+		return "", err
+	}
+	fmt.Println("正在安装 OCR 依赖（首次安装可能需要较长时间）...")
+	var installErr error
+	for _, index := range m.IndexSources(localDir) {
+		if index != "" {
+			fmt.Printf("使用 pip 索引: %s\n", index)
+		}
+		if installErr = runCommand(python, PipInstallArgs(reqFile, hashed, localDir, index)...); installErr == nil {
+			break
+		}
+		fmt.Printf("[WARN] 依赖安装失败: %v\n", installErr)
+	}
+	if installErr != nil {
+		return "", fmt.Errorf("安装 OCR 依赖失败: %v", installErr)
+	}
+	if err = CheckDependencies(python); err != nil {
+		return "", err
+	}
+	fmt.Println("OCR 依赖安装完成")
+	return python, nil
+}
+
+// MissingModels 获取清单中尚未安装的识别模型目录
+// @return: []string
+func (m *Manifest) MissingModels() []string {
+	var missing []string
+	for _, model := range m.Models {
+		dest, err := modelDest(model.Dest)
+		if err != nil {
+			missing = append(missing, model.File)
+			continue
+		}
+		modelDir := filepath.Join(dest, strings.TrimSuffix(model.File, ".tar"))
+		if _, err = os.Stat(modelDir); err != nil {
+			missing = append(missing, modelDir)
+		}
+	}
+	return missing
+}
+
+// RunServer 在前台运行 OCR 服务，直到进程退出或 ctx 取消
+// @description: 输出直接写到标准输出/错误（由 systemd 等进程管理器收集）；ctx 取消时先发送中断信号，超时后强制结束
+// @param: ctx context.Context
+// @param: python string Python 可执行文件
+// @param: script string ocr_server.py 路径
+// @param: host string 监听地址
+// @param: port int 监听端口
+// @return: error 进程的退出错误
+func RunServer(ctx context.Context, python, script, host string, port int) error {
+	cmd := exec.CommandContext(ctx, python, script, "--host", host, "--port", strconv.Itoa(port))
+	cmd.Dir = filepath.Dir(script)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if runtime.GOOS != "windows" {
+		cmd.Cancel = func() error {
+			return cmd.Process.Signal(os.Interrupt)
+		}
+	}
+	cmd.WaitDelay = serverStopTimeout
+	return cmd.Run()
+}
+
+// runCommand 运行命令，输出到控制台
+func runCommand(name string, args ...string) error {
+	cmd := exec.Command(name, args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
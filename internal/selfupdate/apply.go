@@ -0,0 +1,328 @@
+package selfupdate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	_const "qq_client/internal/const"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ApplyOptions 更新器参数
+type ApplyOptions struct {
+	Target      string   // 被替换的程序
+	Source      string   // 已校验的更新文件
+	SHA256      string   // 更新文件的 SHA-256，替换前再次校验
+	Version     string   // 更新版本
+	FromVersion string   // 当前版本（回滚后运行的版本）
+	PID         int      // 主程序进程号，替换前等待其退出（0 表示不等待）
+	Dir         string   // 重启时的工作目录
+	Args        []string // 重启时的命令行参数
+}
+
+// LaunchApply 启动更新器，由更新器在主程序退出后替换程序并重启
+// @description: 更新器是当前程序的副本（以 --apply-update 运行），与主程序分离，输出写入 logs/update.log；调用方启动后应尽快退出
+// @param: opts ApplyOptions（PID 为空时使用当前进程）
+// @return: error
+func LaunchApply(opts ApplyOptions) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("获取程序路径失败: %w", err)
+	}
+	if opts.PID == 0 {
+		opts.PID = os.Getpid()
+	}
+	if opts.Dir == "" {
+		if opts.Dir, err = os.Getwd(); err != nil {
+			return fmt.Errorf("获取工作目录失败: %w", err)
+		}
+	}
+
+	helper := filepath.Join(filepath.Dir(opts.Source), _const.UpdateHelperName)
+	if runtime.GOOS == "windows" {
+		helper += ".exe"
+	}
+	if err = copyFile(self, helper, 0755); err != nil {
+		return fmt.Errorf("创建更新器失败: %w", err)
+	}
+
+	args := []string{_const.UpdateApplyFlag,
+		"-target", opts.Target,
+		"-source", opts.Source,
+		"-sha256", opts.SHA256,
+		"-version", opts.Version,
+		"-from", opts.FromVersion,
+		"-pid", strconv.Itoa(opts.PID),
+		"-dir", opts.Dir,
+		"--"}
+	cmd := exec.Command(helper, append(args, opts.Args...)...)
+	cmd.Dir = opts.Dir
+	if err = os.MkdirAll(filepath.Dir(_const.UpdateLogFile), 0755); err == nil {
+		if logFile, err := os.OpenFile(_const.UpdateLogFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644); err == nil {
+			// 子进程持有日志文件的副本，启动后即可关闭
+			defer logFile.Close()
+			cmd.Stdout, cmd.Stderr = logFile, logFile
+		}
+	}
+	detach(cmd, false)
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("启动更新器失败: %w", err)
+	}
+	return cmd.Process.Release()
+}
+
+// RunApply 更新器入口（main 在参数为 --apply-update 时调用）
+// @param: args []string --apply-update 之后的参数
+// @return: int 进程退出码
+func RunApply(args []string) int {
+	var opts ApplyOptions
+	flags := flag.NewFlagSet(_const.UpdateApplyFlag, flag.ContinueOnError)
+	flags.StringVar(&opts.Target, "target", "", "被替换的程序")
+	flags.StringVar(&opts.Source, "source", "", "已校验的更新文件")
+	flags.StringVar(&opts.SHA256, "sha256", "", "更新文件的 SHA-256")
+	flags.StringVar(&opts.Version, "version", "", "更新版本")
+	flags.StringVar(&opts.FromVersion, "from", "", "当前版本")
+	flags.IntVar(&opts.PID, "pid", 0, "等待退出的主程序进程号")
+	flags.StringVar(&opts.Dir, "dir", "", "重启时的工作目录")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	opts.Args = flags.Args()
+
+	fmt.Printf("[%s] 开始更新 %s\n", time.Now().Format(time.RFC3339), opts.Target)
+	if err := Apply(opts); err != nil {
+		fmt.Printf("[%s] 更新失败: %v\n", time.Now().Format(time.RFC3339), err)
+		return 1
+	}
+	fmt.Printf("[%s] 更新完成\n", time.Now().Format(time.RFC3339))
+	return 0
+}
+
+// Apply 替换程序并重启
+// @description: 等待主程序退出 -> 校验更新文件 -> 复制到目标目录 -> 旧程序改名为 .backup -> 新程序改名为目标 -> 以试运行模式启动；
+// 新版本启动失败、提前退出或未在试运行时间内通过健康检查时，恢复旧程序并重新启动，结果写入 update_result.json 由旧程序上报
+// @param: opts ApplyOptions
+// @return: error
+func Apply(opts ApplyOptions) error {
+	if opts.Target == "" || opts.Source == "" || opts.SHA256 == "" {
+		return fmt.Errorf("缺少 -target、-source 或 -sha256")
+	}
+	if opts.PID > 0 && !waitProcessExit(opts.PID, _const.UpdateParentExitTimeout) {
+		return fmt.Errorf("主程序 (PID %d) 在 %v 内未退出", opts.PID, _const.UpdateParentExitTimeout)
+	}
+
+	// 下载后到替换前文件可能被改动，替换前再次校验
+	sum, err := fileSHA256(opts.Source)
+	if err != nil {
+		return fmt.Errorf("读取更新文件失败: %w", err)
+	}
+	if !strings.EqualFold(sum, opts.SHA256) {
+		return &VerifyError{Reason: ReasonChecksumMismatch, Detail: fmt.Sprintf("期望 %s，实际 %s", strings.ToLower(opts.SHA256), sum)}
+	}
+
+	backup := opts.Target + _const.UpdateBackupSuffix
+	if err = swap(opts.Target, opts.Source, backup); err != nil {
+		return err
+	}
+	fmt.Printf("已替换程序，旧版本备份为 %s\n", backup)
+
+	healthFile := filepath.Join(opts.Dir, _const.UpdateTempDir, _const.UpdateHealthFile)
+	_ = os.Remove(healthFile)
+	var reason string
+	cmd, err := start(opts.Target, opts.Dir, append([]string{_const.UpdateProbationFlag + "=" + healthFile}, opts.Args...))
+	if err != nil {
+		reason = fmt.Sprintf("启动新版本失败: %v", err)
+	} else {
+		fmt.Printf("新版本已启动 (PID %d)，等待健康检查...\n", cmd.Process.Pid)
+		reason = waitHealthy(cmd, healthFile, _const.UpdateProbationWindow)
+	}
+	_ = os.Remove(healthFile)
+	if reason != "" {
+		return rollback(opts, backup, reason)
+	}
+	_ = os.Remove(opts.Source)
+	return nil
+}
+
+// waitHealthy 等待试运行的新版本通过全部健康检查
+// 超时后结束新版本进程
+// @return: string 未通过的原因，通过时为空
+func waitHealthy(cmd *exec.Cmd, healthFile string, window time.Duration) string {
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+	healthy := func() bool {
+		health, err := readHealth(healthFile)
+		return err == nil && health.Healthy && health.PID == cmd.Process.Pid
+	}
+
+	ticker := time.NewTicker(_const.UpdateProbationPoll)
+	defer ticker.Stop()
+	deadline := time.NewTimer(window)
+	defer deadline.Stop()
+	for {
+		select {
+		case err := <-exited:
+			if healthy() {
+				return ""
+			}
+			if err == nil {
+				return "新版本在通过健康检查前退出"
+			}
+			return fmt.Sprintf("新版本在通过健康检查前退出: %v", err)
+		case <-ticker.C:
+			if healthy() {
+				return ""
+			}
+		case <-deadline.C:
+			if healthy() {
+				return ""
+			}
+			failed := probationChecks
+			if health, err := readHealth(healthFile); err == nil {
+				failed = health.failedChecks()
+			}
+			_ = cmd.Process.Kill()
+			<-exited
+			return fmt.Sprintf("%v 内未通过健康检查: %s", window, strings.Join(failed, ", "))
+		}
+	}
+}
+
+// rollback 恢复并启动旧程序，写入回滚结果
+func rollback(opts ApplyOptions, backup, reason string) error {
+	fmt.Printf("正在恢复旧版本: %s\n", reason)
+	if err := restore(opts.Target, backup); err != nil {
+		return fmt.Errorf("%s；恢复旧版本失败: %v（旧程序位于 %s）", reason, err, backup)
+	}
+
+	result := Result{
+		Status:      _const.UpdateStatusRolledBack,
+		Version:     opts.Version,
+		FromVersion: opts.FromVersion,
+		Reason:      reason,
+		Time:        time.Now(),
+	}
+	if err := writeResult(opts.Dir, result); err != nil {
+		fmt.Printf("[WARN] 写入更新结果失败: %v\n", err)
+	}
+
+	cmd, err := start(opts.Target, opts.Dir, opts.Args)
+	if err != nil {
+		return fmt.Errorf("%s；已恢复旧版本但启动失败: %v", reason, err)
+	}
+	_ = cmd.Process.Release()
+	return fmt.Errorf("已回滚到旧版本: %s", reason)
+}
+
+// swap 用更新文件替换目标程序，旧程序保留为 backup
+// 新文件先复制到目标目录再改名，保证替换在同一文件系统内完成；任一步失败时目标程序保持不变
+func swap(target, source, backup string) error {
+	mode := os.FileMode(0755)
+	if info, err := os.Stat(target); err == nil {
+		mode = info.Mode().Perm()
+	}
+	staged := target + ".new"
+	if err := copyFile(source, staged, mode); err != nil {
+		_ = os.Remove(staged)
+		return fmt.Errorf("复制更新文件失败: %w", err)
+	}
+
+	_ = os.Remove(backup)
+	if err := renameRetry(target, backup); err != nil {
+		_ = os.Remove(staged)
+		return fmt.Errorf("备份旧程序失败: %w", err)
+	}
+	if err := renameRetry(staged, target); err != nil {
+		if restoreErr := renameRetry(backup, target); restoreErr != nil {
+			return fmt.Errorf("替换程序失败: %v；恢复旧程序失败: %v（旧程序位于 %s）", err, restoreErr, backup)
+		}
+		_ = os.Remove(staged)
+		return fmt.Errorf("替换程序失败，已恢复旧程序: %w", err)
+	}
+	return nil
+}
+
+// restore 用 backup 恢复目标程序（失败的新版本改名为 .failed 便于排查）
+func restore(target, backup string) error {
+	failed := target + ".failed"
+	_ = os.Remove(failed)
+	if err := renameRetry(target, failed); err != nil {
+		return err
+	}
+	return renameRetry(backup, target)
+}
+
+// start 启动程序（与更新器分离，更新器退出后继续运行）
+// 不继承更新器的输出（logs/update.log）：Windows 下程序在新的控制台窗口中运行（见 RestoreConsoleOutput），其他系统不输出到终端
+func start(path, dir string, args []string) (*exec.Cmd, error) {
+	cmd := exec.Command(path, args...)
+	cmd.Dir = dir
+	detach(cmd, true)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// 改名和重试间隔（测试中替换以模拟改名失败）
+var (
+	renameFile       = os.Rename
+	renameRetryDelay = _const.UpdateRenameRetryDelay
+)
+
+// renameRetry 改名，失败时重试（Windows 下文件可能被杀毒软件等短暂占用）
+func renameRetry(from, to string) error {
+	var err error
+	for i := 0; i < _const.UpdateRenameRetries; i++ {
+		if err = renameFile(from, to); err == nil {
+			return nil
+		}
+		time.Sleep(renameRetryDelay)
+	}
+	return err
+}
+
+// copyFile 复制文件并同步到磁盘
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err == nil {
+		err = out.Sync()
+	}
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Chmod(dst, mode)
+}
+
+// fileSHA256 计算文件的 SHA-256（小写十六进制）
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
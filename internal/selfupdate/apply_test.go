@@ -0,0 +1,184 @@
+package selfupdate
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	_const "qq_client/internal/const"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// writeFile 写入测试文件
+func writeFile(t *testing.T, path, content string, mode os.FileMode) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), mode); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertContent 文件内容应为 want
+func assertContent(t *testing.T, path, want string) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read %s: %v", filepath.Base(path), err)
+	}
+	if string(data) != want {
+		t.Fatalf("%s = %q, want %q", filepath.Base(path), data, want)
+	}
+}
+
+// assertMissing 文件不应存在
+func assertMissing(t *testing.T, path string) {
+	t.Helper()
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("%s should not exist (err = %v)", filepath.Base(path), err)
+	}
+}
+
+// failRename 让改名到 target 的操作失败，其他改名正常执行
+func failRename(t *testing.T, target string) {
+	t.Helper()
+	previousRename, previousDelay := renameFile, renameRetryDelay
+	renameFile = func(from, to string) error {
+		if to == target && strings.HasSuffix(from, ".new") {
+			return &os.LinkError{Op: "rename", Old: from, New: to, Err: os.ErrPermission}
+		}
+		return os.Rename(from, to)
+	}
+	renameRetryDelay = 0
+	t.Cleanup(func() { renameFile, renameRetryDelay = previousRename, previousDelay })
+}
+
+// TestSwapReplacesTarget 替换后目标为新程序，旧程序保留为备份，不留下中间文件
+func TestSwapReplacesTarget(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "scum_client")
+	source := filepath.Join(t.TempDir(), "download.new")
+	backup := target + _const.UpdateBackupSuffix
+	writeFile(t, target, "old", 0755)
+	writeFile(t, source, "new", 0644)
+
+	if err := swap(target, source, backup); err != nil {
+		t.Fatal(err)
+	}
+	assertContent(t, target, "new")
+	assertContent(t, backup, "old")
+	assertMissing(t, target+".new")
+	if info, err := os.Stat(target); err == nil && runtime.GOOS != "windows" && info.Mode().Perm() != 0755 {
+		t.Fatalf("target mode = %v, want the old program's 0755", info.Mode().Perm())
+	}
+}
+
+// TestSwapRestoresWhenRenameFails 新程序改名失败时恢复旧程序
+func TestSwapRestoresWhenRenameFails(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "scum_client")
+	source := filepath.Join(t.TempDir(), "download.new")
+	backup := target + _const.UpdateBackupSuffix
+	writeFile(t, target, "old", 0755)
+	writeFile(t, source, "new", 0644)
+	failRename(t, target)
+
+	err := swap(target, source, backup)
+	if err == nil || !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("swap err = %v, want the rename error", err)
+	}
+	assertContent(t, target, "old")
+	assertMissing(t, backup)
+	assertMissing(t, target+".new")
+	assertContent(t, source, "new")
+}
+
+// TestRestore 恢复备份，失败的新版本保留为 .failed
+func TestRestore(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "scum_client")
+	backup := target + _const.UpdateBackupSuffix
+	writeFile(t, target, "new", 0755)
+	writeFile(t, backup, "old", 0755)
+
+	if err := restore(target, backup); err != nil {
+		t.Fatal(err)
+	}
+	assertContent(t, target, "old")
+	assertContent(t, target+".failed", "new")
+	assertMissing(t, backup)
+}
+
+// applyFixture 更新目录：当前程序 oldScript，下载的更新 newScript（shell 脚本代替程序）
+func applyFixture(t *testing.T, oldScript, newScript string) ApplyOptions {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("使用 shell 脚本代替程序")
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, _const.UpdateTempDir), 0755); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "scum_client")
+	source := filepath.Join(dir, _const.UpdateTempDir, "scum_client_1.0.1.new")
+	writeFile(t, target, oldScript, 0755)
+	writeFile(t, source, newScript, 0644)
+	return ApplyOptions{
+		Target:      target,
+		Source:      source,
+		SHA256:      sha256Hex([]byte(newScript)),
+		Version:     "1.0.1",
+		FromVersion: "1.0.0",
+		Dir:         dir,
+	}
+}
+
+// TestApplyHealthyVersion 新版本通过健康检查后保留新版本并删除下载的文件
+func TestApplyHealthyVersion(t *testing.T) {
+	newScript := "#!/bin/sh\n" +
+		"f=\"${1#" + _const.UpdateProbationFlag + "=}\"\n" +
+		"printf '{\"pid\":%d,\"healthy\":true}' $$ > \"$f\"\n" +
+		"sleep 2\n"
+	opts := applyFixture(t, "#!/bin/sh\nexit 0\n", newScript)
+
+	if err := Apply(opts); err != nil {
+		t.Fatal(err)
+	}
+	assertContent(t, opts.Target, newScript)
+	assertContent(t, opts.Target+_const.UpdateBackupSuffix, "#!/bin/sh\nexit 0\n")
+	assertMissing(t, opts.Source)
+	if result := PendingResult(opts.Dir); result != nil {
+		t.Fatalf("unexpected update result: %+v", result)
+	}
+}
+
+// TestApplyRollsBackWhenNewVersionExits 新版本在通过健康检查前退出时恢复旧版本并写入 rolled_back 结果
+func TestApplyRollsBackWhenNewVersionExits(t *testing.T) {
+	oldScript := "#!/bin/sh\nexit 0\n"
+	newScript := "#!/bin/sh\nexit 3\n"
+	opts := applyFixture(t, oldScript, newScript)
+
+	err := Apply(opts)
+	if err == nil || !strings.Contains(err.Error(), "已回滚") {
+		t.Fatalf("Apply err = %v, want rollback", err)
+	}
+	assertContent(t, opts.Target, oldScript)
+	assertContent(t, opts.Target+".failed", newScript)
+	assertMissing(t, opts.Target+_const.UpdateBackupSuffix)
+
+	result := PendingResult(opts.Dir)
+	if result == nil || result.Status != _const.UpdateStatusRolledBack || result.Version != "1.0.1" || result.FromVersion != "1.0.0" {
+		t.Fatalf("update result = %+v, want rolled_back 1.0.0 <- 1.0.1", result)
+	}
+}
+
+// TestApplyChecksumMismatch 更新文件在替换前被改动时不替换程序
+func TestApplyChecksumMismatch(t *testing.T) {
+	opts := applyFixture(t, "#!/bin/sh\nexit 0\n", "#!/bin/sh\nexit 0\n# new\n")
+	writeFile(t, opts.Source, "#!/bin/sh\necho tampered\n", 0644)
+
+	if err := Apply(opts); Reason(err) != ReasonChecksumMismatch {
+		t.Fatalf("Apply err = %v, want %s", err, ReasonChecksumMismatch)
+	}
+	assertContent(t, opts.Target, "#!/bin/sh\nexit 0\n")
+	assertMissing(t, opts.Target+_const.UpdateBackupSuffix)
+}
@@ -0,0 +1,213 @@
+package selfupdate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	_const "qq_client/internal/const"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 更新后的健康检查项，全部通过后新版本才算更新成功
+const (
+	CheckConfig = "config" // 配置文件解析成功
+	CheckOCR    = "ocr"    // OCR 服务可用
+	CheckAuth   = "auth"   // WebSocket 认证成功
+)
+
+// probationChecks 全部健康检查项
+var probationChecks = []string{CheckConfig, CheckOCR, CheckAuth}
+
+// Health 新版本试运行期间写入的健康状态
+type Health struct {
+	Version   string          `json:"version"`
+	PID       int             `json:"pid"`
+	Checks    map[string]bool `json:"checks"`
+	Healthy   bool            `json:"healthy"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// Result 更新器写入的更新结果，由下次启动的客户端认证后上报
+type Result struct {
+	Status      string    `json:"status"`       // rolled_back 等
+	Version     string    `json:"version"`      // 尝试更新到的版本
+	FromVersion string    `json:"from_version"` // 恢复后运行的版本
+	Reason      string    `json:"reason"`
+	Time        time.Time `json:"time"`
+}
+
+var (
+	probationMutex     sync.Mutex
+	probationFile      string // 为空表示不在试运行
+	probationHealth    Health // 当前健康状态
+	probationOnHealthy func() // 全部检查通过时调用一次
+	probationNotified  bool   // 已调用 probationOnHealthy
+)
+
+// ParseProbationArgs 从命令行参数中取出 --probation=<文件>
+// @description: 试运行参数只对本次启动有效，取出后的参数用于之后的重启
+// @param: args []string 命令行参数（不含程序名）
+// @return: string 健康状态文件（不在试运行时为空）, []string 剩余参数
+func ParseProbationArgs(args []string) (string, []string) {
+	prefix := _const.UpdateProbationFlag + "="
+	rest := make([]string, 0, len(args))
+	file := ""
+	for _, arg := range args {
+		if strings.HasPrefix(arg, prefix) {
+			file = strings.TrimPrefix(arg, prefix)
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return file, rest
+}
+
+// StartProbation 进入试运行（由更新器启动的新版本调用）
+// @param: file string 健康状态文件
+// @param: version string 当前版本
+func StartProbation(file, version string) {
+	probationMutex.Lock()
+	defer probationMutex.Unlock()
+	probationFile = file
+	probationHealth = Health{Version: version, PID: os.Getpid(), Checks: make(map[string]bool)}
+	for _, check := range probationChecks {
+		probationHealth.Checks[check] = false
+	}
+	fmt.Printf("更新试运行中，需要在 %v 内通过健康检查: %v\n", _const.UpdateProbationWindow, probationChecks)
+	writeProbationHealth()
+}
+
+// InProbation 是否处于试运行
+func InProbation() bool {
+	probationMutex.Lock()
+	defer probationMutex.Unlock()
+	return probationFile != ""
+}
+
+// SetOnHealthy 设置全部检查通过时的回调（用于上报更新完成）
+func SetOnHealthy(callback func()) {
+	probationMutex.Lock()
+	probationOnHealthy = callback
+	notify := probationFile != "" && probationHealth.Healthy && !probationNotified
+	if notify {
+		probationNotified = true
+	}
+	probationMutex.Unlock()
+	if notify && callback != nil {
+		callback()
+	}
+}
+
+// MarkHealthy 记录一项健康检查通过（不在试运行时忽略）
+// @param: check string CheckConfig/CheckOCR/CheckAuth
+func MarkHealthy(check string) {
+	probationMutex.Lock()
+	if probationFile == "" || probationHealth.Checks[check] {
+		probationMutex.Unlock()
+		return
+	}
+	probationHealth.Checks[check] = true
+	healthy := true
+	for _, passed := range probationHealth.Checks {
+		healthy = healthy && passed
+	}
+	probationHealth.Healthy = healthy
+	writeProbationHealth()
+
+	var callback func()
+	if healthy && !probationNotified {
+		fmt.Println("更新后的健康检查全部通过")
+		probationNotified = true
+		callback = probationOnHealthy
+	}
+	probationMutex.Unlock()
+	if callback != nil {
+		callback()
+	}
+}
+
+// writeProbationHealth 写入健康状态（调用方持有锁）
+func writeProbationHealth() {
+	probationHealth.UpdatedAt = time.Now()
+	data, err := json.Marshal(probationHealth)
+	if err == nil {
+		err = writeFileAtomic(probationFile, data)
+	}
+	if err != nil {
+		fmt.Printf("[WARN] 写入更新健康状态失败: %v\n", err)
+	}
+}
+
+// readHealth 读取健康状态
+func readHealth(file string) (Health, error) {
+	var health Health
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return health, err
+	}
+	err = json.Unmarshal(data, &health)
+	return health, err
+}
+
+// failedChecks 未通过的检查项
+func (h Health) failedChecks() []string {
+	var failed []string
+	for _, check := range probationChecks {
+		if !h.Checks[check] {
+			failed = append(failed, check)
+		}
+	}
+	return failed
+}
+
+// resultPath 更新结果文件路径
+func resultPath(dir string) string {
+	return filepath.Join(dir, _const.UpdateTempDir, _const.UpdateResultFile)
+}
+
+// writeResult 写入更新结果
+func writeResult(dir string, result Result) error {
+	if err := os.MkdirAll(filepath.Join(dir, _const.UpdateTempDir), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(resultPath(dir), data)
+}
+
+// PendingResult 读取尚未上报的更新结果
+// @param: dir string 程序工作目录
+// @return: *Result 没有待上报的结果时为 nil
+func PendingResult(dir string) *Result {
+	data, err := os.ReadFile(resultPath(dir))
+	if err != nil {
+		return nil
+	}
+	var result Result
+	if err = json.Unmarshal(data, &result); err != nil {
+		fmt.Printf("[WARN] 解析更新结果失败: %v\n", err)
+		_ = os.Remove(resultPath(dir))
+		return nil
+	}
+	return &result
+}
+
+// ClearPendingResult 删除已上报的更新结果
+// @param: dir string 程序工作目录
+func ClearPendingResult(dir string) {
+	_ = os.Remove(resultPath(dir))
+}
+
+// writeFileAtomic 写入临时文件后改名，读取方不会读到写了一半的内容
+func writeFileAtomic(path string, data []byte) error {
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package selfupdate
+
+import (
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// waitProcessExit 等待进程退出
+// @return: bool 超时前进程已退出（或不存在）
+func waitProcessExit(pid int, timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+	for syscall.Kill(pid, 0) == nil {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return true
+}
+
+// detach 使子进程脱离当前进程（新的会话，不随主程序的终端退出）
+func detach(cmd *exec.Cmd, _ bool) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// RestoreConsoleOutput 只在 Windows 下需要（更新器启动的程序使用新的控制台窗口）
+func RestoreConsoleOutput() {}
@@ -0,0 +1,60 @@
+//go:build windows
+
+package selfupdate
+
+import (
+	"log"
+	"os"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// 进程创建标志（syscall 包未定义）
+const (
+	detachedProcess  = 0x00000008 // DETACHED_PROCESS：不继承父进程的控制台
+	createNewConsole = 0x00000010 // CREATE_NEW_CONSOLE：使用新的控制台窗口
+)
+
+// waitProcessExit 等待进程退出
+// @return: bool 超时前进程已退出（或不存在）
+func waitProcessExit(pid int, timeout time.Duration) bool {
+	handle, err := syscall.OpenProcess(syscall.SYNCHRONIZE, false, uint32(pid))
+	if err != nil {
+		return true
+	}
+	defer syscall.CloseHandle(handle)
+	event, err := syscall.WaitForSingleObject(handle, uint32(timeout/time.Millisecond))
+	return err == nil && event != syscall.WAIT_TIMEOUT
+}
+
+// detach 使子进程脱离当前进程（新的进程组）
+// console 为 true 时使用新的控制台窗口（重启的主程序），否则不显示窗口（更新器）
+func detach(cmd *exec.Cmd, console bool) {
+	flags := uint32(syscall.CREATE_NEW_PROCESS_GROUP)
+	if console {
+		flags |= createNewConsole
+	} else {
+		flags |= detachedProcess
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: flags, HideWindow: !console}
+}
+
+// RestoreConsoleOutput 标准输出是空设备但进程有自己的控制台时（由更新器以 CREATE_NEW_CONSOLE 启动），改为输出到控制台
+// 输出被重定向到文件或管道时不做改动
+func RestoreConsoleOutput() {
+	handle, err := syscall.GetStdHandle(syscall.STD_OUTPUT_HANDLE)
+	if err != nil || handle == 0 {
+		return
+	}
+	var mode uint32
+	if fileType, _ := syscall.GetFileType(handle); fileType != syscall.FILE_TYPE_CHAR || syscall.GetConsoleMode(handle, &mode) == nil {
+		return
+	}
+	console, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	os.Stdout, os.Stderr = console, console
+	log.SetOutput(os.Stderr)
+}
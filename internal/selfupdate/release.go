@@ -0,0 +1,161 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	_const "qq_client/internal/const"
+	"strings"
+)
+
+// 校验失败原因（client_update 消息的 reason 字段）
+const (
+	ReasonInvalidVersion   = "invalid_version"   // 版本号无法解析
+	ReasonDowngrade        = "downgrade"         // 版本不高于当前版本
+	ReasonUnsigned         = "unsigned"          // 缺少哈希或签名
+	ReasonNoPublicKey      = "no_public_key"     // 当前程序未内置签名公钥
+	ReasonBadSignature     = "bad_signature"     // 签名校验失败
+	ReasonChecksumMismatch = "checksum_mismatch" // 下载文件的 SHA-256 与消息不一致
+	ReasonDownloadFailed   = "download_failed"   // 下载失败
+)
+
+// VerifyError 更新校验错误，Reason 为失败原因
+type VerifyError struct {
+	Reason string
+	Detail string
+}
+
+func (e *VerifyError) Error() string {
+	return e.Reason + ": " + e.Detail
+}
+
+// Reason 获取错误的失败原因，非 VerifyError 返回空
+func Reason(err error) string {
+	var verifyErr *VerifyError
+	if errors.As(err, &verifyErr) {
+		return verifyErr.Reason
+	}
+	return ""
+}
+
+// maxDownloadSize 更新文件最大大小（测试中调小）
+var maxDownloadSize int64 = _const.UpdateMaxSize
+
+// Release 服务端下发的更新
+type Release struct {
+	Version     string `json:"version"`      // 语义化版本
+	DownloadURL string `json:"download_url"` // 下载地址
+	SHA256      string `json:"sha256"`       // 更新文件的 SHA-256（小写十六进制）
+	Signature   string `json:"signature"`    // ed25519 签名（base64），签名内容见 SignedMessage
+}
+
+// SignedMessage 签名内容，同时绑定版本和文件哈希，防止把旧版本的已签名文件标为新版本下发
+// @param: version Version
+// @param: sha256Hex string 文件 SHA-256
+// @return: []byte
+func SignedMessage(version Version, sha256Hex string) []byte {
+	return []byte("scum_client\n" + version.String() + "\n" + strings.ToLower(sha256Hex))
+}
+
+// Check 下载前检查版本、哈希和签名
+// @description: 拒绝无法解析或不高于当前版本的版本，拒绝缺少哈希、签名或签名无效的更新
+// @param: current string 当前版本
+// @param: publicKey string 签名公钥（base64）
+// @return: Version 更新版本, error（*VerifyError）
+func (r Release) Check(current, publicKey string) (Version, error) {
+	version, err := ParseVersion(r.Version)
+	if err != nil {
+		return Version{}, &VerifyError{Reason: ReasonInvalidVersion, Detail: err.Error()}
+	}
+	currentVersion, err := ParseVersion(current)
+	if err != nil {
+		return Version{}, &VerifyError{Reason: ReasonInvalidVersion, Detail: fmt.Sprintf("当前版本 %s 无法解析: %v", current, err)}
+	}
+	if version.Compare(currentVersion) <= 0 {
+		return Version{}, &VerifyError{Reason: ReasonDowngrade, Detail: fmt.Sprintf("更新版本 %s 不高于当前版本 %s", version, currentVersion)}
+	}
+
+	if r.SHA256 == "" || r.Signature == "" {
+		return Version{}, &VerifyError{Reason: ReasonUnsigned, Detail: "更新缺少 sha256 或 signature"}
+	}
+	if _, err = hex.DecodeString(r.SHA256); err != nil || len(r.SHA256) != sha256.Size*2 {
+		return Version{}, &VerifyError{Reason: ReasonUnsigned, Detail: fmt.Sprintf("无效的 sha256: %q", r.SHA256)}
+	}
+	if publicKey == "" {
+		return Version{}, &VerifyError{Reason: ReasonNoPublicKey, Detail: "当前程序未内置更新签名公钥，无法校验更新"}
+	}
+	key, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return Version{}, &VerifyError{Reason: ReasonNoPublicKey, Detail: "内置的更新签名公钥无效"}
+	}
+	signature, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return Version{}, &VerifyError{Reason: ReasonBadSignature, Detail: "签名格式无效"}
+	}
+	if !ed25519.Verify(key, SignedMessage(version, r.SHA256), signature) {
+		return Version{}, &VerifyError{Reason: ReasonBadSignature, Detail: fmt.Sprintf("版本 %s 的签名校验失败", version)}
+	}
+	return version, nil
+}
+
+// Download 下载更新文件并校验 SHA-256（调用前需通过 Check）
+// @description: 边下载边计算哈希，校验失败时删除文件
+// @param: ctx context.Context
+// @param: version Version Check 返回的版本
+// @param: dir string 下载目录
+// @return: string 下载的文件路径, error（校验失败为 *VerifyError）
+func (r Release) Download(ctx context.Context, version Version, dir string) (string, error) {
+	if r.DownloadURL == "" {
+		return "", &VerifyError{Reason: ReasonDownloadFailed, Detail: "缺少 download_url"}
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("创建下载目录失败: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, _const.UpdateTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.DownloadURL, nil)
+	if err != nil {
+		return "", &VerifyError{Reason: ReasonDownloadFailed, Detail: err.Error()}
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", &VerifyError{Reason: ReasonDownloadFailed, Detail: err.Error()}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", &VerifyError{Reason: ReasonDownloadFailed, Detail: fmt.Sprintf("HTTP %d", resp.StatusCode)}
+	}
+
+	path := filepath.Join(dir, "scum_client_"+version.String()+".new")
+	out, err := os.Create(path)
+	if err != nil {
+		return "", fmt.Errorf("创建更新文件失败: %w", err)
+	}
+	hasher := sha256.New()
+	written, err := io.Copy(io.MultiWriter(out, hasher), io.LimitReader(resp.Body, maxDownloadSize+1))
+	if closeErr := out.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written > maxDownloadSize {
+		err = fmt.Errorf("更新文件超过 %d 字节", maxDownloadSize)
+	}
+	if err != nil {
+		_ = os.Remove(path)
+		return "", &VerifyError{Reason: ReasonDownloadFailed, Detail: err.Error()}
+	}
+
+	if sum := hex.EncodeToString(hasher.Sum(nil)); !strings.EqualFold(sum, r.SHA256) {
+		_ = os.Remove(path)
+		return "", &VerifyError{Reason: ReasonChecksumMismatch, Detail: fmt.Sprintf("期望 %s，实际 %s", strings.ToLower(r.SHA256), sum)}
+	}
+	return path, nil
+}
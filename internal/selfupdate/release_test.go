@@ -0,0 +1,196 @@
+package selfupdate
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+// serveFile 本地下载服务，返回 body
+func serveFile(t *testing.T, body []byte) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// entries 目录中的文件名
+func entries(t *testing.T, dir string) []string {
+	t.Helper()
+	list, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(list))
+	for i, entry := range list {
+		names[i] = entry.Name()
+	}
+	return names
+}
+
+// testKey 测试中生成的签名密钥，返回 base64 公钥
+func testKey(t *testing.T) (string, ed25519.PrivateKey) {
+	t.Helper()
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(public), private
+}
+
+// sign 按 SignedMessage 对版本和哈希签名
+func sign(t *testing.T, key ed25519.PrivateKey, version, sha string) string {
+	t.Helper()
+	parsed, err := ParseVersion(version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(key, SignedMessage(parsed, sha)))
+}
+
+// TestCheck 版本必须高于当前版本，哈希和签名必须由内置公钥对同一版本和哈希签发
+func TestCheck(t *testing.T) {
+	publicKey, privateKey := testKey(t)
+	otherPublicKey, otherPrivateKey := testKey(t)
+	sha := sha256Hex([]byte("scum_client 1.5.0"))
+	otherSHA := sha256Hex([]byte("another build"))
+
+	tests := []struct {
+		name      string
+		release   Release
+		current   string
+		publicKey string
+		want      string // 期望的 Reason，为空表示通过
+	}{
+		{"valid", Release{Version: "1.5.0", SHA256: sha, Signature: sign(t, privateKey, "1.5.0", sha)}, "1.4.0", publicKey, ""},
+		{"v prefix", Release{Version: "v1.5.0", SHA256: sha, Signature: sign(t, privateKey, "1.5.0", sha)}, "v1.4.0", publicKey, ""},
+		{"uppercase sha256", Release{Version: "1.5.0", SHA256: strings.ToUpper(sha), Signature: sign(t, privateKey, "1.5.0", sha)}, "1.4.0", publicKey, ""},
+		{"release over pre-release", Release{Version: "1.5.0", SHA256: sha, Signature: sign(t, privateKey, "1.5.0", sha)}, "1.5.0-rc.2", publicKey, ""},
+		{"newer pre-release", Release{Version: "1.5.0-rc.2", SHA256: sha, Signature: sign(t, privateKey, "1.5.0-rc.2", sha)}, "1.5.0-rc.1", publicKey, ""},
+
+		{"same version", Release{Version: "1.4.0", SHA256: sha, Signature: sign(t, privateKey, "1.4.0", sha)}, "1.4.0", publicKey, ReasonDowngrade},
+		{"same version with build metadata", Release{Version: "1.4.0+2", SHA256: sha, Signature: sign(t, privateKey, "1.4.0+2", sha)}, "1.4.0+1", publicKey, ReasonDowngrade},
+		{"downgrade", Release{Version: "1.3.9", SHA256: sha, Signature: sign(t, privateKey, "1.3.9", sha)}, "1.4.0", publicKey, ReasonDowngrade},
+		{"pre-release of current", Release{Version: "1.4.0-rc.9", SHA256: sha, Signature: sign(t, privateKey, "1.4.0-rc.9", sha)}, "1.4.0", publicKey, ReasonDowngrade},
+		{"older pre-release", Release{Version: "1.5.0-beta", SHA256: sha, Signature: sign(t, privateKey, "1.5.0-beta", sha)}, "1.5.0-rc.1", publicKey, ReasonDowngrade},
+
+		{"invalid version", Release{Version: "1.5", SHA256: sha, Signature: sign(t, privateKey, "1.5.0", sha)}, "1.4.0", publicKey, ReasonInvalidVersion},
+		{"invalid current version", Release{Version: "1.5.0", SHA256: sha, Signature: sign(t, privateKey, "1.5.0", sha)}, "dev", publicKey, ReasonInvalidVersion},
+
+		{"missing signature", Release{Version: "1.5.0", SHA256: sha}, "1.4.0", publicKey, ReasonUnsigned},
+		{"missing sha256", Release{Version: "1.5.0", Signature: sign(t, privateKey, "1.5.0", sha)}, "1.4.0", publicKey, ReasonUnsigned},
+		{"short sha256", Release{Version: "1.5.0", SHA256: sha[:32], Signature: sign(t, privateKey, "1.5.0", sha[:32])}, "1.4.0", publicKey, ReasonUnsigned},
+		{"non-hex sha256", Release{Version: "1.5.0", SHA256: strings.Repeat("z", 64), Signature: sign(t, privateKey, "1.5.0", sha)}, "1.4.0", publicKey, ReasonUnsigned},
+
+		{"no public key", Release{Version: "1.5.0", SHA256: sha, Signature: sign(t, privateKey, "1.5.0", sha)}, "1.4.0", "", ReasonNoPublicKey},
+		{"invalid public key", Release{Version: "1.5.0", SHA256: sha, Signature: sign(t, privateKey, "1.5.0", sha)}, "1.4.0", base64.StdEncoding.EncodeToString([]byte("short")), ReasonNoPublicKey},
+
+		{"signature not base64", Release{Version: "1.5.0", SHA256: sha, Signature: "not base64!"}, "1.4.0", publicKey, ReasonBadSignature},
+		{"truncated signature", Release{Version: "1.5.0", SHA256: sha, Signature: base64.StdEncoding.EncodeToString(make([]byte, 10))}, "1.4.0", publicKey, ReasonBadSignature},
+		{"wrong public key", Release{Version: "1.5.0", SHA256: sha, Signature: sign(t, privateKey, "1.5.0", sha)}, "1.4.0", otherPublicKey, ReasonBadSignature},
+		{"signed by another key", Release{Version: "1.5.0", SHA256: sha, Signature: sign(t, otherPrivateKey, "1.5.0", sha)}, "1.4.0", publicKey, ReasonBadSignature},
+		{"signature for another version", Release{Version: "1.6.0", SHA256: sha, Signature: sign(t, privateKey, "1.5.0", sha)}, "1.4.0", publicKey, ReasonBadSignature},
+		{"signature for another build", Release{Version: "1.5.0+2", SHA256: sha, Signature: sign(t, privateKey, "1.5.0+1", sha)}, "1.4.0", publicKey, ReasonBadSignature},
+		{"signature for another hash", Release{Version: "1.5.0", SHA256: otherSHA, Signature: sign(t, privateKey, "1.5.0", sha)}, "1.4.0", publicKey, ReasonBadSignature},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := tt.release.Check(tt.current, tt.publicKey)
+			if got := Reason(err); got != tt.want {
+				t.Fatalf("Check err = %v (reason %q), want reason %q", err, got, tt.want)
+			}
+			if tt.want == "" && version.String() != strings.TrimPrefix(tt.release.Version, "v") {
+				t.Fatalf("Check version = %s, want %s", version, tt.release.Version)
+			}
+		})
+	}
+}
+
+// TestDownloadVerifiesChecksum 哈希一致时返回下载的文件
+func TestDownloadVerifiesChecksum(t *testing.T) {
+	body := []byte("new scum_client build")
+	server := serveFile(t, body)
+	dir := t.TempDir()
+
+	release := Release{DownloadURL: server.URL, SHA256: strings.ToUpper(sha256Hex(body))}
+	path, err := release.Download(context.Background(), Version{Major: 1, Minor: 2, Patch: 3}, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != string(body) {
+		t.Fatalf("downloaded %q, want %q", data, body)
+	}
+}
+
+// TestDownloadChecksumMismatch 哈希不一致时返回 checksum_mismatch 并删除文件
+func TestDownloadChecksumMismatch(t *testing.T) {
+	server := serveFile(t, []byte("tampered build"))
+	dir := t.TempDir()
+
+	release := Release{DownloadURL: server.URL, SHA256: sha256Hex([]byte("signed build"))}
+	_, err := release.Download(context.Background(), Version{Major: 1}, dir)
+	if Reason(err) != ReasonChecksumMismatch {
+		t.Fatalf("err = %v, want %s", err, ReasonChecksumMismatch)
+	}
+	if names := entries(t, dir); len(names) != 0 {
+		t.Fatalf("files left after mismatch: %v", names)
+	}
+}
+
+// TestDownloadSizeCap 超过最大大小的更新文件返回 download_failed 并删除文件
+func TestDownloadSizeCap(t *testing.T) {
+	previous := maxDownloadSize
+	maxDownloadSize = 1024
+	t.Cleanup(func() { maxDownloadSize = previous })
+
+	body := []byte(strings.Repeat("x", 1025))
+	server := serveFile(t, body)
+	dir := t.TempDir()
+
+	release := Release{DownloadURL: server.URL, SHA256: sha256Hex(body)}
+	_, err := release.Download(context.Background(), Version{Major: 1}, dir)
+	if Reason(err) != ReasonDownloadFailed {
+		t.Fatalf("err = %v, want %s", err, ReasonDownloadFailed)
+	}
+	if names := entries(t, dir); len(names) != 0 {
+		t.Fatalf("files left after oversized download: %v", names)
+	}
+
+	// 正好等于上限时允许
+	body = body[:1024]
+	server = serveFile(t, body)
+	release = Release{DownloadURL: server.URL, SHA256: sha256Hex(body)}
+	if _, err = release.Download(context.Background(), Version{Major: 1}, dir); err != nil {
+		t.Fatalf("download at the size cap: %v", err)
+	}
+}
+
+// TestDownloadHTTPError 非 200 响应返回 download_failed
+func TestDownloadHTTPError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+
+	release := Release{DownloadURL: server.URL, SHA256: sha256Hex(nil)}
+	_, err := release.Download(context.Background(), Version{Major: 1}, t.TempDir())
+	if Reason(err) != ReasonDownloadFailed {
+		t.Fatalf("err = %v, want %s", err, ReasonDownloadFailed)
+	}
+}
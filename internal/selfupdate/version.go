@@ -0,0 +1,134 @@
+// Package selfupdate 程序自我更新：版本比较、更新文件下载与哈希/签名校验
+package selfupdate
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Version 语义化版本（https://semver.org）
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 []string // 预发布标识（如 rc.1 -> ["rc", "1"]），为空表示正式版本
+	Build               string   // 构建元数据，不参与比较
+}
+
+// ParseVersion 解析语义化版本，允许 "v" 前缀
+// @param: s string 版本字符串（如 v1.4.0、1.5.0-rc.1+20240101）
+// @return: Version, error
+func ParseVersion(s string) (Version, error) {
+	var version Version
+	text := strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if index := strings.IndexByte(text, '+'); index >= 0 {
+		version.Build = text[index+1:]
+		text = text[:index]
+		if !validIdentifiers(version.Build) {
+			return Version{}, fmt.Errorf("无效的版本号 %q: 构建元数据只能包含字母、数字、'-' 和 '.'", s)
+		}
+	}
+	if index := strings.IndexByte(text, '-'); index >= 0 {
+		pre := text[index+1:]
+		text = text[:index]
+		if pre == "" {
+			return Version{}, fmt.Errorf("无效的版本号 %q: 预发布标识为空", s)
+		}
+		if !validIdentifiers(pre) {
+			return Version{}, fmt.Errorf("无效的版本号 %q: 预发布标识只能包含字母、数字、'-' 和 '.'", s)
+		}
+		version.Pre = strings.Split(pre, ".")
+	}
+
+	parts := strings.Split(text, ".")
+	if len(parts) != 3 {
+		return Version{}, fmt.Errorf("无效的版本号 %q: 需要 MAJOR.MINOR.PATCH", s)
+	}
+	numbers := make([]int, 3)
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || (len(part) > 1 && part[0] == '0') {
+			return Version{}, fmt.Errorf("无效的版本号 %q: %q 不是有效的数字", s, part)
+		}
+		numbers[i] = n
+	}
+	version.Major, version.Minor, version.Patch = numbers[0], numbers[1], numbers[2]
+	return version, nil
+}
+
+// String 规范形式（不含 "v" 前缀），同时用于签名内容
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.Pre) > 0 {
+		s += "-" + strings.Join(v.Pre, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare 按语义化版本优先级比较
+// @return: int v<other 为 -1，相等为 0，v>other 为 1
+func (v Version) Compare(other Version) int {
+	for _, pair := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if pair[0] != pair[1] {
+			return compareInt(pair[0], pair[1])
+		}
+	}
+
+	// 正式版本高于同号的预发布版本
+	switch {
+	case len(v.Pre) == 0 && len(other.Pre) == 0:
+		return 0
+	case len(v.Pre) == 0:
+		return 1
+	case len(other.Pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(v.Pre) && i < len(other.Pre); i++ {
+		if c := comparePre(v.Pre[i], other.Pre[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(v.Pre), len(other.Pre))
+}
+
+// comparePre 比较一个预发布标识：数字按数值比较且低于字母标识，字母标识按 ASCII 比较
+func comparePre(a, b string) int {
+	na, errA := strconv.Atoi(a)
+	nb, errB := strconv.Atoi(b)
+	switch {
+	case errA == nil && errB == nil:
+		return compareInt(na, nb)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+// validIdentifiers 检查以 '.' 分隔的标识是否非空且只包含 [0-9A-Za-z-]
+func validIdentifiers(s string) bool {
+	for _, identifier := range strings.Split(s, ".") {
+		if identifier == "" {
+			return false
+		}
+		for _, r := range identifier {
+			if !(r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package selfupdate
+
+import "testing"
+
+// TestParseVersion 解析合法版本，拒绝不符合语义化版本的字符串
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"1.4.0", "1.4.0", true},
+		{"v1.4.0", "1.4.0", true},
+		{" v2.0.1 ", "2.0.1", true},
+		{"1.5.0-rc.1", "1.5.0-rc.1", true},
+		{"1.5.0-rc.1+20240101", "1.5.0-rc.1+20240101", true},
+		{"1.5.0+build.7", "1.5.0+build.7", true},
+		{"1.4", "", false},
+		{"1.4.0.1", "", false},
+		{"01.4.0", "", false},
+		{"1.-4.0", "", false},
+		{"1.4.x", "", false},
+		{"1.4.0-", "", false},
+		{"1.4.0-rc..1", "", false},
+		{"1.4.0-rc_1", "", false},
+		{"1.4.0+", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		version, err := ParseVersion(tt.input)
+		if tt.ok != (err == nil) {
+			t.Errorf("ParseVersion(%q) err = %v, want ok = %v", tt.input, err, tt.ok)
+			continue
+		}
+		if tt.ok && version.String() != tt.want {
+			t.Errorf("ParseVersion(%q) = %s, want %s", tt.input, version, tt.want)
+		}
+	}
+}
+
+// TestCompare 按语义化版本优先级比较，预发布版本低于正式版本，构建元数据不参与比较
+func TestCompare(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.4.0", "1.4.0", 0},
+		{"1.4.0", "1.4.1", -1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "1.99.99", 1},
+		{"1.4.0+a", "1.4.0+b", 0},
+		{"1.5.0-rc.1", "1.5.0", -1},
+		{"1.5.0", "1.5.0-rc.1", 1},
+		{"1.5.0-rc.1", "1.4.9", 1},
+		// semver.org 11.4 中的顺序
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.1", "1.0.0-alpha.beta", -1},
+		{"1.0.0-alpha.beta", "1.0.0-beta", -1},
+		{"1.0.0-beta", "1.0.0-beta.2", -1},
+		{"1.0.0-beta.2", "1.0.0-beta.11", -1},
+		{"1.0.0-beta.11", "1.0.0-rc.1", -1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-rc.1", "1.0.0-rc.1", 0},
+	}
+	for _, tt := range tests {
+		a, err := ParseVersion(tt.a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := ParseVersion(tt.b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := a.Compare(b); got != tt.want {
+			t.Errorf("Compare(%s, %s) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := b.Compare(a); got != -tt.want {
+			t.Errorf("Compare(%s, %s) = %d, want %d", tt.b, tt.a, got, -tt.want)
+		}
+	}
+}
@@ -0,0 +1,245 @@
+// Package status 客户端运行状态：主循环、OCR 监控和 WebSocket 客户端更新各自的状态，由 client_status 消息上报
+// 每个游戏实例一个 Tracker，OCR 服务状态由所有实例共用
+package status
+
+import (
+	"qq_client/global"
+	"qq_client/model/request"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/cpu"
+	"github.com/shirou/gopsutil/v4/mem"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// 重启计数的类别
+const (
+	RestartGame       = "game"        // 错误次数过多，结束游戏进程
+	RestartGameLaunch = "game_launch" // 游戏未运行或窗口丢失，启动游戏
+	RestartOCR        = "ocr"         // OCR 服务重启（所有实例共用）
+	RestartWebSocket  = "websocket"   // WebSocket 重连
+)
+
+// Tracker 一个游戏实例的状态（多实例时每个实例各自上报）
+type Tracker struct {
+	mutex     sync.Mutex
+	current   request.ClientStatus
+	changedAt time.Time
+	changes   chan struct{}
+}
+
+var (
+	startedAt = time.Now()
+
+	// 进程级状态（OCR 服务由所有实例共用），变化时通知所有实例
+	trackersMutex sync.Mutex
+	trackers      []*Tracker
+	ocr           request.StatusOCR
+	ocrRestarts   int
+)
+
+// New 创建并登记实例状态
+// @param: instance string 实例名称，单实例时为空
+// @return: *Tracker
+func New(instance string) *Tracker {
+	trackersMutex.Lock()
+	defer trackersMutex.Unlock()
+	t := &Tracker{
+		current: request.ClientStatus{
+			Instance:  instance,
+			GameState: "UNKNOWN",
+			OCR:       ocr,
+			Restarts:  make(map[string]int),
+		},
+		changedAt: time.Now(),
+		changes:   make(chan struct{}, 1),
+	}
+	if ocrRestarts > 0 {
+		t.current.Restarts[RestartOCR] = ocrRestarts
+	}
+	trackers = append(trackers, t)
+	return t
+}
+
+// Changes 状态变化通知（游戏状态、聊天模式、暂停状态、OCR 可用性、游戏进程、窗口位置、重启次数），多次变化可能合并为一次通知
+func (t *Tracker) Changes() <-chan struct{} {
+	return t.changes
+}
+
+// changed 记录变化时间并通知（调用方持有锁）
+func (t *Tracker) changed() {
+	t.changedAt = time.Now()
+	select {
+	case t.changes <- struct{}{}:
+	default:
+	}
+}
+
+// SetGameState 更新游戏状态和聊天模式
+// @param: state string 游戏状态
+// @param: chatMode string 聊天模式，聊天框未打开时为空
+func (t *Tracker) SetGameState(state, chatMode string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.current.GameState != state || t.current.ChatMode != chatMode {
+		t.current.GameState, t.current.ChatMode = state, chatMode
+		t.changed()
+	}
+}
+
+// SetGame 更新游戏进程和窗口，pid 为 0 表示未找到游戏窗口
+// @param: pid int32 游戏进程号
+// @param: window request.StatusWindow 客户区位置和大小
+func (t *Tracker) SetGame(pid int32, window request.StatusWindow) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if pid == 0 {
+		if t.current.Game != nil {
+			t.current.Game = nil
+			t.changed()
+		}
+		return
+	}
+	if t.current.Game == nil || t.current.Game.PID != pid || t.current.Game.Window != window {
+		t.current.Game = &request.StatusGame{PID: pid, Window: window}
+		t.changed()
+	}
+}
+
+// SetPaused 更新聊天监控的暂停状态
+func (t *Tracker) SetPaused(paused bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.current.Paused != paused {
+		t.current.Paused = paused
+		t.changed()
+	}
+}
+
+// SetPendingCommands 更新待执行的服务器指令数（不触发上报）
+func (t *Tracker) SetPendingCommands(count int) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.current.PendingCommands = count
+}
+
+// RecordCommand 记录一次指令执行（不触发上报）
+// @param: latency time.Duration 执行耗时
+// @param: success bool 是否成功
+func (t *Tracker) RecordCommand(latency time.Duration, success bool) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.current.LastCommand = &request.StatusCommand{LatencyMs: latency.Milliseconds(), Success: success, Time: time.Now().Unix()}
+}
+
+// AddRestart 实例的重启次数加一
+// @param: kind string RestartGame/RestartGameLaunch/RestartWebSocket
+func (t *Tracker) AddRestart(kind string) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.current.Restarts[kind]++
+	t.changed()
+}
+
+// SetOCRHealth 更新 OCR 服务健康状态（可用性变化时通知所有实例）
+// @param: available bool 是否可用
+// @param: reason string 不可用的原因
+func SetOCRHealth(available bool, reason string) {
+	if available {
+		reason = ""
+	}
+	trackersMutex.Lock()
+	defer trackersMutex.Unlock()
+	ocr = request.StatusOCR{Available: available, Reason: reason, Assets: ocr.Assets}
+	for _, t := range trackers {
+		t.mutex.Lock()
+		wasAvailable := t.current.OCR.Available
+		t.current.OCR = ocr
+		if wasAvailable != available {
+			t.changed()
+		}
+		t.mutex.Unlock()
+	}
+}
+
+// SetOCRAssets 记录启动时与当前版本不一致的 OCR 文件（所有实例共用）
+// @param: assets []request.StatusAsset 不一致的文件，全部一致时为空
+func SetOCRAssets(assets []request.StatusAsset) {
+	trackersMutex.Lock()
+	defer trackersMutex.Unlock()
+	ocr.Assets = assets
+	for _, t := range trackers {
+		t.mutex.Lock()
+		t.current.OCR.Assets = assets
+		t.changed()
+		t.mutex.Unlock()
+	}
+}
+
+// AddOCRRestart OCR 服务重启次数加一（所有实例共用 OCR 服务）
+func AddOCRRestart() {
+	trackersMutex.Lock()
+	defer trackersMutex.Unlock()
+	ocrRestarts++
+	for _, t := range trackers {
+		t.AddRestart(RestartOCR)
+	}
+}
+
+// Snapshot 当前状态，同时采集主机资源和游戏进程运行时间
+// @description: SendQueue 由调用方（WebSocket 客户端）填写
+// @return: request.ClientStatus
+func (t *Tracker) Snapshot() request.ClientStatus {
+	t.mutex.Lock()
+	snapshot := t.current
+	snapshot.Restarts = make(map[string]int, len(t.current.Restarts))
+	for kind, count := range t.current.Restarts {
+		snapshot.Restarts[kind] = count
+	}
+	if t.current.LastCommand != nil {
+		lastCommand := *t.current.LastCommand
+		snapshot.LastCommand = &lastCommand
+	}
+	if t.current.Game != nil {
+		game := *t.current.Game
+		snapshot.Game = &game
+	}
+	snapshot.ChangedAt = t.changedAt.Unix()
+	t.mutex.Unlock()
+
+	snapshot.Version = global.Version
+	snapshot.Uptime = int64(time.Since(startedAt).Seconds())
+	snapshot.Host = hostUsage()
+	if snapshot.Game != nil {
+		snapshot.Game.Uptime = processUptime(snapshot.Game.PID)
+	}
+	return snapshot
+}
+
+// hostUsage 主机 CPU（距上次采集的平均值）和内存使用情况，获取失败的项为 0
+func hostUsage() request.StatusHost {
+	var host request.StatusHost
+	if percents, err := cpu.Percent(0, false); err == nil && len(percents) > 0 {
+		host.CPUPercent = percents[0]
+	}
+	if memory, err := mem.VirtualMemory(); err == nil {
+		host.MemoryPercent = memory.UsedPercent
+		host.MemoryUsed = memory.Used
+		host.MemoryTotal = memory.Total
+	}
+	return host
+}
+
+// processUptime 进程运行时间（秒），获取失败时为 0
+func processUptime(pid int32) int64 {
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		return 0
+	}
+	createTime, err := proc.CreateTime()
+	if err != nil {
+		return 0
+	}
+	return int64(time.Since(time.UnixMilli(createTime)).Seconds())
+}
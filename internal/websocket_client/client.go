@@ -1,47 +1,91 @@
+// Package websocket_client 与服务端通信的 WebSocket 客户端：自动重连、心跳，消息格式见 model/request 中的消息目录
+//
+// 每个客户端只有一个重连协程；每次连接只有一个读协程和一个写协程，所有写入（消息、ping、pong、心跳）都经过写协程。
+// 写协程定时发送 ping，读协程每收到数据（包括 pong）就延长读取截止时间，半开连接在 PongWait 内即可发现。
+// SendMessage 的消息进入有界发送队列，连接认证成功（MarkReady）后发送，断线期间保留到重新认证后补发
 package websocket_client
 
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 
 	_const "qq_client/internal/const"
+	"qq_client/model/request"
 )
 
+// ErrMessageTooLarge 消息超过最大消息大小，未发送（连接保持不变）
+var ErrMessageTooLarge = errors.New("message exceeds max message size")
+
+// ErrNotConnected 当前没有可用的连接
+var ErrNotConnected = errors.New("connection not running or nil")
+
+// ErrClosed 客户端已关闭或已放弃重连
+var ErrClosed = errors.New("client closed")
+
 // Client represents a WebSocket client
 type Client struct {
-	url           string
-	conn          *websocket.Conn
-	logger        interface{} // 简化版本，不使用logger
-	mutex         sync.RWMutex
-	writeMutex    sync.Mutex // 专门用于写操作的互斥锁
-	isRunning     bool
-	stopChan      chan struct{}
-	reconnectChan chan struct{}
-	ctx           context.Context
-	cancel        context.CancelFunc
+	url    string
+	logger interface{} // 简化版本，不使用logger
+	mutex  sync.RWMutex
+	conn   *connection // 当前连接，断开期间为 nil
+	ctx    context.Context
+	cancel context.CancelFunc
+	// 发送队列，跨连接保留
+	outbox *outbox
+	// 收到的消息（Call 的回复除外），由 ReadMessage 读取
+	incoming *inbox
+	// 重连协程已启动 / 已退出
+	started bool
+	stopped chan struct{}
 	// 重连配置
 	maxRetries       int
 	retryInterval    time.Duration
 	maxRetryInterval time.Duration
 	// 心跳配置
 	heartbeatInterval time.Duration
-	heartbeatTimeout  time.Duration
+	pingInterval      time.Duration
+	pongWait          time.Duration
+	writeWait         time.Duration
 	// 连接稳定性配置
 	readBufferSize  int
 	writeBufferSize int
 	maxMessageSize  int64
-	lastHeartbeat   time.Time
 	// 回调函数
 	onConnect    func()
 	onDisconnect func()
 	onReconnect  func()
+	// 等待回复的请求（消息 ID -> 回复通道）
+	pending      map[string]chan request.WebSocketMessage
+	pendingMutex sync.Mutex
+}
+
+// connection 一次连接，读协程或写协程出错时关闭
+type connection struct {
+	ws        *websocket.Conn
+	send      chan []byte   // 直接发送的消息（SendDirect），由写协程写入连接
+	ready     atomic.Bool   // 已认证，可以发送队列中的消息
+	pong      chan string   // 待回复的 ping
+	done      chan struct{} // 连接关闭
+	closeOnce sync.Once
+	err       error // 关闭原因，done 关闭后可读
+}
+
+// close 关闭连接（可重复调用，只记录第一次的原因）
+func (s *connection) close(err error) {
+	s.closeOnce.Do(func() {
+		s.err = err
+		close(s.done)
+		_ = s.ws.Close()
+	})
 }
 
 // New creates a new WebSocket client
@@ -52,180 +96,175 @@ func New(url string, logger interface{}) *Client {
 		logger:            logger,
 		ctx:               ctx,
 		cancel:            cancel,
-		stopChan:          make(chan struct{}),
-		reconnectChan:     make(chan struct{}),
+		outbox:            newOutbox(_const.OutboxSize),
+		incoming:          newInbox(),
+		stopped:           make(chan struct{}),
 		maxRetries:        -1,                       // 无限重试
 		retryInterval:     _const.RetryInterval,     // 增加重连间隔，减少频繁重连
 		maxRetryInterval:  _const.MaxRetryInterval,  // 增加最大重连间隔
 		heartbeatInterval: _const.HeartbeatInterval, // 与服务端错开的心跳时间，避免冲突
-		heartbeatTimeout:  _const.HeartbeatTimeout,  // 合理的心跳超时时间，给网络波动缓冲
-		readBufferSize:    _const.ReadBufferSize,    // 与服务端一致的缓冲区大小
-		writeBufferSize:   _const.WriteBufferSize,   // 与服务端一致的缓冲区大小
-		maxMessageSize:    _const.MaxMessageSize,    // 与服务端一致的最大消息大小
+		pingInterval:      _const.PingInterval,      // ping 间隔需明显小于 pongWait
+		pongWait:          _const.PongWait,          // 给网络波动留出缓冲
+		writeWait:         _const.WriteWait,
+		readBufferSize:    _const.ReadBufferSize,  // 与服务端一致的缓冲区大小
+		writeBufferSize:   _const.WriteBufferSize, // 与服务端一致的缓冲区大小
+		maxMessageSize:    _const.MaxMessageSize,  // 与服务端一致的最大消息大小
+		pending:           make(map[string]chan request.WebSocketMessage),
 	}
 }
 
-// Connect establishes a WebSocket connection
-func (c *Client) Connect() error {
+// ConnectWithAutoReconnect establishes a WebSocket connection with auto-reconnect
+// 首次连接失败时直接返回错误；连接成功后由重连协程负责之后的断线重连，直到 Close
+func (c *Client) ConnectWithAutoReconnect() error {
 	c.mutex.Lock()
-	dialer := websocket.Dialer{
-		HandshakeTimeout: _const.ConnectionTimeout, // 延长握手超时时间到60秒
-		ReadBufferSize:   c.readBufferSize,         // 使用配置的读取缓冲区
-		WriteBufferSize:  c.writeBufferSize,        // 使用配置的写入缓冲区
-		// 添加更多连接优化配置
-		EnableCompression: false, // 禁用压缩减少CPU开销
+	if c.started {
+		c.mutex.Unlock()
+		return fmt.Errorf("already connected")
 	}
+	c.mutex.Unlock()
 
-	conn, _, err := dialer.Dial(c.url, nil)
+	conn, err := c.dial()
 	if err != nil {
-		c.mutex.Unlock()
 		return err
 	}
 
-	// 设置连接参数 - 移除超时限制提高稳定性
-	conn.SetReadLimit(c.maxMessageSize) // 使用配置的最大消息大小
-	// 移除读取超时限制，避免网络波动导致的连接断开
-	// conn.SetReadDeadline() - 不设置读取超时
-
-	conn.SetPongHandler(func(string) error {
-		// 只记录pong响应，不重新设置读取超时
-		c.mutex.Lock()
-		c.lastHeartbeat = time.Now()
-		c.mutex.Unlock()
-		return nil
-	})
-
-	// 移除写超时限制，避免大量数据传输时超时
-	// conn.SetWriteDeadline() - 不设置写超时
-
+	// 返回前设置当前连接，之后的 SendMessage 可以直接发送
+	c.mutex.Lock()
+	c.started = true
 	c.conn = conn
-	c.isRunning = true
-
-	// 获取回调函数的引用，然后释放锁
-	onConnect := c.onConnect
 	c.mutex.Unlock()
-
-	// 在锁外调用连接回调，避免死锁
-	if onConnect != nil {
-		onConnect()
-	}
-
-	return nil
-}
-
-// ConnectWithAutoReconnect establishes a WebSocket connection with auto-reconnect
-func (c *Client) ConnectWithAutoReconnect() error {
-	if err := c.Connect(); err != nil {
-		return err
-	}
-
-	// 启动重连监控和心跳
-	go c.monitorConnection()
-	go c.heartbeatLoop()
-
+	go c.run(conn)
 	return nil
 }
 
 // Close closes the WebSocket connection
+// 发送关闭帧并等待重连协程退出（onDisconnect 在返回前调用），不能在回调中调用
 func (c *Client) Close() error {
-	c.mutex.Lock()
-	defer c.mutex.Unlock()
-
-	c.isRunning = false
 	c.cancel()
 
-	if c.conn != nil {
-		err := c.conn.Close()
-		c.conn = nil
+	c.mutex.RLock()
+	conn := c.conn
+	started := c.started
+	c.mutex.RUnlock()
 
-		// 调用断开连接回调
-		if c.onDisconnect != nil {
-			c.onDisconnect()
-		}
+	var err error
+	if conn != nil {
+		// WriteControl 可以与写协程并发调用
+		err = conn.ws.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(c.writeWait))
+		conn.close(ErrClosed)
+	}
+	if started {
+		<-c.stopped
+	}
+	return err
+}
 
+// Send 发送一条成功消息
+// @param: msgType string 消息类型
+// @param: payload interface{} 载荷（消息目录中的类型）
+// @return: error
+func (c *Client) Send(msgType string, payload interface{}) error {
+	msg, err := request.NewWebSocketMessage(msgType, payload, "")
+	if err != nil {
 		return err
 	}
-	return nil
+	return c.SendMessage(msg)
 }
 
 // SendMessage sends a message via WebSocket
-func (c *Client) SendMessage(message interface{}) error {
-	// 使用读锁检查连接状态
+// 以默认选项（PriorityNormal、OutboxTTL）放入发送队列，见 QueueMessage
+func (c *Client) SendMessage(message request.WebSocketMessage) error {
+	return c.QueueMessage(message, SendOptions{Priority: PriorityNormal})
+}
+
+// QueueMessage 放入发送队列后返回，连接认证成功后按优先级发送
+// @description: 断线期间消息保留在队列中，重新认证后补发；超过 TTL 未发送或队列已满时被丢弃并计入 QueueStats
+// @param: message request.WebSocketMessage 消息（发送前补全 id、timestamp、version）
+// @param: opts SendOptions 优先级和 TTL
+// @return: error 超过最大消息大小为 ErrMessageTooLarge，队列已满且无法腾出位置为 ErrQueueFull
+func (c *Client) QueueMessage(message request.WebSocketMessage, opts SendOptions) error {
+	if c.ctx.Err() != nil {
+		return ErrClosed
+	}
+	data, err := c.encode(&message)
+	if err != nil {
+		return err
+	}
+	return c.outbox.push(message.Type, data, opts, _const.OutboxTTL)
+}
+
+// QueueStats 发送队列统计（等待发送、队列已满丢弃、过期丢弃的消息数）
+func (c *Client) QueueStats() QueueStats {
+	return c.outbox.stats()
+}
+
+// MarkReady 当前连接已认证，开始发送队列中的消息
+// 每次连接都需要重新调用（断开后队列暂停，直到重新认证）
+func (c *Client) MarkReady() {
 	c.mutex.RLock()
-	if !c.isRunning || c.conn == nil {
-		c.mutex.RUnlock()
-		return fmt.Errorf("connection not running or nil")
+	conn := c.conn
+	c.mutex.RUnlock()
+	if conn == nil {
+		return
+	}
+	conn.ready.Store(true)
+	c.outbox.signal()
+}
+
+// SendDirect 绕过发送队列，直接在当前连接上发送
+// 用于认证等只对当前连接有意义的消息，未连接时返回 ErrNotConnected
+func (c *Client) SendDirect(message request.WebSocketMessage) error {
+	data, err := c.encode(&message)
+	if err != nil {
+		return err
 	}
+
+	c.mutex.RLock()
 	conn := c.conn
 	c.mutex.RUnlock()
+	if conn == nil {
+		return ErrNotConnected
+	}
 
-	// 使用专门的写锁确保同时只有一个写操作
-	c.writeMutex.Lock()
-	defer c.writeMutex.Unlock()
+	timer := time.NewTimer(c.writeWait)
+	defer timer.Stop()
+	select {
+	case conn.send <- data:
+		return nil
+	case <-conn.done:
+		return ErrNotConnected
+	case <-timer.C:
+		return fmt.Errorf("%w, dropped %s", ErrQueueFull, message.Type)
+	}
+}
 
-	// 移除写超时限制，避免网络波动时发送失败
-	// conn.SetWriteDeadline() - 不设置写超时
-	err := conn.WriteJSON(message)
+// encode 补全信封（id、timestamp、version）并编码，超过最大消息大小返回 ErrMessageTooLarge
+func (c *Client) encode(message *request.WebSocketMessage) ([]byte, error) {
+	stamp(message)
+	data, err := json.Marshal(message)
 	if err != nil {
-		// 发送失败时触发重连
-		c.handleDisconnection()
+		return nil, err
 	}
-	return err
+	if int64(len(data)) > c.maxMessageSize {
+		return nil, fmt.Errorf("%w: %s (%d > %d bytes)", ErrMessageTooLarge, message.Type, len(data), c.maxMessageSize)
+	}
+	return data, nil
 }
 
 // ReadMessage reads a message from WebSocket
-func (c *Client) ReadMessage(message interface{}) error {
+// 阻塞到收到消息为止（断线重连期间继续等待）；Call 等待的回复不会返回给调用方；客户端关闭或放弃重连后返回 ErrClosed
+func (c *Client) ReadMessage() (request.WebSocketMessage, error) {
 	for {
-		c.mutex.RLock()
-		if !c.isRunning || c.conn == nil {
-			c.mutex.RUnlock()
-			return fmt.Errorf("connection not running or nil")
+		if msg, ok := c.incoming.pop(); ok {
+			return msg, nil
 		}
-		conn := c.conn
-		c.mutex.RUnlock()
-
-		messageType, data, err := conn.ReadMessage()
-		if err != nil {
-			// 连接断开，触发重连
-			c.handleDisconnection()
-			return err
-		}
-
-		// 处理不同类型的消息
-		switch messageType {
-		case websocket.PingMessage:
-			// 响应ping消息
-			c.mutex.Lock()
-			if c.conn != nil {
-				_ = c.conn.WriteMessage(websocket.PongMessage, data)
-			}
-			c.mutex.Unlock()
-			// 继续读取下一条消息
-			continue
-
-		case websocket.PongMessage:
-			// 更新心跳时间
-			c.mutex.Lock()
-			c.lastHeartbeat = time.Now()
-			c.mutex.Unlock()
-			// 继续读取下一条消息
-			continue
-
-		case websocket.TextMessage:
-			// 处理文本消息
-			err = json.Unmarshal(data, message)
-			if err != nil {
-				return err
-			}
-			return nil
-
-		case websocket.BinaryMessage:
-			// 二进制消息暂时忽略，继续读取下一条消息
-			continue
-
-		default:
-			// 其他类型消息忽略，继续读取下一条消息
-			continue
+		select {
+		case <-c.incoming.notify:
+		case <-c.stopped:
+			return request.WebSocketMessage{}, ErrClosed
+		case <-c.ctx.Done():
+			return request.WebSocketMessage{}, ErrClosed
 		}
 	}
 }
@@ -234,10 +273,12 @@ func (c *Client) ReadMessage(message interface{}) error {
 func (c *Client) IsConnected() bool {
 	c.mutex.RLock()
 	defer c.mutex.RUnlock()
-	return c.isRunning && c.conn != nil
+	return c.conn != nil
 }
 
 // SetCallbacks sets callback functions for connection events
+// 每次连接成功都调用 onConnect；自动重连成功时先调用 onConnect 再调用 onReconnect；
+// 连接断开（包括 Close）时调用一次 onDisconnect。回调都在重连协程中按顺序调用
 func (c *Client) SetCallbacks(onConnect, onDisconnect, onReconnect func()) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
@@ -256,156 +297,281 @@ func (c *Client) SetRetryConfig(maxRetries int, retryInterval, maxRetryInterval
 }
 
 // SetHeartbeatConfig sets heartbeat configuration
-func (c *Client) SetHeartbeatConfig(interval, timeout time.Duration) {
+// @param: interval time.Duration client_heartbeat 消息间隔
+// @param: pingInterval time.Duration ping 间隔
+// @param: pongWait time.Duration 读取超时，需大于 pingInterval
+func (c *Client) SetHeartbeatConfig(interval, pingInterval, pongWait time.Duration) {
 	c.mutex.Lock()
 	defer c.mutex.Unlock()
 	c.heartbeatInterval = interval
-	c.heartbeatTimeout = timeout
+	c.pingInterval = pingInterval
+	c.pongWait = pongWait
 }
 
-// monitorConnection monitors the connection and handles reconnection
-func (c *Client) monitorConnection() {
-	ticker := time.NewTicker(_const.HeartbeatTimeout) // 每5分钟检查一次连接，减少频繁检查
-	defer ticker.Stop()
+// dial 建立连接
+func (c *Client) dial() (*connection, error) {
+	if c.ctx.Err() != nil {
+		return nil, ErrClosed
+	}
+	dialer := websocket.Dialer{
+		HandshakeTimeout: _const.ConnectionTimeout, // 延长握手超时时间到60秒
+		ReadBufferSize:   c.readBufferSize,         // 使用配置的读取缓冲区
+		WriteBufferSize:  c.writeBufferSize,        // 使用配置的写入缓冲区
+		// 添加更多连接优化配置
+		EnableCompression: false, // 禁用压缩减少CPU开销
+	}
+	ws, _, err := dialer.DialContext(c.ctx, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	ws.SetReadLimit(c.maxMessageSize) // 使用配置的最大消息大小
+
+	return &connection{
+		ws:   ws,
+		send: make(chan []byte, _const.SendQueueSize),
+		pong: make(chan string, 1),
+		done: make(chan struct{}),
+	}, nil
+}
 
+// run 重连协程：服务当前连接，断开后重连，直到 Close 或达到最大重试次数
+func (c *Client) run(conn *connection) {
+	defer close(c.stopped)
+	reconnected := false
 	for {
+		c.serve(conn, reconnected)
+		if conn = c.reconnect(); conn == nil {
+			return
+		}
+		reconnected = true
+	}
+}
+
+// serve 启动读写协程并调用回调，阻塞到连接关闭
+func (c *Client) serve(conn *connection, reconnected bool) {
+	c.mutex.Lock()
+	c.conn = conn
+	pongWait, pingInterval, heartbeatInterval := c.pongWait, c.pingInterval, c.heartbeatInterval
+	onConnect, onReconnect := c.onConnect, c.onReconnect
+	c.mutex.Unlock()
+	// Close 可能在设置 c.conn 之前调用
+	if c.ctx.Err() != nil {
+		conn.close(ErrClosed)
+	}
+
+	go c.readLoop(conn, pongWait)
+	go c.writeLoop(conn, pingInterval, heartbeatInterval)
+
+	if onConnect != nil {
+		onConnect()
+	}
+	if reconnected && onReconnect != nil {
+		onReconnect()
+	}
+
+	<-conn.done
+
+	c.mutex.Lock()
+	c.conn = nil
+	onDisconnect := c.onDisconnect
+	c.mutex.Unlock()
+	c.failPending()
+
+	if !errors.Is(conn.err, ErrClosed) {
+		log.Printf("WebSocket connection lost: %v", conn.err)
+	}
+	if onDisconnect != nil {
+		onDisconnect()
+	}
+}
+
+// readLoop 读协程：每收到数据就延长读取截止时间，消息交给 Call 或放入 incoming（不阻塞，保证 ping/pong 及时处理）
+// 超过最大消息大小的消息会导致连接断开并触发重连
+func (c *Client) readLoop(conn *connection, pongWait time.Duration) {
+	extend := func() { _ = conn.ws.SetReadDeadline(time.Now().Add(pongWait)) }
+	extend()
+	conn.ws.SetPongHandler(func(string) error {
+		extend()
+		return nil
+	})
+	conn.ws.SetPingHandler(func(data string) error {
+		extend()
+		// pong 由写协程发送；上一个 pong 尚未发送时丢弃本次（对方只需要收到任意一个）
 		select {
-		case <-c.ctx.Done():
+		case conn.pong <- data:
+		default:
+		}
+		return nil
+	})
+
+	for {
+		messageType, data, err := conn.ws.ReadMessage()
+		if err != nil {
+			conn.close(err)
 			return
-		case <-ticker.C:
-			if !c.IsConnected() {
-				log.Printf("Connection lost, attempting to reconnect...")
-				go c.reconnect()
-			} else {
-				// 检查心跳超时
-				c.mutex.RLock()
-				lastHeartbeat := c.lastHeartbeat
-				heartbeatTimeout := c.heartbeatTimeout
-				c.mutex.RUnlock()
-
-				// 如果从未收到心跳，跳过检查
-				if !lastHeartbeat.IsZero() && time.Since(lastHeartbeat) > heartbeatTimeout {
-					log.Printf("Heartbeat timeout (last: %v, timeout: %v), attempting to reconnect...",
-						lastHeartbeat, heartbeatTimeout)
-					c.handleDisconnection()
-				}
-			}
-		case <-c.reconnectChan:
-			go c.reconnect()
 		}
+		extend()
+		if messageType != websocket.TextMessage {
+			// 二进制消息暂时忽略
+			continue
+		}
+
+		var message request.WebSocketMessage
+		if err = json.Unmarshal(data, &message); err != nil {
+			log.Printf("Invalid WebSocket message: %v", err)
+			continue
+		}
+		if c.deliverReply(message) {
+			continue
+		}
+		c.incoming.push(message)
 	}
 }
 
-// heartbeatLoop sends periodic heartbeat messages
-func (c *Client) heartbeatLoop() {
-	ticker := time.NewTicker(c.heartbeatInterval)
-	defer ticker.Stop()
+// writeLoop 写协程：唯一写入连接的协程，发送队列中的消息、pong、ping 和 client_heartbeat
+func (c *Client) writeLoop(conn *connection, pingInterval, heartbeatInterval time.Duration) {
+	pingTicker := time.NewTicker(pingInterval)
+	defer pingTicker.Stop()
+	heartbeatTicker := time.NewTicker(heartbeatInterval)
+	defer heartbeatTicker.Stop()
+
+	write := func(messageType int, data []byte) bool {
+		_ = conn.ws.SetWriteDeadline(time.Now().Add(c.writeWait))
+		if err := conn.ws.WriteMessage(messageType, data); err != nil {
+			conn.close(err)
+			return false
+		}
+		return true
+	}
 
 	for {
+		var ok bool
 		select {
-		case <-c.ctx.Done():
+		case <-conn.done:
 			return
-		case <-ticker.C:
-			if c.IsConnected() {
-				heartbeatMsg := map[string]interface{}{
-					"type": "heartbeat",
-					"data": map[string]interface{}{
-						"timestamp": time.Now().Unix(),
-					},
-				}
-
-				if err := c.SendMessage(heartbeatMsg); err != nil {
-					log.Printf("Failed to send heartbeat: %v", err)
-					c.handleDisconnection()
-				} else {
-					c.mutex.Lock()
-					c.lastHeartbeat = time.Now()
-					c.mutex.Unlock()
-				}
+		case data := <-conn.send:
+			ok = write(websocket.TextMessage, data)
+		case <-c.outbox.notify:
+			ok = c.flush(conn, write)
+		case data := <-conn.pong:
+			ok = write(websocket.PongMessage, []byte(data))
+		case <-pingTicker.C:
+			ok = write(websocket.PingMessage, nil)
+		case <-heartbeatTicker.C:
+			msg, err := request.NewWebSocketMessage(request.MsgTypeHeartbeat, request.Heartbeat{Timestamp: time.Now().Unix()}, "")
+			if err != nil {
+				continue
 			}
+			stamp(&msg)
+			data, err := json.Marshal(msg)
+			if err != nil {
+				continue
+			}
+			ok = write(websocket.TextMessage, data)
+		}
+		if !ok {
+			return
 		}
 	}
 }
 
-// handleDisconnection handles disconnection events
-func (c *Client) handleDisconnection() {
-	c.mutex.Lock()
-	wasRunning := c.isRunning
-	c.isRunning = false
-	if c.conn != nil {
-		_ = c.conn.Close()
-		c.conn = nil
+// flush 连接已认证时发送队列中的消息，写入失败的消息放回队列
+// 连接已关闭时重新发出通知：旧连接的写协程可能在新连接开始后才退出，通知需要留给新连接的写协程
+func (c *Client) flush(conn *connection, write func(int, []byte) bool) bool {
+	select {
+	case <-conn.done:
+		c.outbox.signal()
+		return false
+	default:
 	}
-	c.mutex.Unlock()
-
-	if wasRunning {
-		// 调用断开连接回调
-		if c.onDisconnect != nil {
-			c.onDisconnect()
+	for conn.ready.Load() {
+		// 认证等直接发送的消息先于队列中的消息（MarkReady 可能在认证消息写入前调用）
+		if !drainDirect(conn, write) {
+			return false
+		}
+		item, ok := c.outbox.pop()
+		if !ok {
+			return true
+		}
+		if !write(websocket.TextMessage, item.data) {
+			c.outbox.requeue(item)
+			c.outbox.signal()
+			return false
 		}
+	}
+	return true
+}
 
-		// 触发重连 - 使用非阻塞发送避免死锁
+// drainDirect 写入所有已排队的直接发送消息
+func drainDirect(conn *connection, write func(int, []byte) bool) bool {
+	for {
 		select {
-		case c.reconnectChan <- struct{}{}:
+		case data := <-conn.send:
+			if !write(websocket.TextMessage, data) {
+				return false
+			}
 		default:
-			// 如果重连通道已满，说明重连已在进行中
+			return true
 		}
 	}
 }
 
 // reconnect attempts to reconnect to the WebSocket server
-func (c *Client) reconnect() {
-	backoff := c.retryInterval
+// @return: *connection 新连接，Close 或达到最大重试次数时为 nil
+func (c *Client) reconnect() *connection {
+	c.mutex.RLock()
+	maxRetries, retryInterval, maxRetryInterval := c.maxRetries, c.retryInterval, c.maxRetryInterval
+	c.mutex.RUnlock()
+
+	backoff := retryInterval
 	retryCount := 0
 	isFirstAttempt := true
 
 	for {
+		wait := backoff
+		// 首次重连前等待更长时间，避免启动时的频繁重连
+		if isFirstAttempt {
+			wait += _const.ShortWaitTime
+			isFirstAttempt = false
+		}
+		// 对于频繁的连接失败，增加额外延迟
+		if retryCount > _const.ClientRetryCount {
+			wait += _const.LongWaitTime
+		}
+		timer := time.NewTimer(wait)
 		select {
 		case <-c.ctx.Done():
-			return
-		case <-time.After(backoff):
-			// 首次重连前等待更长时间，避免启动时的频繁重连
-			if isFirstAttempt {
-				time.Sleep(_const.ShortWaitTime) // 减少初始等待时间
-				isFirstAttempt = false
-			}
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+
+		conn, err := c.dial()
+		if err == nil {
+			return conn
+		}
+		if c.ctx.Err() != nil {
+			return nil
+		}
+		retryCount++
+
+		// 检查是否达到最大重试次数
+		if maxRetries > 0 && retryCount >= maxRetries {
+			log.Printf("Max retry attempts reached, giving up")
+			return nil
+		}
 
-			if err := c.Connect(); err != nil {
-				retryCount++
-
-				// 检查是否达到最大重试次数
-				if c.maxRetries > 0 && retryCount >= c.maxRetries {
-					log.Printf("Max retry attempts reached, giving up")
-					return
-				}
-
-				// 智能退避策略：网络错误使用较短间隔，其他错误使用指数退避
-				if strings.Contains(err.Error(), "connection refused") ||
-					strings.Contains(err.Error(), "network is unreachable") ||
-					strings.Contains(err.Error(), "timeout") {
-					// 网络问题，使用固定的较短间隔
-					backoff = c.retryInterval
-				} else {
-					// 其他错误，使用指数退避
-					backoff *= 2 // 指数退避算法
-					if backoff > c.maxRetryInterval {
-						backoff = c.maxRetryInterval
-					}
-				}
-
-				// 对于频繁的连接失败，增加额外延迟
-				if retryCount > _const.ClientRetryCount {
-					time.Sleep(_const.LongWaitTime)
-				}
-			} else {
-				// 调用重连回调
-				if c.onReconnect != nil {
-					c.onReconnect()
-				}
-
-				// 重新启动连接监控
-				go c.monitorConnection()
-				return
+		// 智能退避策略：网络错误使用较短间隔，其他错误使用指数退避
+		if strings.Contains(err.Error(), "connection refused") ||
+			strings.Contains(err.Error(), "network is unreachable") ||
+			strings.Contains(err.Error(), "timeout") {
+			// 网络问题，使用固定的较短间隔
+			backoff = retryInterval
+		} else {
+			// 其他错误，使用指数退避
+			backoff *= 2 // 指数退避算法
+			if backoff > maxRetryInterval {
+				backoff = maxRetryInterval
 			}
 		}
 	}
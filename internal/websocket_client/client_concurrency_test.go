@@ -0,0 +1,231 @@
+package websocket_client
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"qq_client/model/request"
+)
+
+// 并发测试需要使用 go test -race 运行
+
+// authenticate 测试用的认证：直接发送 client_auth 后开始发送队列中的消息
+func authenticate(c *Client) {
+	msg, _ := request.NewWebSocketMessage(request.MsgTypeAuth, request.AuthRequest{ServerID: 1, Token: "t"}, "")
+	_ = c.SendDirect(msg)
+	c.MarkReady()
+}
+
+// TestConcurrentSendCallCloseDuringReconnects 服务端反复断开连接期间并发调用 SendMessage、Call、ReadMessage 和 Close
+func TestConcurrentSendCallCloseDuringReconnects(t *testing.T) {
+	var delivered sync.Map // 连接序号 -> 收到的 test_send 数
+	server := newTestServer(t, func(index int, ws *websocket.Conn) {
+		count := 0
+		for {
+			var msg request.WebSocketMessage
+			if err := ws.ReadJSON(&msg); err != nil {
+				return
+			}
+			switch msg.Type {
+			case "test_call":
+				reply := request.WebSocketMessage{Type: msg.Type, ReplyTo: msg.ID, Success: true}
+				if err := ws.WriteJSON(reply); err != nil {
+					return
+				}
+			case "test_send":
+				count++
+				delivered.Store(index, count)
+			}
+			// 每个连接处理一定数量的消息后断开，触发重连
+			if count >= 200 {
+				return
+			}
+		}
+	})
+
+	c, _ := newTestClient(t, server.wsURL(), authenticate)
+	if err := c.ConnectWithAutoReconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	var replies atomic.Int64
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				err := c.Send("test_send", "payload")
+				if err != nil && !errors.Is(err, ErrClosed) && !errors.Is(err, ErrQueueFull) {
+					t.Errorf("Send: %v", err)
+					return
+				}
+				time.Sleep(time.Millisecond)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+				_, err := c.Call(ctx, "test_call", nil)
+				cancel()
+				switch {
+				case err == nil:
+					replies.Add(1)
+				case errors.Is(err, ErrNotConnected), errors.Is(err, ErrDisconnected),
+					errors.Is(err, ErrQueueFull), errors.Is(err, context.DeadlineExceeded):
+					time.Sleep(5 * time.Millisecond)
+				default:
+					t.Errorf("Call: %v", err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			if _, err := c.ReadMessage(); errors.Is(err, ErrClosed) {
+				return
+			}
+		}
+	}()
+
+	time.Sleep(3 * time.Second)
+	// Close 与发送、调用并发
+	closed := make(chan struct{})
+	go func() {
+		_ = c.Close()
+		close(closed)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	close(stop)
+
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for _, ch := range []chan struct{}{closed, done} {
+		select {
+		case <-ch:
+		case <-time.After(waitTimeout):
+			t.Fatal("Close or workers did not return")
+		}
+	}
+
+	connections := 0
+	delivered.Range(func(_, _ any) bool {
+		connections++
+		return true
+	})
+	if connections < 2 {
+		t.Fatalf("messages delivered on %d connection(s), want at least 2 (reconnect)", connections)
+	}
+	if replies.Load() == 0 {
+		t.Fatal("no Call received a reply")
+	}
+}
+
+// TestHalfOpenPeerDetected 对方不再响应（不读取、不回复 pong）时，在 PongWait 内发现连接已断开
+func TestHalfOpenPeerDetected(t *testing.T) {
+	release := make(chan struct{})
+	server := newTestServer(t, func(_ int, ws *websocket.Conn) {
+		// 不读取连接：ping 得不到 pong
+		<-release
+	})
+	t.Cleanup(func() { close(release) })
+
+	const pongWait = 300 * time.Millisecond
+	c, ev := newTestClient(t, server.wsURL(), nil)
+	c.SetRetryConfig(-1, time.Hour, time.Hour)
+	c.SetHeartbeatConfig(time.Hour, 100*time.Millisecond, pongWait)
+	if err := c.ConnectWithAutoReconnect(); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	ev.wait(t, "disconnect")
+	if elapsed := time.Since(start); elapsed > pongWait+200*time.Millisecond {
+		t.Fatalf("half-open connection detected after %v, want within %v", elapsed, pongWait)
+	}
+}
+
+// TestSlowReaderKeepsConnection 没有调用 ReadMessage 时读协程仍处理 ping，连接不会因读取超时断开，消息按顺序保留
+func TestSlowReaderKeepsConnection(t *testing.T) {
+	const flood = 500
+	var pongs atomic.Int64
+	server := newTestServer(t, func(_ int, ws *websocket.Conn) {
+		for i := 0; i < flood; i++ {
+			if err := ws.WriteJSON(request.WebSocketMessage{ID: string(rune('a' + i%26)), Type: "test_flood", Timestamp: int64(i)}); err != nil {
+				return
+			}
+		}
+		ws.SetPongHandler(func(string) error {
+			pongs.Add(1)
+			return nil
+		})
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			ticker := time.NewTicker(50 * time.Millisecond)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+					if ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second)) != nil {
+						return
+					}
+				}
+			}
+		}()
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	// 客户端自己不发送 ping，只靠服务端的 ping 延长读取截止时间
+	c, ev := newTestClient(t, server.wsURL(), nil)
+	c.SetHeartbeatConfig(time.Hour, time.Hour, 300*time.Millisecond)
+	if err := c.ConnectWithAutoReconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(time.Second)
+	if got := ev.snapshot(); !equalEvents(got, []string{"connect"}) {
+		t.Fatalf("connection dropped while messages were unread, events: %v", got)
+	}
+	if pongs.Load() < 5 {
+		t.Fatalf("server received %d pongs, want at least 5", pongs.Load())
+	}
+	for i := 0; i < flood; i++ {
+		msg, err := c.ReadMessage()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if msg.Timestamp != int64(i) {
+			t.Fatalf("message %d has timestamp %d", i, msg.Timestamp)
+		}
+	}
+}
@@ -0,0 +1,360 @@
+package websocket_client
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"qq_client/model/request"
+)
+
+// waitTimeout 测试中等待异步事件的最长时间（首次重连前固定多等待 ShortWaitTime）
+const waitTimeout = 5 * time.Second
+
+// testServer 本地 WebSocket 服务端，每个连接在独立协程中交给 handler 处理
+type testServer struct {
+	*httptest.Server
+	mutex sync.Mutex
+	conns int
+}
+
+// newTestServer 启动服务端，handler 返回后关闭连接；index 为连接序号（从 1 开始）
+func newTestServer(t *testing.T, handler func(index int, ws *websocket.Conn)) *testServer {
+	t.Helper()
+	server := &testServer{}
+	upgrader := websocket.Upgrader{}
+	server.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		server.mutex.Lock()
+		server.conns++
+		index := server.conns
+		server.mutex.Unlock()
+		handler(index, ws)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// wsURL 服务端的 ws:// 地址
+func (s *testServer) wsURL() string {
+	return "ws" + strings.TrimPrefix(s.URL, "http")
+}
+
+// events 记录回调的调用顺序
+type events struct {
+	mutex  sync.Mutex
+	list   []string
+	notify chan string
+}
+
+func newEvents() *events {
+	return &events{notify: make(chan string, 64)}
+}
+
+func (e *events) add(name string) {
+	e.mutex.Lock()
+	e.list = append(e.list, name)
+	e.mutex.Unlock()
+	select {
+	case e.notify <- name:
+	default:
+	}
+}
+
+func (e *events) snapshot() []string {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return append([]string(nil), e.list...)
+}
+
+// wait 等待下一个名为 name 的回调
+func (e *events) wait(t *testing.T, name string) {
+	t.Helper()
+	timer := time.NewTimer(waitTimeout)
+	defer timer.Stop()
+	for {
+		select {
+		case got := <-e.notify:
+			if got == name {
+				return
+			}
+		case <-timer.C:
+			t.Fatalf("timed out waiting for %s, events: %v", name, e.snapshot())
+		}
+	}
+}
+
+// newTestClient 创建缩短了重连间隔的客户端，回调记录到 events；onConnect 额外执行 auth（可为 nil）
+func newTestClient(t *testing.T, url string, auth func(c *Client)) (*Client, *events) {
+	t.Helper()
+	c := New(url, nil)
+	c.SetRetryConfig(-1, 10*time.Millisecond, 50*time.Millisecond)
+	ev := newEvents()
+	c.SetCallbacks(func() {
+		ev.add("connect")
+		if auth != nil {
+			auth(c)
+		}
+	}, func() {
+		ev.add("disconnect")
+	}, func() {
+		ev.add("reconnect")
+	})
+	t.Cleanup(func() { _ = c.Close() })
+	return c, ev
+}
+
+// serverMessage 服务端收到的消息：连接序号和消息类型
+type serverMessage struct {
+	conn    int
+	msgType string
+}
+
+// readTyped 服务端读取下一条消息
+func readTyped(t *testing.T, ws *websocket.Conn) request.WebSocketMessage {
+	t.Helper()
+	var msg request.WebSocketMessage
+	_ = ws.SetReadDeadline(time.Now().Add(waitTimeout))
+	if err := ws.ReadJSON(&msg); err != nil {
+		t.Errorf("server read: %v", err)
+	}
+	return msg
+}
+
+func equalEvents(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestReconnectReauthenticates 服务端断开后自动重连：回调顺序为 connect → disconnect → connect → reconnect，
+// 重连后先重新认证，断线期间入队的消息在认证后补发
+func TestReconnectReauthenticates(t *testing.T) {
+	received := make(chan serverMessage, 16)
+	server := newTestServer(t, func(index int, ws *websocket.Conn) {
+		for {
+			var msg request.WebSocketMessage
+			if err := ws.ReadJSON(&msg); err != nil {
+				return
+			}
+			received <- serverMessage{conn: index, msgType: msg.Type}
+			// 第一个连接收到认证后断开
+			if index == 1 {
+				return
+			}
+		}
+	})
+
+	c, ev := newTestClient(t, server.wsURL(), func(c *Client) {
+		msg, _ := request.NewWebSocketMessage(request.MsgTypeAuth, request.AuthRequest{ServerID: 1, Token: "t"}, "")
+		if err := c.SendDirect(msg); err != nil {
+			t.Errorf("auth: %v", err)
+		}
+		c.MarkReady()
+	})
+	if err := c.ConnectWithAutoReconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	ev.wait(t, "disconnect")
+	// 断线期间入队，重新认证后补发
+	if err := c.Send("test_queued", map[string]string{"k": "v"}); err != nil {
+		t.Fatalf("queue while disconnected: %v", err)
+	}
+	ev.wait(t, "reconnect")
+
+	want := []serverMessage{{1, request.MsgTypeAuth}, {2, request.MsgTypeAuth}, {2, "test_queued"}}
+	for _, w := range want {
+		select {
+		case got := <-received:
+			if got != w {
+				t.Fatalf("server received %v, want %v", got, w)
+			}
+		case <-time.After(waitTimeout):
+			t.Fatalf("timed out waiting for %v", w)
+		}
+	}
+	if got := ev.snapshot(); !equalEvents(got, []string{"connect", "disconnect", "connect", "reconnect"}) {
+		t.Fatalf("callback order = %v", got)
+	}
+}
+
+// TestPingExtendsReadDeadline 没有数据消息时，ping/pong 持续延长读取截止时间，连接保持
+func TestPingExtendsReadDeadline(t *testing.T) {
+	var pingMutex sync.Mutex
+	pings := 0
+	server := newTestServer(t, func(_ int, ws *websocket.Conn) {
+		ws.SetPingHandler(func(data string) error {
+			pingMutex.Lock()
+			pings++
+			pingMutex.Unlock()
+			return ws.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second))
+		})
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	c, ev := newTestClient(t, server.wsURL(), nil)
+	c.SetHeartbeatConfig(time.Hour, 50*time.Millisecond, 200*time.Millisecond)
+	if err := c.ConnectWithAutoReconnect(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 远超 pongWait 的时间内没有任何数据消息
+	time.Sleep(time.Second)
+	if got := ev.snapshot(); !equalEvents(got, []string{"connect"}) {
+		t.Fatalf("connection dropped while pongs were arriving, events: %v", got)
+	}
+	if !c.IsConnected() {
+		t.Fatal("client not connected")
+	}
+	pingMutex.Lock()
+	defer pingMutex.Unlock()
+	if pings < 5 {
+		t.Fatalf("server saw %d pings, want at least 5", pings)
+	}
+}
+
+// TestMessageTooLarge 发送超过最大消息大小的消息返回 ErrMessageTooLarge 且连接保持；收到超限的消息时断开连接
+func TestMessageTooLarge(t *testing.T) {
+	received := make(chan string, 4)
+	oversized := make(chan struct{})
+	var closeOnce sync.Once
+	sendOversized := func() { closeOnce.Do(func() { close(oversized) }) }
+	server := newTestServer(t, func(index int, ws *websocket.Conn) {
+		if index > 1 {
+			<-oversized
+			return
+		}
+		msg := readTyped(t, ws)
+		received <- msg.Type
+		<-oversized
+		_ = ws.WriteMessage(websocket.TextMessage, []byte(`{"type":"big","data":"`+strings.Repeat("x", 4096)+`"}`))
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	// 测试失败提前返回时也要让服务端协程退出
+	t.Cleanup(sendOversized)
+	c, ev := newTestClient(t, server.wsURL(), func(c *Client) { c.MarkReady() })
+	c.maxMessageSize = 1024
+	if err := c.ConnectWithAutoReconnect(); err != nil {
+		t.Fatal(err)
+	}
+	ev.wait(t, "connect")
+
+	err := c.Send("test_big", strings.Repeat("x", 2048))
+	if !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("oversized send err = %v, want ErrMessageTooLarge", err)
+	}
+	// 连接不受影响，之后的消息正常发送
+	if err = c.Send("test_small", "ok"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-received:
+		if got != "test_small" {
+			t.Fatalf("server received %s, want test_small", got)
+		}
+	case <-time.After(waitTimeout):
+		t.Fatal("small message not delivered")
+	}
+
+	sendOversized()
+	ev.wait(t, "disconnect")
+}
+
+// TestCloseCallbacks Close 返回前调用一次 onDisconnect，之后不再调用任何回调，读写都返回 ErrClosed
+func TestCloseCallbacks(t *testing.T) {
+	server := newTestServer(t, func(_ int, ws *websocket.Conn) {
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+
+	c, ev := newTestClient(t, server.wsURL(), nil)
+	if err := c.ConnectWithAutoReconnect(); err != nil {
+		t.Fatal(err)
+	}
+	ev.wait(t, "connect")
+
+	read := make(chan error, 1)
+	go func() {
+		_, err := c.ReadMessage()
+		read <- err
+	}()
+
+	if err := c.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if got := ev.snapshot(); !equalEvents(got, []string{"connect", "disconnect"}) {
+		t.Fatalf("events after Close = %v", got)
+	}
+	select {
+	case err := <-read:
+		if !errors.Is(err, ErrClosed) {
+			t.Fatalf("ReadMessage err = %v, want ErrClosed", err)
+		}
+	case <-time.After(waitTimeout):
+		t.Fatal("ReadMessage still blocked after Close")
+	}
+	if err := c.Send("test_after_close", nil); !errors.Is(err, ErrClosed) {
+		t.Fatalf("Send after Close err = %v, want ErrClosed", err)
+	}
+
+	// 不会重连
+	time.Sleep(200 * time.Millisecond)
+	if got := ev.snapshot(); !equalEvents(got, []string{"connect", "disconnect"}) {
+		t.Fatalf("callbacks after Close returned: %v", got)
+	}
+}
+
+// TestCloseWhileReconnecting 重连等待期间 Close 立即返回，不再调用回调
+func TestCloseWhileReconnecting(t *testing.T) {
+	server := newTestServer(t, func(_ int, ws *websocket.Conn) {})
+
+	c, ev := newTestClient(t, server.wsURL(), nil)
+	c.SetRetryConfig(-1, time.Hour, time.Hour)
+	if err := c.ConnectWithAutoReconnect(); err != nil {
+		t.Fatal(err)
+	}
+	ev.wait(t, "disconnect")
+
+	done := make(chan struct{})
+	go func() {
+		_ = c.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(waitTimeout):
+		t.Fatal("Close blocked while waiting to reconnect")
+	}
+	if got := ev.snapshot(); !equalEvents(got, []string{"connect", "disconnect"}) {
+		t.Fatalf("events = %v", got)
+	}
+}
@@ -0,0 +1,53 @@
+package websocket_client
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"qq_client/model/request"
+)
+
+// ErrUnknownType 没有注册该消息类型的处理函数
+var ErrUnknownType = errors.New("unknown message type")
+
+// Handlers 按消息类型注册的处理函数
+type Handlers struct {
+	mutex    sync.RWMutex
+	handlers map[string]func(request.WebSocketMessage) error
+}
+
+// NewHandlers 创建处理函数注册表
+func NewHandlers() *Handlers {
+	return &Handlers{handlers: make(map[string]func(request.WebSocketMessage) error)}
+}
+
+// Handle 注册类型化的处理函数，载荷解析为 T 后调用（同一类型重复注册时覆盖）
+// @param: h *Handlers
+// @param: msgType string 消息类型
+// @param: handler func(msg, payload T) 处理函数，msg 用于读取信封（Success/Error）和回复
+func Handle[T any](h *Handlers, msgType string, handler func(msg request.WebSocketMessage, payload T)) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.handlers[msgType] = func(msg request.WebSocketMessage) error {
+		var payload T
+		if err := msg.Decode(&payload); err != nil {
+			return fmt.Errorf("invalid %s payload: %w", msgType, err)
+		}
+		handler(msg, payload)
+		return nil
+	}
+}
+
+// Dispatch 调用消息类型对应的处理函数
+// @param: msg request.WebSocketMessage
+// @return: error 未注册该类型时为 ErrUnknownType，载荷无法解析时为解析错误
+func (h *Handlers) Dispatch(msg request.WebSocketMessage) error {
+	h.mutex.RLock()
+	handler, ok := h.handlers[msg.Type]
+	h.mutex.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownType, msg.Type)
+	}
+	return handler(msg)
+}
@@ -0,0 +1,197 @@
+package websocket_client
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"qq_client/model/request"
+)
+
+// 发送优先级：队列已满时先丢弃优先级低的消息，补发时先发送优先级高的消息
+const (
+	PriorityLow    = iota // 心跳回应等过期即无意义的消息
+	PriorityNormal        // 默认
+	PriorityHigh          // 更新状态等需要尽量送达的消息
+)
+
+// ErrQueueFull 发送队列已满且没有优先级更低的消息可以丢弃
+var ErrQueueFull = errors.New("send queue full")
+
+// SendOptions 消息进入发送队列的选项
+type SendOptions struct {
+	Priority int           // PriorityLow/PriorityNormal/PriorityHigh
+	TTL      time.Duration // 超过该时间仍未发送则丢弃，为 0 时使用 OutboxTTL
+}
+
+// QueueStats 发送队列统计
+type QueueStats struct {
+	Queued  int    `json:"queued"`  // 等待发送的消息数
+	Dropped uint64 `json:"dropped"` // 队列已满被丢弃的消息数
+	Expired uint64 `json:"expired"` // 超过 TTL 未发送被丢弃的消息数
+}
+
+// outboxItem 队列中的一条消息
+type outboxItem struct {
+	msgType  string
+	data     []byte
+	priority int
+	expires  time.Time
+	seq      uint64 // 入队顺序，同优先级先进先出
+}
+
+// outbox 有界发送队列，按优先级从高到低、同优先级按入队顺序排列
+// 断线期间消息保留在队列中，连接认证成功（MarkReady）后由写协程补发
+type outbox struct {
+	mutex   sync.Mutex
+	items   []outboxItem
+	limit   int
+	seq     uint64
+	dropped uint64
+	expired uint64
+	notify  chan struct{} // 有新消息或可以发送时通知写协程
+}
+
+// newOutbox 创建发送队列
+func newOutbox(limit int) *outbox {
+	return &outbox{limit: limit, notify: make(chan struct{}, 1)}
+}
+
+// push 消息入队
+// @description: 队列已满时丢弃优先级最低的消息中最早入队的一条；该消息优先级高于新消息时拒绝新消息
+// @return: error 新消息被拒绝时为 ErrQueueFull
+func (q *outbox) push(msgType string, data []byte, opts SendOptions, defaultTTL time.Duration) error {
+	ttl := opts.TTL
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	now := time.Now()
+
+	q.mutex.Lock()
+	q.removeExpired(now)
+	if len(q.items) >= q.limit {
+		// items 按优先级降序排列，最后一段为优先级最低的消息
+		victim := len(q.items) - 1
+		for victim > 0 && q.items[victim-1].priority == q.items[victim].priority {
+			victim--
+		}
+		dropped := q.items[victim]
+		q.dropped++
+		if dropped.priority > opts.Priority {
+			q.mutex.Unlock()
+			log.Printf("Send queue full, dropped %s", msgType)
+			return ErrQueueFull
+		}
+		q.items = append(q.items[:victim], q.items[victim+1:]...)
+		log.Printf("Send queue full, dropped queued %s", dropped.msgType)
+	}
+	q.seq++
+	q.insert(outboxItem{msgType: msgType, data: data, priority: opts.Priority, expires: now.Add(ttl), seq: q.seq})
+	q.mutex.Unlock()
+
+	q.signal()
+	return nil
+}
+
+// pop 取出下一条未过期的消息
+func (q *outbox) pop() (outboxItem, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.removeExpired(time.Now())
+	if len(q.items) == 0 {
+		return outboxItem{}, false
+	}
+	item := q.items[0]
+	q.items = q.items[1:]
+	return item, true
+}
+
+// requeue 发送失败的消息放回原位置
+func (q *outbox) requeue(item outboxItem) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.items) >= q.limit {
+		q.dropped++
+		return
+	}
+	q.insert(item)
+}
+
+// stats 队列统计
+func (q *outbox) stats() QueueStats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return QueueStats{Queued: len(q.items), Dropped: q.dropped, Expired: q.expired}
+}
+
+// signal 通知写协程（不阻塞）
+func (q *outbox) signal() {
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// insert 按优先级和入队顺序插入（调用方持有锁）
+func (q *outbox) insert(item outboxItem) {
+	index := len(q.items)
+	for i, queued := range q.items {
+		if item.priority > queued.priority || item.priority == queued.priority && item.seq < queued.seq {
+			index = i
+			break
+		}
+	}
+	q.items = append(q.items, outboxItem{})
+	copy(q.items[index+1:], q.items[index:])
+	q.items[index] = item
+}
+
+// removeExpired 删除过期消息（调用方持有锁）
+func (q *outbox) removeExpired(now time.Time) {
+	kept := q.items[:0]
+	for _, item := range q.items {
+		if now.After(item.expires) {
+			q.expired++
+			continue
+		}
+		kept = append(kept, item)
+	}
+	q.items = kept
+}
+
+// inbox 收到的消息，读协程放入时不阻塞（读协程阻塞时无法处理 ping/pong，连接会因读取超时断开）
+type inbox struct {
+	mutex  sync.Mutex
+	items  []request.WebSocketMessage
+	notify chan struct{} // 有新消息时通知 ReadMessage
+}
+
+// newInbox 创建接收队列
+func newInbox() *inbox {
+	return &inbox{notify: make(chan struct{}, 1)}
+}
+
+// push 放入一条消息
+func (q *inbox) push(msg request.WebSocketMessage) {
+	q.mutex.Lock()
+	q.items = append(q.items, msg)
+	q.mutex.Unlock()
+	select {
+	case q.notify <- struct{}{}:
+	default:
+	}
+}
+
+// pop 取出最早的消息
+func (q *inbox) pop() (request.WebSocketMessage, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.items) == 0 {
+		return request.WebSocketMessage{}, false
+	}
+	msg := q.items[0]
+	q.items[0] = request.WebSocketMessage{}
+	q.items = q.items[1:]
+	return msg, true
+}
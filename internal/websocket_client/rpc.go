@@ -0,0 +1,137 @@
+package websocket_client
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	_const "qq_client/internal/const"
+	"qq_client/model/request"
+)
+
+// ErrDisconnected 等待回复期间连接断开
+var ErrDisconnected = errors.New("connection lost before reply")
+
+// ReplyError 对方回复了失败（Success 为 false）
+type ReplyError struct {
+	Type    string
+	Message string
+}
+
+func (e *ReplyError) Error() string {
+	return fmt.Sprintf("%s failed: %s", e.Type, e.Message)
+}
+
+// Call 发送请求并等待对方的回复
+// @description: 回复通过 reply_to 与请求的 id 匹配，不会交给 ReadMessage；ctx 未设置截止时间时使用 CallTimeout
+// @param: ctx context.Context
+// @param: msgType string 消息类型
+// @param: payload interface{} 载荷（消息目录中的类型）
+// @return: request.WebSocketMessage 回复, error（超时为 context.DeadlineExceeded，回复失败为 *ReplyError）
+func (c *Client) Call(ctx context.Context, msgType string, payload interface{}) (request.WebSocketMessage, error) {
+	msg, err := request.NewWebSocketMessage(msgType, payload, "")
+	if err != nil {
+		return request.WebSocketMessage{}, err
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, _const.CallTimeout)
+		defer cancel()
+	}
+
+	msg.ID = newMessageID()
+	replyChan := make(chan request.WebSocketMessage, 1)
+	c.pendingMutex.Lock()
+	c.pending[msg.ID] = replyChan
+	c.pendingMutex.Unlock()
+	defer func() {
+		c.pendingMutex.Lock()
+		delete(c.pending, msg.ID)
+		c.pendingMutex.Unlock()
+	}()
+
+	// 回复只会在当前连接上到达，请求不进入发送队列
+	if err = c.SendDirect(msg); err != nil {
+		return request.WebSocketMessage{}, err
+	}
+	select {
+	case reply, ok := <-replyChan:
+		if !ok {
+			return request.WebSocketMessage{}, fmt.Errorf("%s: %w", msgType, ErrDisconnected)
+		}
+		if !reply.Success {
+			return reply, &ReplyError{Type: msgType, Message: reply.Error}
+		}
+		return reply, nil
+	case <-ctx.Done():
+		return request.WebSocketMessage{}, fmt.Errorf("%s: no reply: %w", msgType, ctx.Err())
+	}
+}
+
+// Reply 回复一条消息（type 与原消息相同，reply_to 为原消息的 id），经发送队列发送
+// @param: to request.WebSocketMessage 被回复的消息
+// @param: payload interface{} 载荷
+// @param: errorMsg string 错误信息，为空表示成功
+// @param: opts SendOptions 优先级和 TTL
+// @return: error
+func (c *Client) Reply(to request.WebSocketMessage, payload interface{}, errorMsg string, opts SendOptions) error {
+	msg, err := request.NewWebSocketMessage(to.Type, payload, errorMsg)
+	if err != nil {
+		return err
+	}
+	msg.ReplyTo = to.ID
+	return c.QueueMessage(msg, opts)
+}
+
+// deliverReply 把回复交给等待中的 Call
+// @return: bool 有对应的 Call
+func (c *Client) deliverReply(msg request.WebSocketMessage) bool {
+	if msg.ReplyTo == "" {
+		return false
+	}
+	c.pendingMutex.Lock()
+	replyChan, ok := c.pending[msg.ReplyTo]
+	if ok {
+		delete(c.pending, msg.ReplyTo)
+	}
+	c.pendingMutex.Unlock()
+	if ok {
+		replyChan <- msg
+	}
+	return ok
+}
+
+// failPending 连接断开时结束所有等待中的 Call（回复只会在原连接上到达）
+func (c *Client) failPending() {
+	c.pendingMutex.Lock()
+	defer c.pendingMutex.Unlock()
+	for id, replyChan := range c.pending {
+		close(replyChan)
+		delete(c.pending, id)
+	}
+}
+
+// stamp 补全信封：消息 ID、发送时间和协议版本
+func stamp(msg *request.WebSocketMessage) {
+	if msg.ID == "" {
+		msg.ID = newMessageID()
+	}
+	if msg.Timestamp == 0 {
+		msg.Timestamp = time.Now().UnixMilli()
+	}
+	if msg.Version == 0 {
+		msg.Version = request.ProtocolVersion
+	}
+}
+
+// newMessageID 生成随机消息 ID
+func newMessageID() string {
+	var b [12]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
@@ -5,62 +5,126 @@ import (
 	"fmt"
 	"gopkg.in/yaml.v3"
 	"os"
+	"qq_client/assets"
 	"qq_client/global"
 	"qq_client/internal/client"
+	_const "qq_client/internal/const"
+	"qq_client/internal/control"
+	"qq_client/internal/selfupdate"
+	"qq_client/internal/status"
+	"qq_client/model/request"
 	"qq_client/server"
 	"qq_client/util"
 )
 
-//go:embed config.yaml assets/ocr_setup.bat assets/ocr_setup_simple.bat assets/ocr_server.py assets/download_model.py assets/check_models.py assets/fix_ocr_models.bat
+//go:embed config.yaml
 var File embed.FS
 
-// extractEmbeddedFiles 提取嵌入的文件到当前目录
-func extractEmbeddedFiles() error {
-	// 文件映射：嵌入路径 -> 输出文件名
-	fileMap := map[string]string{
-		"assets/ocr_setup.bat":        "ocr_setup.bat",
-		"assets/ocr_setup_simple.bat": "ocr_setup_simple.bat",
-		"assets/ocr_server.py":        "ocr_server.py",
-		"assets/download_model.py":    "download_model.py",
-		"assets/check_models.py":      "check_models.py",
-		"assets/fix_ocr_models.bat":   "fix_ocr_models.bat",
+// reportPendingUpdateResult 通过每个客户端上报上一次更新的结果，全部放入发送队列后删除结果文件
+// 有客户端放入失败时保留结果文件，下次启动时再次上报
+func reportPendingUpdateResult(clients []*client.Client) {
+	result := selfupdate.PendingResult(".")
+	if result == nil {
+		return
 	}
-
-	for embeddedPath, outputFileName := range fileMap {
-		// 检查文件是否已存在
-		if _, err := os.Stat(outputFileName); !os.IsNotExist(err) {
-			fmt.Printf("文件 %s 已存在，跳过提取\n", outputFileName)
-			continue
+	reported := true
+	for _, c := range clients {
+		if err := c.ReportUpdateResult(result); err != nil {
+			fmt.Println(err)
+			reported = false
 		}
+	}
+	if reported {
+		selfupdate.ClearPendingResult(".")
+	}
+}
 
-		// 从嵌入文件系统中读取文件内容
-		content, err := File.ReadFile(embeddedPath)
-		if err != nil {
-			return fmt.Errorf("读取嵌入文件 %s 失败: %v", embeddedPath, err)
-		}
+// reportAssetMismatches 输出提取后仍与当前版本不一致的 OCR 文件，并加入 client_status 的 OCR 状态
+func reportAssetMismatches(mismatches []assets.FileStatus) {
+	if len(mismatches) == 0 {
+		return
+	}
+	fmt.Printf("[WARN] %d 个 OCR 文件与当前版本不一致:\n", len(mismatches))
+	files := make([]request.StatusAsset, 0, len(mismatches))
+	for _, mismatch := range mismatches {
+		fmt.Printf("[WARN]   %s (%s)\n", mismatch.Name, mismatch.Status)
+		files = append(files, request.StatusAsset{Name: mismatch.Name, Status: mismatch.Status})
+	}
+	status.SetOCRAssets(files)
+}
 
-		// 写入到当前目录
-		err = os.WriteFile(outputFileName, content, 0644)
-		if err != nil {
-			return fmt.Errorf("写入文件 %s 失败: %v", outputFileName, err)
-		}
+// loadConfig 加载配置文件（优先使用嵌入的配置，其次是外部文件）
+func loadConfig() error {
+	var configData []byte
+	var err error
 
-		fmt.Printf("已提取文件: %s\n", outputFileName)
+	// 首先尝试从嵌入文件加载
+	if configData, err = File.ReadFile("config.yaml"); err != nil {
+		fmt.Printf("无法从嵌入文件加载配置: %v\n", err)
+
+		// 尝试从外部文件加载
+		if configData, err = os.ReadFile("config.yaml"); err != nil {
+			return fmt.Errorf("无法从外部文件加载配置: %v", err)
+		}
+		fmt.Println("从外部文件加载配置成功")
+	} else {
+		fmt.Println("从嵌入文件加载配置成功")
 	}
 
+	// 解析配置文件
+	if err = yaml.Unmarshal(configData, &global.ScumConfig); err != nil {
+		return fmt.Errorf("解析配置文件失败: %v", err)
+	}
 	return nil
 }
 
 func main() {
+	// 更新器模式：替换程序并重启（由自我更新启动，不运行客户端）
+	if len(os.Args) > 1 && os.Args[1] == _const.UpdateApplyFlag {
+		os.Exit(selfupdate.RunApply(os.Args[2:]))
+	}
+
+	// 由更新器重新启动时输出到自己的控制台窗口
+	selfupdate.RestoreConsoleOutput()
+
 	// init
 	var err error
+	fmt.Printf("SCUM Client %s\n", global.Version)
+
+	// 更新后的试运行：由更新器启动时需要在限定时间内通过健康检查，否则更新器会恢复旧版本
+	// 试运行参数只对本次启动有效，从 os.Args 中移除，避免之后的重启再次进入试运行
+	probationFile, args := selfupdate.ParseProbationArgs(os.Args[1:])
+	os.Args = append(os.Args[:1], args...)
+	if probationFile != "" {
+		selfupdate.StartProbation(probationFile, global.Version)
+	}
 
 	// 首先提取嵌入的 OCR 相关文件
 	fmt.Println("正在提取 OCR 必需文件...")
-	if err = extractEmbeddedFiles(); err != nil {
+	if mismatches, err := assets.Extract("."); err != nil {
 		fmt.Printf("提取 OCR 文件失败: %v\n", err)
 		fmt.Println("程序将继续运行，但 OCR 功能可能不可用")
+	} else {
+		reportAssetMismatches(mismatches)
+	}
+
+	// 加载配置文件（OCR 服务地址等配置需要在启动 OCR 服务前生效）
+	if err = loadConfig(); err != nil {
+		fmt.Println(err)
+		fmt.Println("程序将退出，请确保配置文件存在")
+		return
+	}
+	global.ApplyOCRServiceConfig(&global.ScumConfig)
+	profiles, err := global.ScumConfig.Profiles()
+	if err != nil {
+		fmt.Println(err)
+		fmt.Println("程序将退出，请检查配置文件中的 servers")
+		return
 	}
+	if err = control.SetLogLevel(global.ScumConfig.LogLevel); err != nil {
+		fmt.Printf("日志级别配置无效，使用 debug: %v\n", err)
+	}
+	selfupdate.MarkHealthy(selfupdate.CheckConfig)
 
 	// 确保 OCR 服务运行
 	fmt.Println("检查 OCR 服务状态...")
@@ -70,45 +134,47 @@ func main() {
 		fmt.Println("请手动运行 ocr_setup.bat 来设置 OCR 环境")
 	} else {
 		fmt.Println("OCR 服务已就绪")
+		selfupdate.MarkHealthy(selfupdate.CheckOCR)
 
-		// 清空文本位置缓存（程序启动时初始化）
-		util.ClearTextPositionCache()
-
-		// 加载配置文件
-		var configData []byte
+		// 启动 OCR 服务监控（崩溃检测、健康检查、自动重启）
+		util.StartOCRSupervisor()
 
-		// 首先尝试从嵌入文件加载
-		if configData, err = File.ReadFile("config.yaml"); err != nil {
-			fmt.Printf("无法从嵌入文件加载配置: %v\n", err)
+		// 加载持久化的文本位置缓存
+		if err = util.LoadTextPositionCache(); err != nil {
+			fmt.Printf("加载文本位置缓存失败: %v\n", err)
+		}
 
-			// 尝试从外部文件加载
-			if configData, err = os.ReadFile("config.yaml"); err != nil {
-				fmt.Printf("无法从外部文件加载配置: %v\n", err)
-				fmt.Println("程序将退出，请确保配置文件存在")
+		// 每个服务器实例一个客户端和一个主循环，实例配置由两者共用
+		instances := make([]*server.Instance, 0, len(profiles))
+		clients := make([]*client.Client, 0, len(profiles))
+		for i := range profiles {
+			profile := &profiles[i]
+			tracker := status.New(profile.Name)
+			controls := control.New()
+
+			// 启动客户端
+			c := client.New(&global.ScumConfig, profile, tracker, controls)
+			if err = c.Start(); err != nil {
+				fmt.Printf("客户端启动失败 %s: %v\n", profile.Name, err)
 				return
 			}
-			fmt.Println("从外部文件加载配置成功")
-		} else {
-			fmt.Println("从嵌入文件加载配置成功")
+			clients = append(clients, c)
+			instances = append(instances, server.NewInstance(profile, tracker, controls))
 		}
 
-		// 解析配置文件
-		if err = yaml.Unmarshal(configData, &global.ScumConfig); err != nil {
-			fmt.Printf("解析配置文件失败: %v\n", err)
-			return
-		}
+		// 上一次更新的结果（如回滚）向每个实例的后端上报
+		reportPendingUpdateResult(clients)
 
-		// 启动客户端
-		if err = client.New(&global.ScumConfig).Start(); err != nil {
-			fmt.Printf("客户端启动失败: %v\n", err)
-			return
-		}
+		// 更新后的试运行全部检查通过时，每个实例都向自己的后端上报更新完成（整个进程只注册一次）
+		selfupdate.SetOnHealthy(func() {
+			for _, c := range clients {
+				c.ReportUpdateCompleted()
+			}
+		})
 
-		fmt.Println("SCUM Client 启动成功")
+		fmt.Printf("SCUM Client 启动成功，实例数: %d\n", len(instances))
 
 		// 循环机器人主逻辑
-		for {
-			server.Start()
-		}
+		server.Run(instances)
 	}
 }
@@ -1,11 +1,215 @@
 package request
 
+import "encoding/json"
+
+// WebSocket 消息目录
+//
+// 所有消息都使用 WebSocketMessage 信封，Data 为下表中对应类型的载荷。
+// 信封中的 id 由发送方生成，回复消息的 reply_to 为所回复消息的 id、type 与原消息相同；
+// 不认识 id/reply_to 的旧版本服务端可以忽略这些字段：
+//
+//	类型              方向              载荷
+//	client_auth       客户端 -> 服务端  AuthRequest
+//	client_auth       服务端 -> 客户端  AuthResponse（Success/Error 为认证结果）
+//	client_heartbeat  双向              Heartbeat
+//	client_update     服务端 -> 客户端  ClientUpdateRequest
+//	client_update     客户端 -> 服务端  ClientUpdateStatus（Success/Error 为更新是否失败）
+//	client_status     客户端 -> 服务端  ClientStatus（定时上报，状态变化时立即上报）
+//	client_screenshot 服务端 -> 客户端  无载荷；回复 DiagnosticsChunk（游戏窗口截图，image/png）
+//	client_log_tail   服务端 -> 客户端  LogTailRequest；回复 DiagnosticsChunk（日志末尾若干行，text/plain）
+//	client_config     服务端 -> 客户端  无载荷；回复 DiagnosticsChunk（生效的配置，敏感值已替换，application/json）
+//	client_text_cache 服务端 -> 客户端  无载荷；回复 DiagnosticsChunk（文本位置缓存，application/json）
+//	client_control    服务端 -> 客户端  ControlRequest；回复 ControlResult（Success/Error 为执行结果）
+//
+// 诊断结果可能超过最大消息大小，按分片依次回复同一请求（reply_to 相同），
+// 服务端按 transfer_id 收齐 total 个分片后按 index 拼接，并用 sha256 校验完整内容；
+// 失败时只回复一条 Success 为 false 的消息，没有载荷
+const (
+	ProtocolVersion = 1 // 协议版本，信封格式或消息语义不兼容变化时递增
+
+	MsgTypeAuth         = "client_auth"      // 与后端保持一致
+	MsgTypeHeartbeat    = "client_heartbeat" // 与后端保持一致
+	MsgTypeClientUpdate = "client_update"
+	MsgTypeClientStatus = "client_status"
+
+	MsgTypeScreenshot = "client_screenshot"
+	MsgTypeLogTail    = "client_log_tail"
+	MsgTypeConfig     = "client_config"
+	MsgTypeTextCache  = "client_text_cache"
+	MsgTypeControl    = "client_control"
+)
+
 // WebSocketMessage represents a message sent over WebSocket
 type WebSocketMessage struct {
-	Type    string      `json:"type"`
-	Data    interface{} `json:"data,omitempty"`
-	Error   string      `json:"error,omitempty"`
-	Success bool        `json:"success"`
+	ID        string          `json:"id,omitempty"`        // 消息 ID（发送时生成）
+	ReplyTo   string          `json:"reply_to,omitempty"`  // 所回复消息的 ID，非回复消息为空
+	Timestamp int64           `json:"timestamp,omitempty"` // 发送时间（Unix 毫秒）
+	Version   int             `json:"version,omitempty"`   // 协议版本
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data,omitempty"` // 载荷，类型见消息目录
+	Error     string          `json:"error,omitempty"`
+	Success   bool            `json:"success"`
+}
+
+// NewWebSocketMessage 创建消息
+// @param: msgType string 消息类型
+// @param: payload interface{} 载荷（消息目录中的类型，nil 表示无载荷）
+// @param: errorMsg string 错误信息，为空时 Success 为 true
+// @return: WebSocketMessage, error
+func NewWebSocketMessage(msgType string, payload interface{}, errorMsg string) (WebSocketMessage, error) {
+	msg := WebSocketMessage{Type: msgType, Success: errorMsg == "", Error: errorMsg}
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return msg, err
+		}
+		msg.Data = data
+	}
+	return msg, nil
+}
+
+// Decode 将载荷解析到 v（没有载荷时 v 保持不变）
+func (m WebSocketMessage) Decode(v interface{}) error {
+	if len(m.Data) == 0 || string(m.Data) == "null" {
+		return nil
+	}
+	return json.Unmarshal(m.Data, v)
+}
+
+// AuthRequest client_auth 认证请求
+type AuthRequest struct {
+	ServerID uint   `json:"server_id"`
+	Token    string `json:"token"`
+	Version  string `json:"version"` // 客户端版本
+}
+
+// AuthResponse client_auth 认证响应
+type AuthResponse struct {
+	FtpProvider int `json:"ftp_provider,omitempty"` // 服务器 FTP 提供商类型，为 0 时不修改
+}
+
+// Heartbeat client_heartbeat 心跳
+type Heartbeat struct {
+	Timestamp int64 `json:"timestamp"` // Unix 秒
+}
+
+// ClientUpdateRequest client_update 服务端下发的更新
+type ClientUpdateRequest struct {
+	Action      string `json:"action"` // update
+	Type        string `json:"type"`   // self_update
+	Version     string `json:"version"`
+	DownloadURL string `json:"download_url"`
+	SHA256      string `json:"sha256"`
+	Signature   string `json:"signature"`
+}
+
+// ClientUpdateStatus client_update 客户端上报的更新状态
+type ClientUpdateStatus struct {
+	Type           string `json:"type"`              // self_update
+	Status         string `json:"status"`            // starting/checking/.../rolled_back
+	Version        string `json:"version"`           // 更新版本
+	CurrentVersion string `json:"current_version"`   // 当前运行的版本
+	Message        string `json:"message,omitempty"` // 状态说明
+	Reason         string `json:"reason,omitempty"`  // 拒绝或回滚的原因
+	Time           int64  `json:"time,omitempty"`    // 结果产生时间（Unix 秒），仅上报回滚结果时使用
+}
+
+// ClientStatus client_status 客户端状态
+type ClientStatus struct {
+	Instance        string         `json:"instance,omitempty"` // 实例名称，单实例时为空
+	Version         string         `json:"version"`
+	Uptime          int64          `json:"uptime"`                 // 客户端运行时间（秒）
+	GameState       string         `json:"game_state"`             // LOGIN/LOADING/GAME_MAIN/GAME_GLOBAL 等，游戏未运行为 NOT_RUNNING
+	Paused          bool           `json:"paused"`                 // 聊天监控是否被远程暂停
+	ChatMode        string         `json:"chat_mode,omitempty"`    // GLOBAL/LOCAL/ADMIN/UNKNOWN，聊天框未打开时为空
+	PendingCommands int            `json:"pending_commands"`       // 待执行的服务器指令数
+	SendQueue       StatusQueue    `json:"send_queue"`             // WebSocket 发送队列
+	LastCommand     *StatusCommand `json:"last_command,omitempty"` // 最近一次执行的指令
+	OCR             StatusOCR      `json:"ocr"`
+	Game            *StatusGame    `json:"game,omitempty"` // 游戏进程，未找到游戏窗口时为空
+	Restarts        map[string]int `json:"restarts"`       // 各类重启次数（game、game_launch、ocr、websocket）
+	Host            StatusHost     `json:"host"`
+	ChangedAt       int64          `json:"changed_at"` // 状态最近一次变化的时间（Unix 秒）
+}
+
+// StatusQueue 发送队列统计
+type StatusQueue struct {
+	Queued  int    `json:"queued"`
+	Dropped uint64 `json:"dropped"` // 队列已满被丢弃的消息数
+	Expired uint64 `json:"expired"` // 超过有效期被丢弃的消息数
+}
+
+// StatusCommand 最近一次执行的指令
+type StatusCommand struct {
+	LatencyMs int64 `json:"latency_ms"`
+	Success   bool  `json:"success"`
+	Time      int64 `json:"time"` // Unix 秒
+}
+
+// StatusOCR OCR 服务健康状态
+type StatusOCR struct {
+	Available bool          `json:"available"`
+	Reason    string        `json:"reason,omitempty"` // 不可用的原因
+	Assets    []StatusAsset `json:"assets,omitempty"` // 启动时与当前版本不一致的 OCR 脚本和字典
+}
+
+// StatusAsset 与当前版本不一致的嵌入文件
+type StatusAsset struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // outdated/modified/missing
+}
+
+// StatusGame 游戏进程和窗口
+type StatusGame struct {
+	PID    int32        `json:"pid"`
+	Uptime int64        `json:"uptime"` // 进程运行时间（秒），无法获取时为 0
+	Window StatusWindow `json:"window"` // 客户区在屏幕上的位置和大小
+}
+
+// StatusWindow 窗口位置和大小
+type StatusWindow struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// StatusHost 主机资源
+type StatusHost struct {
+	CPUPercent    float64 `json:"cpu_percent"`
+	MemoryPercent float64 `json:"memory_percent"`
+	MemoryUsed    uint64  `json:"memory_used"`  // 字节
+	MemoryTotal   uint64  `json:"memory_total"` // 字节
+}
+
+// LogTailRequest client_log_tail 读取日志的请求
+type LogTailRequest struct {
+	File  string `json:"file,omitempty"`  // logs 目录中的文件名，为空时读取最新的客户端日志
+	Lines int    `json:"lines,omitempty"` // 行数，为 0 时使用默认值
+}
+
+// ControlRequest client_control 远程控制请求
+type ControlRequest struct {
+	Action string `json:"action"`          // pause_chat/resume_chat/restart_game/restart_ocr/clear_text_cache/replace_game_config/set_log_level
+	Level  string `json:"level,omitempty"` // set_log_level 的日志级别：debug/info/error
+}
+
+// ControlResult client_control 远程控制的执行结果
+type ControlResult struct {
+	Action  string `json:"action"`
+	Message string `json:"message,omitempty"` // 执行结果说明
+}
+
+// DiagnosticsChunk 诊断结果的一个分片
+type DiagnosticsChunk struct {
+	TransferID  string `json:"transfer_id"`    // 同一结果的所有分片相同
+	Index       int    `json:"index"`          // 分片序号，从 0 开始
+	Total       int    `json:"total"`          // 分片总数
+	Name        string `json:"name,omitempty"` // 内容名称（如日志文件名）
+	ContentType string `json:"content_type"`   // image/png、text/plain、application/json
+	Size        int    `json:"size"`           // 完整内容的字节数
+	SHA256      string `json:"sha256"`         // 完整内容的 SHA-256（十六进制）
+	Data        []byte `json:"data"`           // 本分片的内容（JSON 中为 base64）
 }
 
 // OcrItem represents a single OCR recognition item
@@ -26,8 +230,20 @@ type OcrPosition struct {
 
 // OcrResult represents OCR recognition result
 type OcrResult struct {
+	Code    int              `json:"code"`
+	Data    interface{}      `json:"data"`              // 合并的完整文字（向后兼容）
+	Items   []OcrItem        `json:"items"`             // 识别到的文本块数组（新格式）
+	Matches []OcrItem        `json:"matches,omitempty"` // 匹配目标文字的文本块（指定 target_text 时）
+	Results []OcrQueryResult `json:"results,omitempty"` // 每个查询的结果（指定 queries 时）
+	Message string           `json:"message"`           // 响应消息
+}
+
+// OcrQueryResult represents the result of one query on a shared frame
+type OcrQueryResult struct {
+	ID      string      `json:"id"`
 	Code    int         `json:"code"`
-	Data    interface{} `json:"data"`    // 合并的完整文字（向后兼容）
-	Items   []OcrItem   `json:"items"`   // 识别到的文本块数组（新格式）
-	Message string      `json:"message"` // 响应消息
+	Data    interface{} `json:"data"`
+	Items   []OcrItem   `json:"items"`
+	Matches []OcrItem   `json:"matches,omitempty"`
+	Message string      `json:"message"`
 }
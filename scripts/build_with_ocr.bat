@@ -84,7 +84,9 @@ REM 生成UUID作为文件名
 for /f "tokens=*" %%i in ('powershell -command "[System.Guid]::NewGuid().ToString()"') do set UUID=%%i
 set OUTPUT_NAME=scum_client_%UUID%.exe
 
-go build -o %OUTPUT_NAME% main.go
+REM 版本号和更新签名公钥从环境变量读取（未设置公钥的程序拒绝自动更新）
+if "%VERSION%"=="" set VERSION=0.0.0-dev
+go build -ldflags "-X qq_client/global.Version=%VERSION% -X qq_client/global.UpdatePublicKey=%UPDATE_PUBLIC_KEY%" -o %OUTPUT_NAME% .
 if %errorlevel% neq 0 (
     echo ❌ 主程序编译失败
     pause
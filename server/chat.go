@@ -6,7 +6,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"github.com/atotto/clipboard"
 	"github.com/go-vgo/robotgo"
 	"io"
 	"log"
@@ -15,6 +14,7 @@ import (
 	"path/filepath"
 	"qq_client/global"
 	_const "qq_client/internal/const"
+	"qq_client/internal/control"
 	"qq_client/util"
 	"regexp"
 	"strings"
@@ -35,19 +35,9 @@ var logFile *os.File
 var logger *log.Logger
 
 // 定时指令状态追踪
-var lastPeriodicCommandTime time.Time
 var currentPeriodicCommand string
 var lastClipboardContent string
 
-// 批量指令获取缓存
-var commandBatch []string
-var lastCommandFetchTime time.Time
-
-// 执行性能优化相关变量
-var commandStats map[string]*CommandStats
-var lastResponseTimes map[string]time.Duration
-var preProcessedCommands map[string]string
-
 // CommandStats 指令执行统计
 type CommandStats struct {
 	TotalExecutions int
@@ -77,18 +67,22 @@ func init() {
 	}
 
 	// 创建多重写入器，同时输出到控制台和文件
-	logger = log.New(io.MultiWriter(os.Stdout, logFile), "", log.LstdFlags)
+	logger = log.New(io.MultiWriter(stdoutWriter{}, logFile), "", log.LstdFlags)
 	logger.Printf("=== SCUM Client 启动 ===")
+}
 
-	// 初始化性能优化相关变量
-	commandStats = make(map[string]*CommandStats)
-	lastResponseTimes = make(map[string]time.Duration)
-	preProcessedCommands = make(map[string]string)
-	lastPeriodicCommandTime = time.Now()
+// stdoutWriter 写入当前的 os.Stdout（启动时可能被 selfupdate.RestoreConsoleOutput 替换为控制台）
+type stdoutWriter struct{}
+
+func (stdoutWriter) Write(p []byte) (int, error) {
+	return os.Stdout.Write(p)
 }
 
 // 统一的日志函数
 func logInfo(format string, v ...interface{}) {
+	if !control.LogEnabled(control.LogInfo) {
+		return
+	}
 	if logger != nil {
 		logger.Printf("[INFO] "+format, v...)
 	} else {
@@ -105,6 +99,9 @@ func logError(format string, v ...interface{}) {
 }
 
 func logDebug(format string, v ...interface{}) {
+	if !control.LogEnabled(control.LogDebug) {
+		return
+	}
 	if logger != nil {
 		logger.Printf("[DEBUG] "+format, v...)
 	} else {
@@ -116,19 +113,21 @@ func logDebug(format string, v ...interface{}) {
 func isChatInterfaceOpen(hand syscall.Handle) string {
 	// 检查MUTE按钮是否存在（聊天界面的标志）
 	if util.ExtractTextFromSpecifiedAreaAndValidateThreeTimes(hand, "MUTE") == nil {
-		// 检查当前聊天模式，按输入框颜色
-		cache, _ := util.GetTextPositionFromCache("MUTE")
-		colorHex := util.SpecifiedCoordinateColor(hand, cache.X2+100, cache.Y1+5)
-		fmt.Println("<UNK>", colorHex)
-		chatMode := util.GetChatModeByColor(colorHex)
-		return chatMode
+		return currentChatMode(hand)
 	}
 	return ""
 }
 
+// currentChatMode 聊天界面已打开时，按输入框颜色判断当前聊天模式
+func currentChatMode(hand syscall.Handle) string {
+	colorHex := util.LayoutPointColor(hand, _const.AnchorChatModeProbe)
+	fmt.Println("<UNK>", colorHex)
+	return util.GetChatModeByColor(colorHex)
+}
+
 // 优化的聊天框激活函数
-func ensureChatBoxActive(hand syscall.Handle) bool {
-	logDebug("开始检查聊天框状态")
+func (inst *Instance) ensureChatBoxActive(hand syscall.Handle) bool {
+	inst.logDebug("开始检查聊天框状态")
 
 	// 设置窗口为前台 - 已注释：使用句柄操作不需要窗口置顶
 	// util.SetForegroundWindow(hand)
@@ -137,7 +136,7 @@ func ensureChatBoxActive(hand syscall.Handle) bool {
 	// 首先检查是否已经在聊天界面
 	currentMode := isChatInterfaceOpen(hand)
 	if currentMode == "" {
-		logDebug("聊天界面未打开，尝试按T键激活")
+		inst.logDebug("聊天界面未打开，尝试按T键激活")
 
 		// 先按ESC确保退出任何菜单
 		_ = util.KeyTapToWindow(hand, _const.VK_ESCAPE)
@@ -149,17 +148,17 @@ func ensureChatBoxActive(hand syscall.Handle) bool {
 
 		// 验证是否成功激活
 		if currentMode = isChatInterfaceOpen(hand); currentMode == "" {
-			logError("按T后仍无法激活聊天界面")
+			inst.logError("按T后仍无法激活聊天界面")
 			return false
 		}
-		logDebug("聊天界面已激活")
+		inst.logDebug("聊天界面已激活")
 	} else {
-		logDebug("聊天界面已经处于激活状态")
+		inst.logDebug("聊天界面已经处于激活状态")
 	}
 
 	maxAttempts := 5
 	for i := 0; i < maxAttempts && currentMode != "GLOBAL"; i++ {
-		logDebug("尝试切换到GLOBAL模式，当前: %s，尝试次数: %d", currentMode, i+1)
+		inst.logDebug("尝试切换到GLOBAL模式，当前: %s，尝试次数: %d", currentMode, i+1)
 
 		switch currentMode {
 		case "LOCAL":
@@ -171,7 +170,7 @@ func ensureChatBoxActive(hand syscall.Handle) bool {
 			_ = util.KeyTapToWindow(hand, _const.VK_TAB)
 			time.Sleep(300 * time.Millisecond)
 		case "UNKNOWN":
-			logError("未知聊天模式，尝试按tab切换")
+			inst.logError("未知聊天模式，尝试按tab切换")
 			_ = util.KeyTapToWindow(hand, _const.VK_TAB)
 			time.Sleep(300 * time.Millisecond)
 		}
@@ -181,22 +180,24 @@ func ensureChatBoxActive(hand syscall.Handle) bool {
 	}
 
 	if currentMode == "GLOBAL" {
-		logDebug("聊天框已切换到GLOBAL模式")
+		inst.logDebug("聊天框已切换到GLOBAL模式")
 		return true
 	} else {
-		logError("无法切换到GLOBAL模式，当前模式: %s", currentMode)
+		inst.logError("无法切换到GLOBAL模式，当前模式: %s", currentMode)
 		return false
 	}
 }
 
 // 写入剪贴板并验证
-func writeToClipboard(text string) error {
+func writeToClipboard(session *util.InputSession, text string) error {
 	maxAttempts := 8
 	for i := 0; i < maxAttempts; i++ {
-		_ = clipboard.WriteAll(text)
+		if err := session.WriteClipboard(text); errors.Is(err, util.ErrInputSessionEnded) {
+			return err
+		}
 		time.Sleep(100 * time.Millisecond)
 
-		if content, err := clipboard.ReadAll(); err == nil && content == text {
+		if content, err := session.ReadClipboard(); err == nil && content == text {
 			logDebug("剪贴板写入成功: %s", text[:min(50, len(text))])
 			return nil
 		}
@@ -215,8 +216,8 @@ func min(a, b int) int {
 }
 
 // preProcessCommand 预处理指令，减少执行时的处理时间
-func preProcessCommand(text string) string {
-	if processed, exists := preProcessedCommands[text]; exists {
+func (inst *Instance) preProcessCommand(text string) string {
+	if processed, exists := inst.preProcessedCommands[text]; exists {
 		return processed
 	}
 
@@ -228,13 +229,13 @@ func preProcessCommand(text string) string {
 	}
 
 	// 缓存预处理结果
-	preProcessedCommands[text] = commandToSend
+	inst.preProcessedCommands[text] = commandToSend
 	return commandToSend
 }
 
 // getOptimalWaitTime 根据历史数据获取最优等待时间
-func getOptimalWaitTime(command string) time.Duration {
-	if lastTime, exists := lastResponseTimes[command]; exists {
+func (inst *Instance) getOptimalWaitTime(command string) time.Duration {
+	if lastTime, exists := inst.lastResponseTimes[command]; exists {
 		// 基于历史响应时间优化等待时间
 		switch command {
 		case "#ListPlayers true":
@@ -267,8 +268,9 @@ func getOptimalWaitTime(command string) time.Duration {
 }
 
 // updateCommandStats 更新指令执行统计
-func updateCommandStats(command string, duration time.Duration, success bool) {
-	if stats, exists := commandStats[command]; exists {
+func (inst *Instance) updateCommandStats(command string, duration time.Duration, success bool) {
+	inst.status.RecordCommand(duration, success)
+	if stats, exists := inst.commandStats[command]; exists {
 		stats.TotalExecutions++
 		// 计算平均响应时间
 		stats.AverageTime = (stats.AverageTime*time.Duration(stats.TotalExecutions-1) + duration) / time.Duration(stats.TotalExecutions)
@@ -281,7 +283,7 @@ func updateCommandStats(command string, duration time.Duration, success bool) {
 			stats.SuccessRate = stats.SuccessRate * float64(stats.TotalExecutions-1) / float64(stats.TotalExecutions)
 		}
 	} else {
-		commandStats[command] = &CommandStats{
+		inst.commandStats[command] = &CommandStats{
 			TotalExecutions: 1,
 			AverageTime:     duration,
 			LastExecuteTime: time.Now(),
@@ -296,21 +298,23 @@ func updateCommandStats(command string, duration time.Duration, success bool) {
 
 	// 更新历史响应时间
 	if success {
-		lastResponseTimes[command] = duration
+		inst.lastResponseTimes[command] = duration
 	}
 }
 
 // fastClipboardOperation 快速剪贴板操作
-func fastClipboardOperation(text string) error {
+func fastClipboardOperation(session *util.InputSession, text string) error {
 	// 使用更激进的重试策略
 	maxAttempts := 3
 	for i := 0; i < maxAttempts; i++ {
-		_ = clipboard.WriteAll(text)
+		if err := session.WriteClipboard(text); errors.Is(err, util.ErrInputSessionEnded) {
+			return err
+		}
 
 		// 减少验证时间
 		time.Sleep(30 * time.Millisecond)
 
-		if content, err := clipboard.ReadAll(); err == nil && content == text {
+		if content, err := session.ReadClipboard(); err == nil && content == text {
 			return nil
 		}
 
@@ -323,11 +327,11 @@ func fastClipboardOperation(text string) error {
 }
 
 // parallelSquadSend 并行发送squad数据，不阻塞主流程
-func parallelSquadSend(body map[string]interface{}) {
+func (inst *Instance) parallelSquadSend(body map[string]interface{}) {
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
-				logError("并行发送squad数据时发生panic: %v", r)
+				inst.logError("并行发送squad数据时发生panic: %v", r)
 			}
 		}()
 
@@ -349,7 +353,7 @@ func parallelSquadSend(body map[string]interface{}) {
 
 		if byteBody, err = json.Marshal(&body); err == nil {
 			if req, err = http.NewRequest("POST", fmt.Sprintf(
-				"%s/api/v1/squad", global.ScumConfig.ServerUrl), bytes.NewReader(byteBody)); err == nil {
+				"%s/api/v1/squad", inst.profile.ServerUrl), bytes.NewReader(byteBody)); err == nil {
 				req.Header.Set("Content-Type", "application/json")
 				if resp, err = httpClient.Do(req); err == nil {
 					_, _ = io.ReadAll(resp.Body)
@@ -359,7 +363,7 @@ func parallelSquadSend(body map[string]interface{}) {
 		}
 
 		if err != nil {
-			logDebug("并行发送squad数据失败: %v", err)
+			inst.logDebug("并行发送squad数据失败: %v", err)
 		}
 	}()
 }
@@ -368,7 +372,7 @@ func parallelSquadSend(body map[string]interface{}) {
 // @author: [Fantasia](https://www.npc0.com)
 // @function: run
 // @description: 获取运行命令
-func run() string {
+func (inst *Instance) run() string {
 	// init
 	var err error
 	var bodyBytes []byte
@@ -378,7 +382,7 @@ func run() string {
 		TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
 	}}
 	if req, err = http.NewRequest("GET", fmt.Sprintf(
-		"%s/api/v1/run?id=%d", global.ScumConfig.ServerUrl, global.ScumConfig.ServerID), nil); err == nil {
+		"%s/api/v1/run?id=%d", inst.profile.ServerUrl, inst.profile.ServerID), nil); err == nil {
 		req.Header.Set("Content-Type", "application/json")
 		if resp, err = httpClient.Do(req); err == nil {
 			if bodyBytes, err = io.ReadAll(resp.Body); err == nil {
@@ -393,10 +397,10 @@ func run() string {
 // @author: [Fantasia](https://www.npc0.com)
 // @function: getAllPendingCommands
 // @description: 获取当前服务器的所有待处理指令
-func getAllPendingCommands() []string {
+func (inst *Instance) getAllPendingCommands() []string {
 	// 检查缓存是否过期（30秒刷新一次）
-	if time.Since(lastCommandFetchTime) < 30*time.Second && len(commandBatch) > 0 {
-		return commandBatch
+	if time.Since(inst.lastCommandFetchTime) < 30*time.Second && len(inst.commandBatch) > 0 {
+		return inst.commandBatch
 	}
 
 	// init
@@ -412,15 +416,15 @@ func getAllPendingCommands() []string {
 
 	// 获取批量指令
 	if req, err = http.NewRequest("GET", fmt.Sprintf(
-		"%s/api/v1/run/batch?id=%d", global.ScumConfig.ServerUrl, global.ScumConfig.ServerID), nil); err == nil {
+		"%s/api/v1/run/batch?id=%d", inst.profile.ServerUrl, inst.profile.ServerID), nil); err == nil {
 		req.Header.Set("Content-Type", "application/json")
 		if resp, err = httpClient.Do(req); err == nil {
 			if bodyBytes, err = io.ReadAll(resp.Body); err == nil {
 				// 解析JSON数组
 				if err = json.Unmarshal(bodyBytes, &commands); err == nil {
-					commandBatch = commands
-					lastCommandFetchTime = time.Now()
-					logDebug("批量获取到 %d 条指令", len(commands))
+					inst.commandBatch = commands
+					inst.lastCommandFetchTime = time.Now()
+					inst.logDebug("批量获取到 %d 条指令", len(commands))
 					return commands
 				}
 			}
@@ -428,10 +432,10 @@ func getAllPendingCommands() []string {
 	}
 
 	// 如果批量获取失败，回退到单条获取
-	if singleCommand := run(); singleCommand != "" {
+	if singleCommand := inst.run(); singleCommand != "" {
 		commands = []string{singleCommand}
-		commandBatch = commands
-		lastCommandFetchTime = time.Now()
+		inst.commandBatch = commands
+		inst.lastCommandFetchTime = time.Now()
 	}
 
 	return commands
@@ -441,21 +445,21 @@ func getAllPendingCommands() []string {
 // @author: [Fantasia](https://www.npc0.com)
 // @function: executePeriodicCommands
 // @description: 执行定时指令（每分钟执行的三个固定指令）- 高速优化版本
-func executePeriodicCommands(hwnd syscall.Handle) {
+func (inst *Instance) executePeriodicCommands(hwnd syscall.Handle) {
 	// 检查是否到了执行时间（每分钟执行一次）
-	if time.Since(lastPeriodicCommandTime) < 60*time.Second {
+	if time.Since(inst.lastPeriodicCommandTime) < 60*time.Second {
 		return
 	}
 
 	// 自建服务器和命令行服务器不执行这三个固定指令（由scum_run自动推送）
-	if global.ScumConfig.FtpProvider == global.FtpProviderSelfBuilt || global.ScumConfig.FtpProvider == global.FtpProviderCommandLine {
-		logDebug("自建服务器或命令行服务器类型，跳过定时指令执行（由scum_run自动推送）")
-		lastPeriodicCommandTime = time.Now()
+	if inst.profile.FtpProvider == global.FtpProviderSelfBuilt || inst.profile.FtpProvider == global.FtpProviderCommandLine {
+		inst.logDebug("自建服务器或命令行服务器类型，跳过定时指令执行（由scum_run自动推送）")
+		inst.lastPeriodicCommandTime = time.Now()
 		return
 	}
 
 	startTime := time.Now()
-	logInfo("开始高速执行定时指令...")
+	inst.logInfo("开始高速执行定时指令...")
 
 	// 定义三个固定指令
 	periodicCommands := []string{
@@ -465,19 +469,19 @@ func executePeriodicCommands(hwnd syscall.Handle) {
 	}
 
 	// 激活聊天框
-	if !ensureChatBoxActive(hwnd) {
-		logError("无法激活聊天框进行定时指令执行")
+	if !inst.ensureChatBoxActive(hwnd) {
+		inst.logError("无法激活聊天框进行定时指令执行")
 		return
 	}
 
 	successCount := 0
 	// 高速依次执行每个指令
 	for i, command := range periodicCommands {
-		logInfo("高速执行定时指令 [%d/%d]: %s", i+1, len(periodicCommands), command)
+		inst.logInfo("高速执行定时指令 [%d/%d]: %s", i+1, len(periodicCommands), command)
 
 		// 发送指令
-		if out, err := Send(hwnd, command); err != nil {
-			logError("定时指令执行失败 %s: %v", command, err)
+		if out, err := inst.Send(hwnd, command); err != nil {
+			inst.logError("定时指令执行失败 %s: %v", command, err)
 			continue
 		} else if out != "" {
 			successCount++
@@ -494,12 +498,12 @@ func executePeriodicCommands(hwnd syscall.Handle) {
 
 			if mode != "" {
 				// 使用并行发送，提升性能
-				parallelSquadSend(map[string]interface{}{
-					"id":   global.ScumConfig.ServerID,
+				inst.parallelSquadSend(map[string]interface{}{
+					"id":   inst.profile.ServerID,
 					"mode": mode,
 					"info": out,
 				})
-				logDebug("定时指令结果已并行发送: %s, 数据长度: %d", command, len(out))
+				inst.logDebug("定时指令结果已并行发送: %s, 数据长度: %d", command, len(out))
 			}
 		}
 
@@ -515,34 +519,40 @@ func executePeriodicCommands(hwnd syscall.Handle) {
 
 	// 关闭聊天框
 	duration := time.Since(startTime)
-	logInfo("定时指令执行完毕，成功: %d/%d，耗时: %v，关闭聊天框",
+	inst.logInfo("定时指令执行完毕，成功: %d/%d，耗时: %v，关闭聊天框",
 		successCount, len(periodicCommands), duration)
 
 	_ = util.KeyTapToWindow(hwnd, _const.VK_ESCAPE)
 	time.Sleep(200 * time.Millisecond) // 从300ms减少到200ms
 
 	// 更新最后执行时间
-	lastPeriodicCommandTime = time.Now()
+	inst.lastPeriodicCommandTime = time.Now()
 }
 
 // ChatMonitorWithActivation
 // @author: [Fantasia](https://www.npc0.com)
 // @function: ChatMonitorWithActivation
 // @description: 带激活功能的聊天监控 - 高速优化版本
-func ChatMonitorWithActivation(hwnd syscall.Handle) {
-	logInfo("开始智能聊天监控（高速按需激活模式）...")
+func (inst *Instance) ChatMonitorWithActivation(hwnd syscall.Handle) {
+	inst.logInfo("开始智能聊天监控（高速按需激活模式）...")
 
 	for {
+		// 远程暂停或请求重启游戏时退出监控，由主循环处理
+		if inst.control.Interrupted() {
+			inst.logInfo("聊天监控已中断（暂停或重启游戏）")
+			return
+		}
+
 		// 获取所有待处理指令
-		commands := getAllPendingCommands()
+		commands := inst.getAllPendingCommands()
 
 		if len(commands) > 0 {
 			batchStartTime := time.Now()
-			logInfo("检测到 %d 条待处理指令，启动高速批量处理", len(commands))
+			inst.logInfo("检测到 %d 条待处理指令，启动高速批量处理", len(commands))
 
 			// 激活聊天框
-			if !ensureChatBoxActive(hwnd) {
-				logError("无法激活聊天框")
+			if !inst.ensureChatBoxActive(hwnd) {
+				inst.logError("无法激活聊天框")
 				time.Sleep(2 * time.Second) // 从5秒减少到2秒
 				continue
 			}
@@ -554,25 +564,20 @@ func ChatMonitorWithActivation(hwnd syscall.Handle) {
 					continue
 				}
 
-				logInfo("高速执行指令 [%d/%d]: %s", i+1, len(commands), command)
-
-				var out string
-				var err error
-				if out, err = Send(hwnd, command); err != nil {
-					logError("指令执行失败: %v", err)
-					// 快速重试一次
-					time.Sleep(300 * time.Millisecond) // 从1秒减少到300ms
-					if out, err = Send(hwnd, command); err != nil {
-						logError("快速重试失败: %v", err)
-						continue
-					}
+				inst.logInfo("高速执行指令 [%d/%d]: %s", i+1, len(commands), command)
+
+				// 发送失败时快速重试一次，执行结果上报给后台
+				resp, err := inst.runServerCommand(hwnd, command, 300*time.Millisecond)
+				if err != nil {
+					inst.logError("快速重试失败: %v", err)
+					continue
 				}
 
-				// 异步处理指令结果，不阻塞主流程
-				if out != "" {
+				// 异步处理剪贴板输出，不阻塞主流程
+				if out := resp.Output; resp.Source == _const.ResponseSourceClipboard && out != "" {
 					go func(result string) {
-						SaveChat(result)
-						logDebug("指令结果已异步保存，长度: %d", len(result))
+						inst.SaveChat(result)
+						inst.logDebug("指令结果已异步保存，长度: %d", len(result))
 					}(out)
 				}
 
@@ -591,19 +596,19 @@ func ChatMonitorWithActivation(hwnd syscall.Handle) {
 
 			// 执行完所有指令后关闭聊天框
 			batchDuration := time.Since(batchStartTime)
-			logInfo("批量指令执行完毕，成功: %d/%d，耗时: %v，关闭聊天框",
+			inst.logInfo("批量指令执行完毕，成功: %d/%d，耗时: %v，关闭聊天框",
 				successCount, len(commands), batchDuration)
 
 			_ = util.KeyTapToWindow(hwnd, _const.VK_ESCAPE)
 			time.Sleep(200 * time.Millisecond) // 从300ms减少到200ms
 
 			// 清空指令缓存
-			commandBatch = []string{}
-			lastCommandFetchTime = time.Time{}
+			inst.commandBatch = []string{}
+			inst.lastCommandFetchTime = time.Time{}
 		}
 
 		// 执行定时指令
-		executePeriodicCommands(hwnd)
+		inst.executePeriodicCommands(hwnd)
 
 		// 动态等待时间（根据当前负载调整）
 		if len(commands) > 10 {
@@ -618,16 +623,16 @@ func ChatMonitorWithActivation(hwnd syscall.Handle) {
 // @author: [Fantasia](https://www.npc0.com)
 // @function: Send
 // @description: 发送回调 - 保持兼容性，内部使用并行发送
-func squad(body map[string]interface{}) {
+func (inst *Instance) squad(body map[string]interface{}) {
 	// 使用并行发送提升性能
-	parallelSquadSend(body)
+	inst.parallelSquadSend(body)
 }
 
 // SaveChat
 // @author: [Fantasia](https://www.npc0.com)
 // @function: SaveChat
 // @description: 回写聊天信息
-func SaveChat(text string) {
+func (inst *Instance) SaveChat(text string) {
 	// init
 	var err error
 	var byteBody []byte
@@ -638,7 +643,7 @@ func SaveChat(text string) {
 	}}
 	if byteBody, err = json.Marshal(&text); err == nil {
 		if req, err = http.NewRequest("POST", fmt.Sprintf(
-			"%s/api/v1/recycling", global.ScumConfig.ServerUrl), bytes.NewReader(byteBody)); err == nil {
+			"%s/api/v1/recycling", inst.profile.ServerUrl), bytes.NewReader(byteBody)); err == nil {
 			req.Header.Set("Content-Type", "application/json")
 			if resp, err = httpClient.Do(req); err == nil {
 				_, _ = io.ReadAll(resp.Body)
@@ -650,38 +655,74 @@ func SaveChat(text string) {
 // Send
 // @author: [Fantasia](https://www.npc0.com)
 // @function: Send
-// @description: 发送命令 - 高速优化版本
-func Send(hand syscall.Handle, text string) (out string, err error) {
+// @description: 发送命令 - 高速优化版本，返回游戏复制到剪贴板的输出（列表类指令），其他指令为空（不读取游戏日志和聊天记录）
+func (inst *Instance) Send(hand syscall.Handle, text string) (string, error) {
+	resp, err := inst.sendCommand(hand, text, false)
+	if resp.Source != _const.ResponseSourceClipboard {
+		return "", err
+	}
+	return resp.Output, err
+}
+
+// SendCommand
+// @function: SendCommand
+// @description: 发送命令并获取执行结果：列表类指令读取剪贴板，其他指令按 response_capture 读取游戏日志或识别聊天记录
+// @param: hand syscall.Handle 窗口句柄
+// @param: text string 指令
+// @return: CommandResponse 执行结果（success/error/no_output）, error 指令未能发送
+func (inst *Instance) SendCommand(hand syscall.Handle, text string) (CommandResponse, error) {
+	return inst.sendCommand(hand, text, true)
+}
+
+// sendCommand 发送命令，capture 为 false 时只读取剪贴板输出，其他指令直接返回 no_output
+func (inst *Instance) sendCommand(hand syscall.Handle, text string, capture bool) (resp CommandResponse, err error) {
+	var out string
 	startTime := time.Now()
-	logInfo("开始发送指令: %s", text)
+	inst.logInfo("开始发送指令: %s", text)
 
 	// 验证输入参数
+	resp = CommandResponse{Command: text, Outcome: _const.CommandOutcomeNoOutput}
 	if strings.Contains(text, "502 Bad Gateway") {
-		logDebug("检测到502错误，跳过处理")
-		return "", nil
-	}
-
-	// 设置窗口为前台并确保聊天框激活
-	if !ensureChatBoxActive(hand) {
-		logError("无法激活聊天框")
-		return "", errors.New("无法激活聊天框")
+		inst.logDebug("检测到502错误，跳过处理")
+		return resp, nil
 	}
 
 	// 使用预处理的指令，减少正则匹配时间
-	commandToSend := preProcessCommand(text)
+	commandToSend := inst.preProcessCommand(text)
+	resp.Command = commandToSend
 
 	// 验证指令长度
 	if len(commandToSend) > 200 {
-		logError("指令长度超限: %d", len(commandToSend))
-		return "", errors.New("指令长度超限")
+		inst.logError("指令长度超限: %d", len(commandToSend))
+		return resp, errors.New("指令长度超限")
+	}
+
+	// 从激活聊天框到读取响应（只读取游戏日志时到发送完成）独占剪贴板和键盘，其他指令（包括其他实例的指令）排队等待
+	// 返回前恢复用户的剪贴板；结果以字符串返回，SaveChat/parallelSquadSend 的后台发送不再访问剪贴板
+	session := util.BeginInputSession(inst.name)
+	defer session.End()
+	if multiInstance() {
+		util.SetForegroundWindow(hand)
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	// 确保聊天框激活
+	if !inst.ensureChatBoxActive(hand) {
+		inst.logError("无法激活聊天框")
+		return resp, errors.New("无法激活聊天框")
 	}
 
 	// 第一步：快速清空剪贴板（减少等待时间）
-	_ = clipboard.WriteAll("")
+	session.ClearClipboard()
 	time.Sleep(30 * time.Millisecond) // 从原来的多次验证改为快速操作
 
 	// 第二步：快速清空输入框（优化时序）
-	robotgo.MoveClick(82, 319, "", false)
+	inputX, inputY, err := util.LayoutScreenPoint(hand, _const.AnchorChatInput)
+	if err != nil {
+		inst.logError("定位聊天输入框失败: %v", err)
+		return resp, err
+	}
+	robotgo.MoveClick(inputX, inputY, "", false)
 	time.Sleep(80 * time.Millisecond) // 从150ms减少到80ms
 	_ = util.KeyTapToWindow(hand, _const.VK_A, _const.VK_CONTROL)
 	time.Sleep(30 * time.Millisecond) // 从50ms减少到30ms
@@ -689,33 +730,40 @@ func Send(hand syscall.Handle, text string) (out string, err error) {
 	time.Sleep(80 * time.Millisecond) // 从150ms减少到80ms
 
 	// 第三步：快速写入指令到剪贴板
-	if err = fastClipboardOperation(commandToSend); err != nil {
-		logError("快速剪贴板写入失败，回退到标准方式: %v", err)
+	if err = fastClipboardOperation(session, commandToSend); err != nil {
+		inst.logError("快速剪贴板写入失败，回退到标准方式: %v", err)
 		// 回退到标准方式
-		if err = writeToClipboard(commandToSend); err != nil {
-			logError("写入剪贴板失败: %v", err)
-			return "", err
+		if err = writeToClipboard(session, commandToSend); err != nil {
+			inst.logError("写入剪贴板失败: %v", err)
+			return resp, err
 		}
 	}
 
+	// 记录游戏日志位置和聊天记录，发送后只读取新增的输出
+	_, needsResponse := updateClipboard[commandToSend]
+	var mark responseMark
+	if capture && !needsResponse {
+		mark = inst.markResponse(hand)
+	}
+
 	// 第四步：快速粘贴并发送指令
 	_ = util.KeyTapToWindow(hand, _const.VK_V, _const.VK_CONTROL)
 	time.Sleep(120 * time.Millisecond) // 从200ms减少到120ms
 	_ = util.KeyTapToWindow(hand, _const.VK_RETURN)
 
-	logInfo("指令已发送: %s", commandToSend)
+	inst.logInfo("指令已发送: %s", commandToSend)
 
 	// 第五步：对于需要返回结果的指令，智能等待响应
-	if _, needsResponse := updateClipboard[commandToSend]; needsResponse {
-		logInfo("等待指令响应: %s", commandToSend)
+	if needsResponse {
+		inst.logInfo("等待指令响应: %s", commandToSend)
 
 		// 立即清空剪贴板，准备接收游戏返回的结果
-		_ = clipboard.WriteAll("")
+		session.ClearClipboard()
 		time.Sleep(20 * time.Millisecond) // 减少等待时间
 
 		// 使用智能等待时间
-		waitTime := getOptimalWaitTime(commandToSend)
-		logDebug("智能等待响应时间: %v", waitTime)
+		waitTime := inst.getOptimalWaitTime(commandToSend)
+		inst.logDebug("智能等待响应时间: %v", waitTime)
 
 		// 分段等待，提前检查响应
 		waitSteps := 4
@@ -725,22 +773,22 @@ func Send(hand syscall.Handle, text string) (out string, err error) {
 			time.Sleep(stepTime)
 
 			// 每个步骤都检查一次响应
-			if out, err = clipboard.ReadAll(); err == nil && out != "" && out != commandToSend {
+			if out, err = session.ReadClipboard(); err == nil && out != "" && out != commandToSend {
 				responseTime := time.Since(startTime)
-				logInfo("快速获取响应 (步骤%d/%d)，长度: %d，耗时: %v", step+1, waitSteps, len(out), responseTime)
-				updateCommandStats(commandToSend, responseTime, true)
-				return out, nil
+				inst.logInfo("快速获取响应 (步骤%d/%d)，长度: %d，耗时: %v", step+1, waitSteps, len(out), responseTime)
+				inst.updateCommandStats(commandToSend, responseTime, true)
+				return clipboardResponse(commandToSend, out), nil
 			}
 		}
 
 		// 如果分段等待没有结果，进行快速重试
 		maxAttempts := 3 // 从5次减少到3次
 		for i := 0; i < maxAttempts; i++ {
-			if out, err = clipboard.ReadAll(); err == nil && out != "" && out != commandToSend {
+			if out, err = session.ReadClipboard(); err == nil && out != "" && out != commandToSend {
 				responseTime := time.Since(startTime)
-				logInfo("重试获取响应成功，长度: %d，耗时: %v", len(out), responseTime)
-				updateCommandStats(commandToSend, responseTime, true)
-				return out, nil
+				inst.logInfo("重试获取响应成功，长度: %d，耗时: %v", len(out), responseTime)
+				inst.updateCommandStats(commandToSend, responseTime, true)
+				return clipboardResponse(commandToSend, out), nil
 			}
 
 			// 减少重试间隔
@@ -751,109 +799,129 @@ func Send(hand syscall.Handle, text string) (out string, err error) {
 
 		// 快速检查聊天框状态
 		if isChatInterfaceOpen(hand) == "" {
-			logError("聊天框丢失")
-			updateCommandStats(commandToSend, time.Since(startTime), false)
-			return "", errors.New("聊天框状态异常")
+			inst.logError("聊天框丢失")
+			inst.updateCommandStats(commandToSend, time.Since(startTime), false)
+			return resp, errors.New("聊天框状态异常")
 		}
 
 		// 最后一次快速尝试
-		if out, err = clipboard.ReadAll(); err == nil && out != "" && out != commandToSend {
+		if out, err = session.ReadClipboard(); err == nil && out != "" && out != commandToSend {
 			responseTime := time.Since(startTime)
-			logInfo("最终获取到响应，长度: %d，耗时: %v", len(out), responseTime)
-			updateCommandStats(commandToSend, responseTime, true)
-			return out, nil
+			inst.logInfo("最终获取到响应，长度: %d，耗时: %v", len(out), responseTime)
+			inst.updateCommandStats(commandToSend, responseTime, true)
+			return clipboardResponse(commandToSend, out), nil
 		}
 
-		logError("未获取到有效响应，剪贴板内容: %s", out)
-		updateCommandStats(commandToSend, time.Since(startTime), false)
-	} else {
-		// 对于不需要响应的指令，记录执行时间
-		updateCommandStats(commandToSend, time.Since(startTime), true)
+		inst.logError("未获取到有效响应，剪贴板内容: %s", out)
+		inst.updateCommandStats(commandToSend, time.Since(startTime), false)
+		return resp, nil
+	}
+
+	// 其他指令从游戏日志或聊天记录读取输出；只读取游戏日志时不需要键盘和剪贴板，先结束输入会话，让排队的指令先执行
+	if !mark.useOCR {
+		session.End()
 	}
+	resp = inst.captureResponse(hand, commandToSend, mark)
+	switch resp.Outcome {
+	case _const.CommandOutcomeError:
+		inst.logError("指令返回错误 (%s): %s", resp.Source, resp.Error)
+	case _const.CommandOutcomeSuccess:
+		inst.logInfo("指令执行成功 (%s): %s", resp.Source, resp.Output)
+	default:
+		inst.logDebug("指令没有输出: %s", commandToSend)
+	}
+	inst.updateCommandStats(commandToSend, time.Since(startTime), resp.Outcome != _const.CommandOutcomeError)
+	return resp, nil
+}
 
-	return out, nil
+// clipboardResponse 剪贴板输出的执行结果
+func clipboardResponse(command, out string) CommandResponse {
+	return CommandResponse{Command: command, Outcome: _const.CommandOutcomeSuccess, Source: _const.ResponseSourceClipboard, Output: out}
 }
 
 // ChatMonitor
 // @author: [Fantasia](https://www.npc0.com)
 // @function: ChatMonitor
 // @description: 聊天监控信息 - 优化版本
-func ChatMonitor(hand syscall.Handle) {
+func (inst *Instance) ChatMonitor(hand syscall.Handle) {
 	// init
 	var i int
 	var err error
 	var out string
 
-	logInfo("开始聊天监控...")
+	inst.logInfo("开始聊天监控...")
 
 	// 初始化传送指令
-	_, _ = Send(hand, "#Teleport 0 0 0")
+	_, _ = inst.Send(hand, "#Teleport 0 0 0")
 
 	for {
+		// 远程暂停或请求重启游戏时退出监控，由主循环处理
+		if inst.control.Interrupted() {
+			inst.logInfo("聊天监控已中断（暂停或重启游戏）")
+			return
+		}
+
 		// 延时
 		time.Sleep(150 * time.Millisecond)
 
 		// 获取并执行服务器指令
-		if command := run(); command != "" {
-			logInfo("收到服务器指令: %s", command)
-			if out, err = Send(hand, command); err != nil {
-				logError("执行指令失败: %v，尝试重试", err)
-				// 重试一次
-				time.Sleep(1 * time.Second)
-				if out, err = Send(hand, command); err != nil {
-					logError("重试失败，退出监控: %v", err)
-					return
-				}
+		if command := inst.run(); command != "" {
+			inst.logInfo("收到服务器指令: %s", command)
+			// 发送失败时重试一次，执行结果上报给后台
+			resp, err := inst.runServerCommand(hand, command, time.Second)
+			if err != nil {
+				inst.logError("重试失败，退出监控: %v", err)
+				return
 			}
-			logInfo("指令执行完成")
+			inst.logInfo("指令执行完成: %s", resp.Outcome)
 		}
 
 		// 定时获取载具和玩家信息（每15次循环 = 约2.25秒）
 		if i%15 == 0 {
-			logDebug("开始获取载具和玩家信息...")
+			inst.logDebug("开始获取载具和玩家信息...")
 
 			// 获取载具列表
-			if out, err = Send(hand, "#ListSpawnedVehicles true"); err != nil {
-				logError("获取载具列表失败: %v", err)
+			if out, err = inst.Send(hand, "#ListSpawnedVehicles true"); err != nil {
+				inst.logError("获取载具列表失败: %v", err)
 				return
 			} else if out != "" {
-				squad(map[string]interface{}{
-					"id":   global.ScumConfig.ServerID,
+				inst.squad(map[string]interface{}{
+					"id":   inst.profile.ServerID,
 					"mode": "spawned",
 					"info": out,
 				})
-				logDebug("载具信息已发送，数据长度: %d", len(out))
+				inst.logDebug("载具信息已发送，数据长度: %d", len(out))
 			}
 
 			// 获取玩家列表
-			if out, err = Send(hand, "#ListPlayers true"); err != nil {
-				logError("获取玩家列表失败: %v", err)
+			if out, err = inst.Send(hand, "#ListPlayers true"); err != nil {
+				inst.logError("获取玩家列表失败: %v", err)
 				return
 			} else if out != "" {
-				squad(map[string]interface{}{
-					"id":   global.ScumConfig.ServerID,
+				inst.squad(map[string]interface{}{
+					"id":   inst.profile.ServerID,
 					"mode": "user",
 					"info": out,
 				})
-				logDebug("玩家信息已发送，数据长度: %d", len(out))
+				inst.logDebug("玩家信息已发送，数据长度: %d", len(out))
 			}
 		}
 
 		// 定时获取领地和队伍信息（每150次循环 = 约22.5秒）
 		if i%150 == 0 && i > 0 {
-			logDebug("开始获取领地和队伍信息...")
+			inst.logDebug("开始获取领地和队伍信息...")
 
 			// 获取领地信息
 			var flagNum = 1
 			for {
 				flagCommand := fmt.Sprintf("#listflags %d true", flagNum)
-				if out, err = Send(hand, flagCommand); err != nil {
-					logError("获取领地信息失败 %s: %v", flagCommand, err)
+				if out, err = inst.Send(hand, flagCommand); err != nil {
+					inst.logError("获取领地信息失败 %s: %v", flagCommand, err)
 					return
 				} else {
 					// 发送领地信息
-					squad(map[string]interface{}{
-						"id":   global.ScumConfig.ServerID,
+					inst.squad(map[string]interface{}{
+						"id":   inst.profile.ServerID,
 						"mode": "flags",
 						"info": out,
 					})
@@ -861,14 +929,14 @@ func ChatMonitor(hand syscall.Handle) {
 					// 判断是否为最后一页
 					if lenList := flagsRegexp.FindAllStringSubmatch(out, 1); len(lenList) > 0 {
 						if lenList[0][1] == lenList[0][2] {
-							logDebug("领地信息获取完成，共%s页", lenList[0][2])
+							inst.logDebug("领地信息获取完成，共%s页", lenList[0][2])
 							break
 						}
 					}
 
 					// 检查输出长度，如果太短可能是错误
 					if len(out) < 10 {
-						logError("领地信息输出异常: %s", out)
+						inst.logError("领地信息输出异常: %s", out)
 						break
 					}
 
@@ -879,16 +947,16 @@ func ChatMonitor(hand syscall.Handle) {
 			}
 
 			// 获取队伍信息
-			if out, err = Send(hand, "#dumpallsquadsinfolist"); err != nil {
-				logError("获取队伍信息失败: %v", err)
+			if out, err = inst.Send(hand, "#dumpallsquadsinfolist"); err != nil {
+				inst.logError("获取队伍信息失败: %v", err)
 				return
 			} else if out != "" {
-				squad(map[string]interface{}{
-					"id":   global.ScumConfig.ServerID,
+				inst.squad(map[string]interface{}{
+					"id":   inst.profile.ServerID,
 					"mode": "all_group",
 					"info": out,
 				})
-				logDebug("队伍信息已发送，数据长度: %d", len(out))
+				inst.logDebug("队伍信息已发送，数据长度: %d", len(out))
 			}
 		}
 
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"qq_client/global"
+	"qq_client/internal/control"
+	"qq_client/internal/status"
+	"qq_client/util"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Instance 一个游戏实例：后端服务器身份、游戏窗口和主循环状态
+// 多实例时每个实例运行自己的主循环，物理输入（前台窗口、鼠标、剪贴板）由 input 调度
+type Instance struct {
+	name    string // 实例名称，单实例时为空
+	profile *global.ServerProfile
+	status  *status.Tracker
+	control *control.Controls
+	pid     uint32 // 最近一次找到的游戏窗口所属进程，未找到时为 0
+
+	// windowPID 按进程号匹配游戏窗口（初始为配置的 window_pid），游戏重启后重新绑定到新进程；由 windowMutex 保护
+	windowPID int32
+
+	// 错误计算
+	errorNumber  int
+	errorNumber2 int
+
+	// 窗口位置缓存
+	lastWindowX, lastWindowY          int
+	lastWindowWidth, lastWindowHeight int
+
+	// 聊天框状态追踪
+	lastChatState        string
+	chatStateStableCount int
+
+	// 待处理指令队列状态
+	hasPendingCommands bool
+
+	// 配置替换标记
+	configReplaced bool
+
+	// 定时指令状态追踪
+	lastPeriodicCommandTime time.Time
+
+	// 批量指令获取缓存
+	commandBatch         []string
+	lastCommandFetchTime time.Time
+
+	// 执行性能优化相关
+	commandStats         map[string]*CommandStats
+	lastResponseTimes    map[string]time.Duration
+	preProcessedCommands map[string]string
+}
+
+// instanceCount 运行中的实例数
+var instanceCount int
+
+// 实例与游戏窗口的绑定：windowMutex 保护 instances 中每个实例的 windowPID 和 boundPIDs
+var (
+	windowMutex sync.Mutex
+	instances   []*Instance
+	boundPIDs   = make(map[uint32]*Instance) // 游戏进程号 -> 最近在该进程中找到窗口的实例
+)
+
+// NewInstance 创建游戏实例
+// @param: profile *global.ServerProfile 实例配置（与该实例的 WebSocket 客户端共用，认证后更新 FtpProvider）
+// @param: tracker *status.Tracker 实例状态
+// @param: controls *control.Controls 实例的远程控制
+// @return: *Instance
+func NewInstance(profile *global.ServerProfile, tracker *status.Tracker, controls *control.Controls) *Instance {
+	return &Instance{
+		name:                    profile.Name,
+		profile:                 profile,
+		status:                  tracker,
+		control:                 controls,
+		windowPID:               profile.WindowPID,
+		lastWindowX:             -1,
+		lastWindowY:             -1,
+		lastWindowWidth:         -1,
+		lastWindowHeight:        -1,
+		lastChatState:           "UNKNOWN",
+		lastPeriodicCommandTime: time.Now(),
+		commandStats:            make(map[string]*CommandStats),
+		lastResponseTimes:       make(map[string]time.Duration),
+		preProcessedCommands:    make(map[string]string),
+	}
+}
+
+// Run 运行所有实例的主循环（阻塞）
+// @param: instances []*Instance
+func Run(all []*Instance) {
+	instanceCount = len(all)
+	windowMutex.Lock()
+	instances = all
+	windowMutex.Unlock()
+	if len(all) > 1 {
+		logInfo("多实例模式：%d 个游戏实例共用键盘、鼠标和剪贴板", len(all))
+	}
+
+	var wg sync.WaitGroup
+	for _, inst := range all {
+		wg.Add(1)
+		go func(inst *Instance) {
+			defer wg.Done()
+			// 循环机器人主逻辑
+			for {
+				inst.Start()
+			}
+		}(inst)
+	}
+	wg.Wait()
+}
+
+// findGameWindow 查找实例的游戏窗口
+// @description: 按进程号或标题匹配；按进程号没有找到时（游戏重启后进程号变化，或配置的 window_pid 已失效）
+// 认领一个没有被其他实例使用的游戏窗口，并把实例绑定到该窗口的进程
+// @return: syscall.Handle 未找到时为 0
+func (inst *Instance) findGameWindow() syscall.Handle {
+	windowMutex.Lock()
+	pid := inst.windowPID
+	windowMutex.Unlock()
+	if hand := util.FindGameWindow(pid, inst.profile.WindowTitle); hand != 0 || pid == 0 {
+		return hand
+	}
+	return inst.claimGameWindow()
+}
+
+// claimGameWindow 认领没有被其他实例使用的游戏窗口：不属于其他实例绑定或配置的进程，标题也不是其他按标题匹配的实例的窗口标题
+func (inst *Instance) claimGameWindow() syscall.Handle {
+	windowMutex.Lock()
+	defer windowMutex.Unlock()
+	for _, hand := range util.FindWindowsByClass("UnrealWindow") {
+		_, pid := util.GetWindowThreadProcessId(hand)
+		if owner, ok := boundPIDs[pid]; ok && owner != inst {
+			continue
+		}
+		title := util.GetWindowTitle(hand)
+		claimed := false
+		for _, other := range instances {
+			if other == inst {
+				continue
+			}
+			if other.windowPID == int32(pid) || (other.windowPID == 0 && other.profile.WindowTitle == title) {
+				claimed = true
+				break
+			}
+		}
+		if claimed {
+			continue
+		}
+		inst.logInfo("游戏进程 %d 的窗口未找到，绑定到游戏进程 %d", inst.windowPID, pid)
+		inst.windowPID = int32(pid)
+		boundPIDs[pid] = inst
+		return hand
+	}
+	return 0
+}
+
+// bindGameProcess 记录实例的游戏窗口所属进程，pid 为 0 时解除绑定（游戏已结束或窗口未找到）
+func (inst *Instance) bindGameProcess(pid uint32) {
+	windowMutex.Lock()
+	defer windowMutex.Unlock()
+	if inst.pid != 0 && boundPIDs[inst.pid] == inst {
+		delete(boundPIDs, inst.pid)
+	}
+	if pid != 0 {
+		boundPIDs[pid] = inst
+	}
+	inst.pid = pid
+}
+
+// multiInstance 是否同时运行多个实例
+func multiInstance() bool {
+	return instanceCount > 1
+}
+
+// logInfo 实例日志（多实例时以实例名称为前缀）
+func (inst *Instance) logInfo(format string, v ...interface{}) {
+	logInfo(inst.logPrefix()+format, v...)
+}
+
+// logError 实例日志（多实例时以实例名称为前缀）
+func (inst *Instance) logError(format string, v ...interface{}) {
+	logError(inst.logPrefix()+format, v...)
+}
+
+// logDebug 实例日志（多实例时以实例名称为前缀）
+func (inst *Instance) logDebug(format string, v ...interface{}) {
+	logDebug(inst.logPrefix()+format, v...)
+}
+
+// logPrefix 日志前缀
+func (inst *Instance) logPrefix() string {
+	if inst.name == "" {
+		return ""
+	}
+	return "[" + inst.name + "] "
+}
@@ -0,0 +1,315 @@
+package server
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"qq_client/global"
+	_const "qq_client/internal/const"
+	"qq_client/util"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// responseErrorRegexp 游戏输出中的错误文本：按单词匹配（不区分大小写），避免玩家名、物品名中的片段被当作错误
+var responseErrorRegexp = regexp.MustCompile(`(?i)\b(?:error|invalid|not found|unknown command|failed|cannot|can't|not allowed)\b|(?i)\busage:`)
+
+// gameLogLineRegexp 虚幻引擎日志行：[时间][帧号]分类: [级别: ]内容
+var gameLogLineRegexp = regexp.MustCompile(`^\[\d{4}\.\d{2}\.\d{2}-\d{2}\.\d{2}\.\d{2}:\d{3}\]\[\s*\d+\]([A-Za-z0-9_]+):\s+(?:(Fatal|Error|Warning|Display|Log|Verbose|VeryVerbose):\s+)?(.*)$`)
+
+// gameLogLine 解析后的游戏日志行
+type gameLogLine struct {
+	category  string
+	verbosity string // 没有级别时为空
+	message   string
+}
+
+// parseGameLogLine 解析游戏日志行，不是虚幻引擎日志格式时返回 false
+func parseGameLogLine(line string) (gameLogLine, bool) {
+	match := gameLogLineRegexp.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return gameLogLine{}, false
+	}
+	return gameLogLine{category: match[1], verbosity: match[2], message: strings.TrimSpace(match[3])}, true
+}
+
+// CommandResponse 指令的执行结果
+type CommandResponse struct {
+	Command string // 实际发送的指令
+	Outcome string // success/error/no_output
+	Source  string // 结果来源 clipboard/game_log/chat_ocr，没有输出时为空（游戏日志中只有回显时为 game_log）
+	Output  string // 游戏输出（多行以换行分隔）
+	Error   string // 游戏返回的错误文本（Outcome 为 error 时）
+}
+
+// runServerCommand 执行后台下发的指令：发送失败时等待 retryDelay 后重试一次，最终结果上报给后台
+// @return: CommandResponse 执行结果, error 重试后仍未能发送
+func (inst *Instance) runServerCommand(hand syscall.Handle, command string, retryDelay time.Duration) (CommandResponse, error) {
+	resp, err := inst.SendCommand(hand, command)
+	if err != nil {
+		inst.logError("执行指令失败: %v，尝试重试", err)
+		time.Sleep(retryDelay)
+		resp, err = inst.SendCommand(hand, command)
+	}
+	inst.reportCommandResult(resp, err)
+	return resp, err
+}
+
+// reportCommandResult 异步上报指令执行结果（/api/v1/run/result），不阻塞聊天监控
+func (inst *Instance) reportCommandResult(resp CommandResponse, sendErr error) {
+	body := map[string]interface{}{
+		"id":      inst.profile.ServerID,
+		"command": resp.Command,
+		"outcome": resp.Outcome,
+		"source":  resp.Source,
+		"output":  resp.Output,
+		"error":   resp.Error,
+	}
+	if sendErr != nil {
+		body["outcome"] = _const.CommandOutcomeNotSent
+		body["error"] = sendErr.Error()
+	}
+	go func() {
+		var err error
+		var byteBody []byte
+		var req *http.Request
+		var httpResp *http.Response
+		httpClient := &http.Client{Timeout: 5 * time.Second, Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		}}
+		if byteBody, err = json.Marshal(&body); err == nil {
+			if req, err = http.NewRequest("POST", fmt.Sprintf(
+				"%s/api/v1/run/result", inst.profile.ServerUrl), bytes.NewReader(byteBody)); err == nil {
+				req.Header.Set("Content-Type", "application/json")
+				if httpResp, err = httpClient.Do(req); err == nil {
+					_, _ = io.ReadAll(httpResp.Body)
+					httpResp.Body.Close()
+				}
+			}
+		}
+		if err != nil {
+			inst.logDebug("上报指令结果失败: %v", err)
+		}
+	}()
+}
+
+// responseMark 发送指令前记录的位置，发送后只读取之后新增的输出
+type responseMark struct {
+	useLog   bool
+	useOCR   bool
+	logPath  string
+	logSize  int64
+	chatBase []string // 发送前聊天记录区域的文字
+}
+
+// responseCaptureMode 生效的响应获取方式
+func responseCaptureMode() string {
+	return strings.ToLower(strings.TrimSpace(global.ScumConfig.ResponseCapture))
+}
+
+// markResponse 发送指令前记录游戏日志大小和聊天记录（在输入会话内调用，聊天框已打开）
+func (inst *Instance) markResponse(hand syscall.Handle) responseMark {
+	mode := responseCaptureMode()
+	if mode == _const.ResponseCaptureOff {
+		return responseMark{}
+	}
+
+	var mark responseMark
+	if mode != _const.ResponseCaptureOCR {
+		mark.logPath = util.GameLogPath(inst.profile.GameLogPath)
+		if size, err := util.GameLogSize(mark.logPath); err == nil {
+			mark.useLog = true
+			mark.logSize = size
+		} else if mode == _const.ResponseCaptureLog || mode == _const.ResponseCaptureBoth {
+			inst.logError("游戏日志不可读 %s: %v", mark.logPath, err)
+		}
+	}
+	// 自动模式下日志不可读时识别聊天记录
+	if mode == _const.ResponseCaptureOCR || mode == _const.ResponseCaptureBoth || (mode == _const.ResponseCaptureAuto && !mark.useLog) {
+		if lines, err := util.ReadChatLines(hand); err == nil {
+			mark.useOCR = true
+			mark.chatBase = lines
+		} else {
+			inst.logError("识别聊天记录失败: %v", err)
+		}
+	}
+	return mark
+}
+
+// captureResponse 等待并读取指令的输出，返回执行结果
+// @description: 游戏日志按 ResponseLogPollInterval 读取新增行，聊天记录按 ResponseOCRPollInterval 识别新增行；
+// 两者都启用时以先读到输出的为准，ResponseCaptureTimeout 内都没有输出时为 no_output
+func (inst *Instance) captureResponse(hand syscall.Handle, command string, mark responseMark) CommandResponse {
+	resp := CommandResponse{Command: command, Outcome: _const.CommandOutcomeNoOutput}
+	if !mark.useLog && !mark.useOCR {
+		return resp
+	}
+
+	deadline := time.Now().Add(_const.ResponseCaptureTimeout)
+	logOffset := mark.logSize
+	categories := gameLogCategories()
+	var logLines []string
+	var related []gameLogLine
+	var nextOCR time.Time
+	for {
+		if mark.useLog {
+			lines, next, err := util.ReadGameLogSince(mark.logPath, logOffset)
+			if err != nil {
+				inst.logError("读取游戏日志失败: %v", err)
+				mark.useLog = false
+			}
+			logOffset = next
+			logLines = append(logLines, lines...)
+			// 输出可能分多次写入日志，一次轮询没有新增输出后才返回
+			previous := len(related)
+			related = commandLogLines(command, logLines, categories)
+			if len(related) > 1 && len(related) == previous {
+				return classifyLogResponse(command, related)
+			}
+		}
+		if mark.useOCR && !time.Now().Before(nextOCR) {
+			nextOCR = time.Now().Add(_const.ResponseOCRPollInterval)
+			if lines, err := util.ReadChatLines(hand); err != nil {
+				inst.logDebug("识别聊天记录失败: %v", err)
+			} else if lines = newChatLines(command, mark.chatBase, lines); len(lines) > 0 {
+				return classifyResponse(command, _const.ResponseSourceChatOCR, lines)
+			}
+		}
+		if !time.Now().Before(deadline) || (!mark.useLog && !mark.useOCR) {
+			switch {
+			case len(related) > 1:
+				return classifyLogResponse(command, related)
+			case len(related) == 1:
+				// 只有回显：指令已执行但没有输出
+				resp.Source = _const.ResponseSourceGameLog
+			}
+			return resp
+		}
+		time.Sleep(_const.ResponseLogPollInterval)
+	}
+}
+
+// gameLogCategories 管理员指令所在的日志分类
+func gameLogCategories() map[string]bool {
+	categories := make(map[string]bool)
+	for _, category := range global.ScumConfig.GameLogCategories {
+		if category = strings.TrimSpace(category); category != "" {
+			categories[category] = true
+		}
+	}
+	if len(categories) == 0 {
+		categories[_const.GameLogDefaultCategory] = true
+	}
+	return categories
+}
+
+// commandLogLines 游戏日志新增行中指令的回显和输出
+// @description: 只看管理员指令分类的行；从回显指令名称的行开始，到下一条回显其他指令的行为止；
+// 没有回显时返回空（输出还没有写入日志，或日志中的是其他指令的输出）
+// @return: []gameLogLine 第一行为回显
+func commandLogLines(command string, lines []string, categories map[string]bool) []gameLogLine {
+	name := commandName(command)
+	if name == "" {
+		return nil
+	}
+	nameRegexp := regexp.MustCompile(`(?i)(?:^|[\s'"#])` + regexp.QuoteMeta(name) + `\b`)
+
+	related := make([]gameLogLine, 0)
+	for _, raw := range lines {
+		line, ok := parseGameLogLine(raw)
+		if !ok || !categories[line.category] {
+			continue
+		}
+		if len(related) == 0 {
+			if nameRegexp.MatchString(line.message) {
+				related = append(related, line)
+			}
+			continue
+		}
+		// 下一条指令的回显
+		if strings.HasPrefix(line.message, "#") && !nameRegexp.MatchString(line.message) {
+			break
+		}
+		related = append(related, line)
+	}
+	return related
+}
+
+// commandName 指令名称（不含 # 前缀）
+func commandName(command string) string {
+	if fields := strings.Fields(command); len(fields) > 0 {
+		return strings.TrimPrefix(fields[0], "#")
+	}
+	return ""
+}
+
+// classifyLogResponse 根据游戏日志判断执行结果：Error/Fatal/Warning 级别或包含错误文本的行作为错误
+func classifyLogResponse(command string, lines []gameLogLine) CommandResponse {
+	resp := CommandResponse{
+		Command: command,
+		Outcome: _const.CommandOutcomeSuccess,
+		Source:  _const.ResponseSourceGameLog,
+	}
+	output := make([]string, len(lines))
+	for i, line := range lines {
+		output[i] = line.message
+		if resp.Outcome == _const.CommandOutcomeError {
+			continue
+		}
+		if line.verbosity == "Error" || line.verbosity == "Fatal" || line.verbosity == "Warning" || isErrorText(line.message) {
+			resp.Outcome = _const.CommandOutcomeError
+			resp.Error = line.message
+		}
+	}
+	resp.Output = strings.Join(output, "\n")
+	return resp
+}
+
+// newChatLines 聊天记录中发送指令后新增的行（不包括指令本身的回显）
+// @description: 按出现次数比较，发送前已有的行在发送后被顶出区域时不影响结果
+func newChatLines(command string, base, lines []string) []string {
+	seen := make(map[string]int)
+	for _, line := range base {
+		seen[line]++
+	}
+	added := make([]string, 0)
+	for _, line := range lines {
+		if seen[line] > 0 {
+			seen[line]--
+			continue
+		}
+		if strings.Contains(line, command) {
+			continue
+		}
+		added = append(added, line)
+	}
+	return added
+}
+
+// classifyResponse 根据聊天记录判断执行结果：包含错误文本的行作为错误，否则为成功
+func classifyResponse(command, source string, lines []string) CommandResponse {
+	resp := CommandResponse{
+		Command: command,
+		Outcome: _const.CommandOutcomeSuccess,
+		Source:  source,
+		Output:  strings.Join(lines, "\n"),
+	}
+	for _, line := range lines {
+		if isErrorText(line) {
+			resp.Outcome = _const.CommandOutcomeError
+			resp.Error = line
+			break
+		}
+	}
+	return resp
+}
+
+// isErrorText 文本是否包含错误标记
+func isErrorText(text string) bool {
+	return responseErrorRegexp.MatchString(text)
+}
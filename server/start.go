@@ -1,46 +1,41 @@
 package server
 
 import (
+	"errors"
 	"os/exec"
-	"qq_client/global"
 	_const "qq_client/internal/const"
+	"qq_client/internal/status"
+	"qq_client/model/request"
 	"qq_client/util"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 )
 
-// 错误计算
-var errorNumber int
-var errorNumber2 int
-
-// 窗口位置缓存
-var lastWindowX, lastWindowY int = -1, -1
-var lastWindowWidth, lastWindowHeight int = -1, -1
-
-// 添加聊天框状态追踪
-var lastChatState = "UNKNOWN"
-var chatStateStableCount = 0
-
-// 添加待处理指令队列状态
-var hasPendingCommands = false
-
-// 添加配置替换标记
-var configReplaced = false
-
 // setWindowPositionOnce 只在必要时设置窗口位置
-func setWindowPositionOnce(hand syscall.Handle) {
+func (inst *Instance) setWindowPositionOnce(hand syscall.Handle) {
+	// 实例配置了窗口位置槽位时使用槽位，否则使用当前布局的窗口位置；都没有时不调整窗口
+	window := inst.profile.Window
+	if window == nil {
+		window = util.GetLayoutWindow()
+	}
+	if window == nil {
+		return
+	}
+
 	// 如果位置已经正确，跳过设置
-	if lastWindowX == global.GameWindowX && lastWindowY == global.GameWindowY &&
-		lastWindowWidth == global.GameWindowWidth && lastWindowHeight == global.GameWindowHeight {
+	if inst.lastWindowX == window.X && inst.lastWindowY == window.Y &&
+		inst.lastWindowWidth == window.Width && inst.lastWindowHeight == window.Height {
 		return
 	}
 
-	logInfo("设置窗口位置和大小...")
-	util.MoveWindow(hand, global.GameWindowX, global.GameWindowY, global.GameWindowWidth, global.GameWindowHeight)
+	inst.logInfo("设置窗口位置和大小...")
+	util.MoveWindow(hand, window.X, window.Y, window.Width, window.Height)
 
 	// 更新缓存
-	lastWindowX, lastWindowY = global.GameWindowX, global.GameWindowY
-	lastWindowWidth, lastWindowHeight = global.GameWindowWidth, global.GameWindowHeight
+	inst.lastWindowX, inst.lastWindowY = window.X, window.Y
+	inst.lastWindowWidth, inst.lastWindowHeight = window.Width, window.Height
 
 	// 等待窗口稳定
 	time.Sleep(500 * time.Millisecond)
@@ -50,53 +45,102 @@ func setWindowPositionOnce(hand syscall.Handle) {
 // @author: [Fantasia](https://www.npc0.com)
 // @function: ErrorReboot
 // @description: 错误重启
-func ErrorReboot() {
-	// 判断错误次数
-	if errorNumber > 15 || errorNumber2 > 100 {
+func (inst *Instance) ErrorReboot() {
+	// 判断错误次数，或服务端远程请求重启
+	forced := inst.control.GameRestartRequested()
+	if forced || inst.errorNumber > 15 || inst.errorNumber2 > 100 {
 		// 错误次数大于15，重启游戏
-		logError("错误次数过多 (errorNumber: %d, errorNumber2: %d)，重启游戏", errorNumber, errorNumber2)
-		cmd := exec.Command("taskkill", "/IM", "SCUM.exe", "/F")
-		_ = cmd.Run()
+		if forced {
+			inst.logInfo("收到远程重启请求，重启游戏")
+		} else {
+			inst.logError("错误次数过多 (errorNumber: %d, errorNumber2: %d)，重启游戏", inst.errorNumber, inst.errorNumber2)
+		}
+		err := inst.killGame()
+		inst.bindGameProcess(0)
+		inst.status.AddRestart(status.RestartGame)
 		// 重置错误计数器
-		errorNumber = 0
-		errorNumber2 = 0
+		inst.errorNumber = 0
+		inst.errorNumber2 = 0
 		// 重置窗口位置缓存
-		lastWindowX, lastWindowY = -1, -1
-		lastWindowWidth, lastWindowHeight = -1, -1
+		inst.lastWindowX, inst.lastWindowY = -1, -1
+		inst.lastWindowWidth, inst.lastWindowHeight = -1, -1
 		// 重置聊天状态
-		lastChatState = "UNKNOWN"
-		chatStateStableCount = 0
+		inst.lastChatState = "UNKNOWN"
+		inst.chatStateStableCount = 0
 		// 重置指令队列状态
-		hasPendingCommands = false
+		inst.hasPendingCommands = false
 		// 重置配置替换标记
-		configReplaced = false
-		// 清空文本位置缓存
-		util.ClearTextPositionCache()
+		inst.configReplaced = false
+		// 文本位置缓存不再清空，窗口大小或游戏版本变化时由 SyncTextPositionCache 自动失效
+		inst.control.CompleteGameRestart(err)
+	}
+}
+
+// killGame 结束游戏进程：已知窗口所属进程时只结束该进程，单实例时未知进程按进程名结束
+func (inst *Instance) killGame() error {
+	switch {
+	case inst.pid != 0:
+		return exec.Command("taskkill", "/PID", strconv.FormatUint(uint64(inst.pid), 10), "/F").Run()
+	case !multiInstance():
+		return exec.Command("taskkill", "/IM", "SCUM.exe", "/F").Run()
+	default:
+		// 多实例时按进程名结束会影响其他实例
+		return errors.New("游戏窗口未找到，无法确定游戏进程")
 	}
 }
 
 // 检查是否有待处理的服务器指令
-func checkPendingCommands() bool {
-	commands := getAllPendingCommands()
+func (inst *Instance) checkPendingCommands() bool {
+	commands := inst.getAllPendingCommands()
+	inst.status.SetPendingCommands(len(commands))
 	return len(commands) > 0
 }
 
+// reportGameState 上报游戏状态，GAME_<模式> 拆分为聊天模式（GAME_MAIN 表示聊天框未打开）
+func (inst *Instance) reportGameState(state string) {
+	chatMode := ""
+	if mode, ok := strings.CutPrefix(state, "GAME_"); ok && mode != "MAIN" {
+		chatMode = mode
+	}
+	inst.status.SetGameState(state, chatMode)
+}
+
+// reportGameWindow 上报游戏进程号和窗口客户区位置
+func (inst *Instance) reportGameWindow(hand syscall.Handle) {
+	_, pid := util.GetWindowThreadProcessId(hand)
+	inst.bindGameProcess(pid)
+	var window request.StatusWindow
+	window.Width, window.Height, _ = util.GetClientSize(hand)
+	window.X, window.Y, _ = util.ClientToScreen(hand, 0, 0)
+	inst.status.SetGame(int32(pid), window)
+}
+
 // 检查游戏当前状态
-func checkGameState(hand syscall.Handle) string {
+func (inst *Instance) checkGameState(hand syscall.Handle) string {
+	// 0. OCR 服务不可用时无法判断界面状态，单独上报，不计入游戏错误
+	if !util.IsOCRServiceAvailable() {
+		return "OCR_UNAVAILABLE"
+	}
+
+	// 登录页面和聊天界面的文字在同一帧截图上一次识别
+	texts := util.ValidateTexts(hand, "CONTINUE", "MUTE")
+
 	// 1. 检查是否在登录页面
-	if util.ExtractTextFromSpecifiedAreaAndValidateThreeTimes(hand, "CONTINUE") == nil {
+	if texts["CONTINUE"] == nil {
 		return "LOGIN"
 	}
 
 	// 2. 检查是否在加载界面
-	if util.SpecifiedCoordinateColor(hand, 427, 142) == "ffffff" && util.SpecifiedCoordinateColor(hand, 438, 153) == "ffffff" {
+	if util.LayoutColorMatches(hand, _const.AnchorLoadingProbe1) && util.LayoutColorMatches(hand, _const.AnchorLoadingProbe2) {
 		return "LOADING"
 	}
 
 	// 3. 检查是否在聊天界面
-	if currentMode := isChatInterfaceOpen(hand); currentMode != "" {
+	if texts["MUTE"] == nil {
 		// 进一步检查聊天模式
-		return "GAME_" + currentMode
+		if currentMode := currentChatMode(hand); currentMode != "" {
+			return "GAME_" + currentMode
+		}
 	}
 
 	return "GAME_MAIN"
@@ -106,135 +150,166 @@ func checkGameState(hand syscall.Handle) string {
 // @author: [Fantasia](https://www.npc0.com)
 // @function: 启动服务主逻辑
 // @description: 机器人登录检测主逻辑 - 优化版本
-func Start() {
+func (inst *Instance) Start() {
 	// init
 	var ok bool
 	var err error
 	var hand syscall.Handle
 
-	ErrorReboot()
-	logDebug("检查游戏状态...")
+	inst.ErrorReboot()
+
+	// 聊天监控已被远程暂停：不检测游戏状态、不执行指令（重启游戏请求仍由 ErrorReboot 执行）
+	if inst.control.Paused() {
+		time.Sleep(_const.ControlPauseWaitTime)
+		return
+	}
+	inst.logDebug("检查游戏状态...")
 
 	// 判断是否有scum游戏进程
 	if ok, err = util.CheckIfAppRunning("SCUM"); err != nil || !ok {
 		// 启动游戏
 		cmd := exec.Command("cmd", "/C", "start", "", "steam://rungameid/513710")
-		logInfo("游戏未启动，正在启动游戏...")
+		inst.logInfo("游戏未启动，正在启动游戏...")
+		inst.reportGameState("NOT_RUNNING")
+		inst.bindGameProcess(0)
+		inst.status.SetGame(0, request.StatusWindow{})
+		inst.status.AddRestart(status.RestartGameLaunch)
 		_ = cmd.Start()
-		errorNumber++
+		inst.errorNumber++
 		// 延时30秒等待游戏启动
 		time.Sleep(30 * time.Second)
 		return
 	}
 
 	// 查找窗口句柄
-	if hand = util.FindWindow("UnrealWindow", "SCUM  "); hand == 0 {
+	if hand = inst.findGameWindow(); hand == 0 {
 		cmd := exec.Command("cmd", "/C", "start", "", "steam://rungameid/513710")
-		logError("游戏窗口未找到，重新启动游戏...")
+		inst.logError("游戏窗口未找到，重新启动游戏...")
+		inst.reportGameState("WINDOW_NOT_FOUND")
+		inst.bindGameProcess(0)
+		inst.status.SetGame(0, request.StatusWindow{})
+		inst.status.AddRestart(status.RestartGameLaunch)
 		_ = cmd.Start()
 		// 延时120秒等待游戏完全加载
 		time.Sleep(120 * time.Second)
-		errorNumber++
+		inst.errorNumber++
 		return
 	}
 
-	logDebug("找到游戏窗口，开始状态检测...")
+	inst.logDebug("找到游戏窗口，开始状态检测...")
 
 	// 游戏成功启动后替换配置文件（只执行一次）
-	if !configReplaced {
-		logInfo("检测到游戏成功启动，正在替换SCUM配置文件...")
+	if !inst.configReplaced {
+		inst.logInfo("检测到游戏成功启动，正在替换SCUM配置文件...")
 		if err := util.ReplaceSCUMConfig(); err != nil {
-			logError("替换SCUM配置文件失败: %v", err)
+			inst.logError("替换SCUM配置文件失败: %v", err)
 		} else {
-			logInfo("SCUM配置文件替换完成")
+			inst.logInfo("SCUM配置文件替换完成")
 		}
-		configReplaced = true
+		inst.configReplaced = true
 	}
 
 	// 只在必要时设置游戏窗口大小和位置
-	setWindowPositionOnce(hand)
+	inst.setWindowPositionOnce(hand)
+	inst.reportGameWindow(hand)
+
+	// 校验文本位置缓存（窗口大小、界面语言、游戏版本变化时失效）并按需落盘
+	util.SyncTextPositionCache(hand)
 
 	// 设置游戏窗口置顶 - 已注释：使用句柄操作不需要窗口置顶
-	util.SetForegroundWindow(hand)
+	// 多实例时前台窗口属于持有输入的实例，由 Send 在发送指令前置顶
+	if !multiInstance() {
+		util.SetForegroundWindow(hand)
+	}
 	// time.Sleep(200 * time.Millisecond)
 
 	// 获取当前游戏状态
-	currentState := checkGameState(hand)
-	logDebug("当前游戏状态: %s", currentState)
+	currentState := inst.checkGameState(hand)
+	inst.logDebug("当前游戏状态: %s", currentState)
+	inst.reportGameState(currentState)
 
 	// 检查是否有待处理的指令
-	hasPendingCommands = checkPendingCommands()
+	inst.hasPendingCommands = inst.checkPendingCommands()
 
 	// 根据状态进行相应处理
 	switch {
+	case currentState == "OCR_UNAVAILABLE":
+		inst.logError("OCR 服务不可用，等待监控自动恢复: %s", util.GetOCRUnavailableReason())
+		time.Sleep(_const.OCRUnavailableWaitTime)
+		return
+
 	case currentState == "LOGIN":
-		logInfo("检测到登录界面，验证机器人状态...")
+		inst.logInfo("检测到登录界面，验证机器人状态...")
 		util.SendKeyToWindow(hand, 0x0D)
 		time.Sleep(100 * time.Millisecond)
 		util.SendKeyToWindow(hand, 0x0D)
 
-		if util.SpecifiedCoordinateColor(hand, 97, 142) != "ffffff" {
+		if !util.LayoutColorMatches(hand, _const.AnchorBotMode) {
 			// 没有机器人,切换机器人模式
-			logInfo("未检测到机器人模式，正在切换...")
+			inst.logInfo("未检测到机器人模式，正在切换...")
 			if err = util.KeyTapToWindow(hand, _const.VK_D, _const.VK_CONTROL); err != nil {
-				logError("切换机器人模式失败: %v", err)
-				errorNumber++
+				inst.logError("切换机器人模式失败: %v", err)
+				inst.errorNumber++
 				return
 			}
 			// 延时等待切换完成
 			time.Sleep(1 * time.Second)
-			errorNumber++
+			inst.errorNumber++
 		}
 
 		// 点击登录
-		logInfo("开始登录...")
-		if err = util.ClickTextCenter(hand, "CONTINUE"); err != nil {
-			logError("点击CONTINUE失败: %v", err)
-			errorNumber++
+		inst.logInfo("开始登录...")
+		// 点击需要前台窗口和物理鼠标，持有输入期间执行
+		session := util.BeginInputSession(inst.name)
+		err = util.ClickTextCenter(hand, "CONTINUE")
+		session.End()
+		if err != nil {
+			inst.logError("点击CONTINUE失败: %v", err)
+			inst.errorNumber++
 		}
-		logInfo("点击登录成功...")
+		inst.logInfo("点击登录成功...")
 		time.Sleep(1 * time.Second)
 		return
 
 	case currentState == "LOADING":
 		// 在加载界面，等待
-		logDebug("检测到加载界面，等待加载完成...")
+		inst.logDebug("检测到加载界面，等待加载完成...")
 		time.Sleep(1 * time.Second)
-		errorNumber2++
+		inst.errorNumber2++
 		return
 
 	case currentState == "GAME_MAIN":
 		// 在游戏主界面，检查是否有待处理的指令
-		ChatMonitorWithActivation(hand)
+		inst.ChatMonitorWithActivation(hand)
 		return
 	case currentState == "GAME_GLOBAL":
 		// 已经在GLOBAL模式，可以直接启动监控
-		logInfo("检测到GLOBAL模式，启动聊天监控...")
+		inst.logInfo("检测到GLOBAL模式，启动聊天监控...")
 		// 重置错误计数器
-		errorNumber2 = 0
-		errorNumber = 0
-		ChatMonitor(hand)
+		inst.errorNumber2 = 0
+		inst.errorNumber = 0
+		inst.ChatMonitor(hand)
 		return
 
 	case currentState == "GAME_LOCAL":
 		// 在LOCAL模式，需要切换到GLOBAL
-		logInfo("检测到LOCAL模式，切换聊天模式...")
+		inst.logInfo("检测到LOCAL模式，切换聊天模式...")
 		_ = util.KeyTapToWindow(hand, _const.VK_TAB)
 		time.Sleep(300 * time.Millisecond)
 
 		// 验证是否切换成功
 		if isChatInterfaceOpen(hand) == "GLOBAL" {
-			logInfo("成功切换到GLOBAL模式，启动聊天监控...")
-			errorNumber2 = 0
-			errorNumber = 0
-			ChatMonitor(hand)
+			inst.logInfo("成功切换到GLOBAL模式，启动聊天监控...")
+			inst.errorNumber2 = 0
+			inst.errorNumber = 0
+			inst.ChatMonitor(hand)
 			return
 		}
 		return
 
 	case currentState == "GAME_ADMIN":
 		// 在ADMIN模式，切换到GLOBAL模式
-		logInfo("检测到ADMIN模式，切换到GLOBAL模式...")
+		inst.logInfo("检测到ADMIN模式，切换到GLOBAL模式...")
 		_ = util.KeyTapToWindow(hand, _const.VK_TAB)
 		time.Sleep(150 * time.Millisecond)
 		_ = util.KeyTapToWindow(hand, _const.VK_TAB)
@@ -242,28 +317,28 @@ func Start() {
 
 		// 验证是否切换成功
 		if isChatInterfaceOpen(hand) == "GLOBAL" {
-			logInfo("成功切换到GLOBAL模式，启动聊天监控...")
-			errorNumber2 = 0
-			errorNumber = 0
-			ChatMonitor(hand)
+			inst.logInfo("成功切换到GLOBAL模式，启动聊天监控...")
+			inst.errorNumber2 = 0
+			inst.errorNumber = 0
+			inst.ChatMonitor(hand)
 			return
 		}
 		return
 
 	case currentState == "GAME_UNKNOWN":
 		// 在游戏界面但聊天模式未知，尝试激活聊天
-		logInfo("检测到游戏界面，聊天模式未知，尝试激活聊天功能...")
+		inst.logInfo("检测到游戏界面，聊天模式未知，尝试激活聊天功能...")
 
 		// 检查状态稳定性，避免频繁按T
-		if lastChatState == currentState {
-			chatStateStableCount++
+		if inst.lastChatState == currentState {
+			inst.chatStateStableCount++
 		} else {
-			chatStateStableCount = 0
-			lastChatState = currentState
+			inst.chatStateStableCount = 0
+			inst.lastChatState = currentState
 		}
 
 		// 只有在状态稳定且计数较低时才按T
-		if chatStateStableCount < 3 {
+		if inst.chatStateStableCount < 3 {
 			// 先按ESC确保退出任何菜单
 			_ = util.KeyTapToWindow(hand, _const.VK_ESCAPE)
 			time.Sleep(200 * time.Millisecond)
@@ -272,32 +347,32 @@ func Start() {
 			_ = util.KeyTapToWindow(hand, _const.VK_T)
 			time.Sleep(500 * time.Millisecond)
 		} else {
-			logError("聊天状态持续未知，可能需要手动干预")
-			errorNumber2++
+			inst.logError("聊天状态持续未知，可能需要手动干预")
+			inst.errorNumber2++
 		}
 		return
 
 	default:
 		// 未知状态
-		logError("检测到未知游戏状态: %s", currentState)
+		inst.logError("检测到未知游戏状态: %s", currentState)
 
 		// 检查状态稳定性
-		if lastChatState == currentState {
-			chatStateStableCount++
+		if inst.lastChatState == currentState {
+			inst.chatStateStableCount++
 		} else {
-			chatStateStableCount = 0
-			lastChatState = currentState
+			inst.chatStateStableCount = 0
+			inst.lastChatState = currentState
 		}
 
 		// 只有在连续出现问题时才重新设置窗口位置
-		if chatStateStableCount > 5 {
-			logInfo("状态持续异常，重新设置窗口位置...")
-			lastWindowX, lastWindowY = -1, -1 // 重置缓存，强制重新设置
-			setWindowPositionOnce(hand)
-			chatStateStableCount = 0
+		if inst.chatStateStableCount > 5 {
+			inst.logInfo("状态持续异常，重新设置窗口位置...")
+			inst.lastWindowX, inst.lastWindowY = -1, -1 // 重置缓存，强制重新设置
+			inst.setWindowPositionOnce(hand)
+			inst.chatStateStableCount = 0
 		}
 
-		errorNumber2++
+		inst.errorNumber2++
 		return
 	}
 }
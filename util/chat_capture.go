@@ -0,0 +1,101 @@
+package util
+
+import (
+	"fmt"
+	"qq_client/global"
+	_const "qq_client/internal/const"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// chatLineItem 聊天记录中的一个文本块
+type chatLineItem struct {
+	text         string
+	x1, y1, y2   int
+	centerY      int
+	lineAssigned bool
+}
+
+// ReadChatLines
+// @function: ReadChatLines
+// @description: 识别聊天记录区域（chat_log_top_left ~ chat_log_bottom_right 锚点之间）的文字，按行从上到下返回
+// @param: hand syscall.Handle 窗口句柄
+// @return: []string 区域内没有文字时为空, error
+func ReadChatLines(hand syscall.Handle) ([]string, error) {
+	left, top, err := LayoutPoint(hand, _const.AnchorChatLogTopLeft)
+	if err != nil {
+		return nil, err
+	}
+	right, bottom, err := LayoutPoint(hand, _const.AnchorChatLogBottomRight)
+	if err != nil {
+		return nil, err
+	}
+	if right <= left || bottom <= top {
+		return nil, fmt.Errorf("聊天记录区域无效: [%d,%d,%d,%d]", left, top, right, bottom)
+	}
+
+	imageData, err := CaptureWindowPNG(hand)
+	if err != nil {
+		return nil, fmt.Errorf("截图失败: %v", err)
+	}
+	ctx, cancel := ocrTimeoutFor(OCRPriorityNormal)
+	defer cancel()
+	ocrResult, err := RecognizeOCR(ctx, OCRCall{
+		Stream:   fmt.Sprintf("chat_log:%d", hand),
+		Priority: OCRPriorityNormal,
+		Image:    imageData,
+		Options: global.OCRRequest{
+			Crop: &global.OCRRect{Left: left, Top: top, Right: right, Bottom: bottom},
+			Lang: global.ScumConfig.OCRLanguage,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("OCR请求失败: %v", err)
+	}
+	// 未指定目标文字时识别成功为 100，区域内没有文字为 200（items 为空）
+	if ocrResult.Code != 100 && ocrResult.Code != 200 {
+		return nil, fmt.Errorf("OCR识别失败，code: %d (%s)", ocrResult.Code, ocrResult.Message)
+	}
+
+	items := make([]*chatLineItem, 0)
+	for _, item := range parseOcrItems(ocrResult) {
+		x1, y1, _, y2, ok := ocrItemRect(item)
+		if !ok || strings.TrimSpace(item.Text) == "" {
+			continue
+		}
+		items = append(items, &chatLineItem{text: strings.TrimSpace(item.Text), x1: x1, y1: y1, y2: y2, centerY: (y1 + y2) / 2})
+	}
+	return groupChatLines(items), nil
+}
+
+// groupChatLines 按垂直位置把文本块合并成行：中心点落在同一文本块上下边界内的视为同一行，行内按从左到右拼接
+func groupChatLines(items []*chatLineItem) []string {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].centerY < items[j].centerY
+	})
+
+	lines := make([]string, 0)
+	for i, first := range items {
+		if first.lineAssigned {
+			continue
+		}
+		line := []*chatLineItem{first}
+		first.lineAssigned = true
+		for _, item := range items[i+1:] {
+			if !item.lineAssigned && item.centerY >= first.y1 && item.centerY <= first.y2 {
+				line = append(line, item)
+				item.lineAssigned = true
+			}
+		}
+		sort.Slice(line, func(a, b int) bool {
+			return line[a].x1 < line[b].x1
+		})
+		texts := make([]string, len(line))
+		for k, item := range line {
+			texts[k] = item.text
+		}
+		lines = append(lines, strings.Join(texts, " "))
+	}
+	return lines
+}
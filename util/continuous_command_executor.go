@@ -5,8 +5,6 @@ import (
 	"strings"
 	"syscall"
 	"time"
-
-	"github.com/atotto/clipboard"
 )
 
 // ContinuousCommandExecutor 连续命令执行器
@@ -82,6 +80,13 @@ func (cce *ContinuousCommandExecutor) StartContinuousSession() error {
 
 // AddCommandToContinuousSession 在连续会话中添加命令
 func (cce *ContinuousCommandExecutor) AddCommandToContinuousSession(command string) error {
+	session := BeginInputSession("continuous_executor")
+	defer session.End()
+	return cce.addCommand(session, command)
+}
+
+// addCommand 在持有输入会话时发送命令
+func (cce *ContinuousCommandExecutor) addCommand(session *InputSession, command string) error {
 	if !cce.isChatSessionActive {
 		return fmt.Errorf("连续会话未激活，请先调用 StartContinuousSession()")
 	}
@@ -100,7 +105,7 @@ func (cce *ContinuousCommandExecutor) AddCommandToContinuousSession(command stri
 	fmt.Printf("在连续会话中添加命令: %s\n", processedCommand)
 
 	// 发送命令文本（不重新激活聊天框）
-	if err := cce.inputManager.SendText(processedCommand, cce.defaultInputMethod); err != nil {
+	if err := cce.inputManager.SendText(session, processedCommand, cce.defaultInputMethod); err != nil {
 		return fmt.Errorf("发送命令文本失败: %v", err)
 	}
 
@@ -379,19 +384,23 @@ func (cce *ContinuousCommandExecutor) WaitForCommandResponse(command string, tim
 		return "", fmt.Errorf("连续会话未激活")
 	}
 
+	// 发送命令到读取响应期间独占剪贴板和键盘
+	session := BeginInputSession("continuous_executor")
+	defer session.End()
+
 	// 清空剪贴板准备接收结果
-	clipboard.WriteAll("")
+	session.ClearClipboard()
 	time.Sleep(50 * time.Millisecond)
 
 	// 发送命令
-	if err := cce.AddCommandToContinuousSession(command); err != nil {
+	if err := cce.addCommand(session, command); err != nil {
 		return "", err
 	}
 
 	// 等待结果
 	start := time.Now()
 	for time.Since(start) < timeout {
-		if result, err := clipboard.ReadAll(); err == nil && result != "" && result != command {
+		if result, err := session.ReadClipboard(); err == nil && result != "" && result != command {
 			fmt.Printf("获取到命令响应，长度: %d\n", len(result))
 			return result, nil
 		}
@@ -5,8 +5,6 @@ import (
 	"strings"
 	"syscall"
 	"time"
-
-	"github.com/atotto/clipboard"
 )
 
 // EnhancedCommandExecutor 增强命令执行器
@@ -160,6 +158,10 @@ func (ece *EnhancedCommandExecutor) ExecuteBatch(commands []string) ([]*CommandE
 
 // executeWithMethod 使用指定方法执行命令
 func (ece *EnhancedCommandExecutor) executeWithMethod(command string, inputMethod InputMethod, chatMethod ChatActivationMethod, config *CommandConfig) (string, error) {
+	// 从激活聊天框到读取结果独占剪贴板和键盘
+	session := BeginInputSession("enhanced_executor")
+	defer session.End()
+
 	// 1. 确保窗口激活 - 已注释：使用句柄操作不需要窗口置顶
 	// SetForegroundWindow(ece.hwnd)
 	// time.Sleep(50 * time.Millisecond)
@@ -174,7 +176,7 @@ func (ece *EnhancedCommandExecutor) executeWithMethod(command string, inputMetho
 	}
 
 	// 3. 发送命令文本
-	if err := ece.inputManager.SendText(command, inputMethod); err != nil {
+	if err := ece.inputManager.SendText(session, command, inputMethod); err != nil {
 		return "", fmt.Errorf("发送命令文本失败: %v", err)
 	}
 
@@ -188,7 +190,7 @@ func (ece *EnhancedCommandExecutor) executeWithMethod(command string, inputMetho
 	// 5. 等待并获取结果（如果需要）
 	var result string
 	if config.NeedsResponse {
-		result = ece.waitForCommandResult(command, config.ExpectedWaitTime)
+		result = ece.waitForCommandResult(session, command, config.ExpectedWaitTime)
 	}
 
 	return result, nil
@@ -329,9 +331,9 @@ func (ece *EnhancedCommandExecutor) getCommandConfig(command string) *CommandCon
 }
 
 // waitForCommandResult 等待命令结果
-func (ece *EnhancedCommandExecutor) waitForCommandResult(command string, expectedWait time.Duration) string {
+func (ece *EnhancedCommandExecutor) waitForCommandResult(session *InputSession, command string, expectedWait time.Duration) string {
 	// 清空剪贴板准备接收结果
-	clipboard.WriteAll("")
+	session.ClearClipboard()
 	time.Sleep(50 * time.Millisecond)
 
 	// 分段等待，提前检查结果
@@ -341,14 +343,14 @@ func (ece *EnhancedCommandExecutor) waitForCommandResult(command string, expecte
 	for step := 0; step < steps; step++ {
 		time.Sleep(stepTime)
 
-		if result, err := clipboard.ReadAll(); err == nil && result != "" && result != command {
+		if result, err := session.ReadClipboard(); err == nil && result != "" && result != command {
 			fmt.Printf("获取到命令结果，长度: %d\n", len(result))
 			return result
 		}
 	}
 
 	// 最终尝试
-	if result, err := clipboard.ReadAll(); err == nil && result != "" && result != command {
+	if result, err := session.ReadClipboard(); err == nil && result != "" && result != command {
 		return result
 	}
 
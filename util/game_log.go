@@ -0,0 +1,93 @@
+package util
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	_const "qq_client/internal/const"
+	"strings"
+)
+
+// GameLogPath 游戏日志文件路径
+// @param: configured string 配置的路径，为空时为 %LOCALAPPDATA%\SCUM\Saved\Logs\SCUM.log
+// @return: string 无法确定路径时为空
+func GameLogPath(configured string) string {
+	if configured != "" {
+		return configured
+	}
+	localAppData := os.Getenv("LOCALAPPDATA")
+	if localAppData == "" {
+		return ""
+	}
+	return filepath.Join(localAppData, _const.GameLogRelativePath)
+}
+
+// GameLogSize 游戏日志当前大小（发送指令前记录，之后只读取新增的内容）
+// @param: path string 日志路径
+// @return: int64, error 文件不存在或无法读取
+func GameLogSize(path string) (int64, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// ReadGameLogSince 读取 offset 之后新增的完整行
+// @description: 末尾未写完的行留到下次读取；文件比 offset 小时（游戏重启后重新创建了日志）从头读取；
+// 新增内容超过 GameLogMaxReadSize 时只读取末尾
+// @param: path string 日志路径
+// @param: offset int64 上次读取到的位置
+// @return: lines []string, next int64 下次读取的位置, err error
+func ReadGameLogSince(path string, offset int64) ([]string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, offset, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, offset, err
+	}
+
+	size := info.Size()
+	if size < offset {
+		offset = 0
+	}
+	if size == offset {
+		return nil, offset, nil
+	}
+	start := max(offset, size-_const.GameLogMaxReadSize)
+	data := make([]byte, size-start)
+	if _, err = file.ReadAt(data, start); err != nil && err != io.EOF {
+		return nil, offset, err
+	}
+
+	// 只返回完整的行
+	end := bytes.LastIndexByte(data, '\n')
+	if end < 0 {
+		return nil, offset, nil
+	}
+	next := start + int64(end) + 1
+	data = data[:end]
+	if start == 0 {
+		data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	}
+	// 从日志中间开始读取时第一行可能不完整
+	if start > offset {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		} else {
+			return nil, next, nil
+		}
+	}
+
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		if line = strings.TrimRight(line, "\r"); strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines, next, nil
+}
@@ -1,14 +1,10 @@
 package util
 
 import (
-	"bytes"
-	"encoding/base64"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
+	"image"
+	"math"
 	"qq_client/global"
 	_const "qq_client/internal/const"
 	"qq_client/model/request"
@@ -20,11 +16,13 @@ import (
 
 // TextPositionCache 文本位置缓存结构
 type TextPositionCache struct {
-	X1    int
-	Y1    int
-	X2    int
-	Y2    int
-	Found bool
+	X1           int       `json:"x1"`
+	Y1           int       `json:"y1"`
+	X2           int       `json:"x2"`
+	Y2           int       `json:"y2"`
+	Found        bool      `json:"found"`
+	Confidence   float64   `json:"confidence"`    // OCR 置信度或模板匹配得分
+	LastVerified time.Time `json:"last_verified"` // 最后一次验证通过的时间
 }
 
 // 全局文本位置缓存
@@ -33,12 +31,13 @@ var (
 	cacheMutex        sync.RWMutex
 )
 
-// ClearTextPositionCache 清空文本位置缓存（进程重启时调用）
-// @description: 清空文本位置缓存
+// ClearTextPositionCache 清空文本位置缓存
+// @description: 清空文本位置缓存（下次同步时写入磁盘）
 func ClearTextPositionCache() {
 	cacheMutex.Lock()
 	defer cacheMutex.Unlock()
 	textPositionCache = make(map[string]*TextPositionCache)
+	textCacheDirty = true
 }
 
 // GetTextPositionFromCache 从缓存获取文本位置
@@ -53,230 +52,275 @@ func GetTextPositionFromCache(text string) (*TextPositionCache, bool) {
 }
 
 // setTextPositionCache 设置文本位置缓存
-// @description: 设置文本位置缓存
+// @description: 设置文本位置缓存，并记录验证时间
 // @param: text string 目标文本
 // @param: cache *TextPositionCache 缓存数据
 func setTextPositionCache(text string, cache *TextPositionCache) {
 	cacheMutex.Lock()
 	defer cacheMutex.Unlock()
+	if cache.LastVerified.IsZero() {
+		cache.LastVerified = time.Now()
+	}
 	textPositionCache[text] = cache
+	textCacheDirty = true
+}
+
+// touchTextPositionCache 更新缓存条目的验证时间
+// @description: 缓存位置验证通过后调用
+// @param: text string 目标文本
+func touchTextPositionCache(text string) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	if cache, exists := textPositionCache[text]; exists {
+		cache.LastVerified = time.Now()
+		textCacheDirty = true
+	}
+}
+
+// deleteTextPositionCache 删除缓存条目
+// @param: text string 目标文本
+func deleteTextPositionCache(text string) {
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	delete(textPositionCache, text)
+	textCacheDirty = true
 }
 
 // getMultilingualTexts 获取多语言文本列表
-// @description: 根据文本key获取所有支持的语言版本
+// @description: 按界面语言回退链从字典文件获取文本key的所有语言版本，字典中没有时回退到内置映射
 // @param: textKey string 文本key（如 "MUTE", "GLOBAL" 等）
-// @return: []string 多语言文本列表
+// @return: []string 多语言文本列表（已去重，按回退链顺序）
 func getMultilingualTexts(textKey string) []string {
-	// 检查是否有多语言映射
-	if texts, exists := global.GameUIText[strings.ToUpper(textKey)]; exists {
-		return texts
+	key := strings.ToUpper(textKey)
+	locales := getLocales()
+
+	var texts []string
+	seen := make(map[string]bool)
+	appendTexts := func(values []string) {
+		for _, value := range values {
+			if !seen[value] {
+				seen[value] = true
+				texts = append(texts, value)
+			}
+		}
+	}
+
+	for _, lang := range localeChain(locales) {
+		appendTexts(locales[lang][key])
+	}
+	if len(texts) == 0 {
+		appendTexts(global.GameUIText[key])
 	}
 	// 如果没有映射，返回原始文本
-	return []string{textKey}
+	if len(texts) == 0 {
+		return []string{textKey}
+	}
+	return texts
 }
 
-// searchTextInFullScreen 全屏搜索文本并返回位置（支持多语言）
-// @description: 全屏搜索文本并返回位置，支持多语言匹配
-// @param: hand syscall.Handle 窗口句柄
-// @param: targetText string 目标文本key（如 "MUTE", "GLOBAL" 等）
-// @return: *TextPositionCache, error
-func searchTextInFullScreen(hand syscall.Handle, targetText string) (*TextPositionCache, error) {
-	// 获取多语言文本列表
-	var ocrResult request.OcrResult
-	textVariants := getMultilingualTexts(targetText)
+// parseOcrItems 解析OCR响应中的识别结果
+// @description: 优先使用 items 数组格式，没有时回退到旧的 data 数组格式
+// @param: ocrResult *request.OcrResult OCR响应
+// @return: []request.OcrItem
+func parseOcrItems(ocrResult *request.OcrResult) []request.OcrItem {
+	if len(ocrResult.Items) > 0 {
+		return ocrResult.Items
+	}
 
-	// 全屏截图
-	imagePath, err := ScreenshotGrayscale(hand, 0, 0, global.GameWindowWidth, global.GameWindowHeight)
-	if err != nil {
-		return nil, fmt.Errorf("全屏截图失败: %v", err)
+	dataArray, ok := ocrResult.Data.([]interface{})
+	if !ok {
+		return nil
 	}
 
-	// 读取图片
-	defer os.Remove(imagePath)
-	imageData, err := os.ReadFile(imagePath)
-	if err != nil {
-		return nil, fmt.Errorf("读取图片文件失败: %v", err)
+	var items []request.OcrItem
+	for _, item := range dataArray {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		text, _ := itemMap["text"].(string)
+		confidence, _ := itemMap["confidence"].(float64)
+		boxData, _ := itemMap["box"].([]interface{})
+
+		// 转换 box 坐标
+		var box [][]float64
+		for _, point := range boxData {
+			pointArray, ok := point.([]interface{})
+			if !ok || len(pointArray) < 2 {
+				continue
+			}
+			x, _ := pointArray[0].(float64)
+			y, _ := pointArray[1].(float64)
+			box = append(box, []float64{x, y})
+		}
+
+		// 提取 position（如果有）
+		var position request.OcrPosition
+		if posMap, ok := itemMap["position"].(map[string]interface{}); ok {
+			if left, ok := posMap["left"].(float64); ok {
+				position.Left = int(left)
+			}
+			if top, ok := posMap["top"].(float64); ok {
+				position.Top = int(top)
+			}
+			if right, ok := posMap["right"].(float64); ok {
+				position.Right = int(right)
+			}
+			if bottom, ok := posMap["bottom"].(float64); ok {
+				position.Bottom = int(bottom)
+			}
+		}
+
+		items = append(items, request.OcrItem{
+			Text:       text,
+			Confidence: confidence,
+			Box:        box,
+			Position:   position,
+		})
 	}
+	return items
+}
 
-	// 将图片转换为Base64编码
-	base64Data := base64.StdEncoding.EncodeToString(imageData)
+// ocrItemRect 计算识别结果的文本区域
+// @description: 优先使用 position 字段，无效时使用 box 的最小外接矩形
+// @param: item request.OcrItem
+// @return: x1, y1, x2, y2 int, ok bool
+func ocrItemRect(item request.OcrItem) (int, int, int, int, bool) {
+	// 检查 position 是否有效（right 和 bottom 应该大于 left 和 top）
+	if item.Position.Right > item.Position.Left && item.Position.Bottom > item.Position.Top {
+		return item.Position.Left, item.Position.Top, item.Position.Right, item.Position.Bottom, true
+	}
+	if len(item.Box) < 4 {
+		return 0, 0, 0, 0, false
+	}
 
-	// 将请求数据转换为JSON
-	jsonData, err := json.Marshal(map[string]interface{}{
-		"image": base64Data,
-	})
+	minX, minY := math.MaxFloat64, math.MaxFloat64
+	maxX, maxY := 0.0, 0.0
+	for _, point := range item.Box {
+		if len(point) < 2 {
+			continue
+		}
+		minX, maxX = math.Min(minX, point[0]), math.Max(maxX, point[0])
+		minY, maxY = math.Min(minY, point[1]), math.Max(maxY, point[1])
+	}
+	return int(minX), int(minY), int(maxX), int(maxY), true
+}
 
-	// 发送POST请求到PaddleOCR服务
-	client := &http.Client{Timeout: _const.OCRServiceAPITimeout}
-	ocrAPIURL := fmt.Sprintf("http://%s:%d/api/ocr", global.OCRServiceHost, global.OCRServicePort)
-	resp, err := client.Post(ocrAPIURL,
-		"application/json", bytes.NewBuffer(jsonData))
+// locateText 定位文本位置（模板匹配优先，OCR 兜底）
+// @description: 先使用模板匹配查找控件，未配置模板或未匹配时回退到 OCR 全屏搜索
+// @param: hand syscall.Handle 窗口句柄
+// @param: textKey string 目标文本key（如 "MUTE", "GLOBAL" 等）
+// @return: *TextPositionCache, error
+func locateText(hand syscall.Handle, textKey string) (*TextPositionCache, error) {
+	if hasUITemplates(hand, textKey) {
+		if cache, err := searchTextByTemplate(hand, textKey, image.Rectangle{}); err == nil {
+			return cache, nil
+		} else {
+			fmt.Printf("模板匹配失败，回退到OCR全屏搜索: %v\n", err)
+		}
+	}
+	return searchTextInFullScreen(hand, textKey)
+}
+
+// verifyTextByTemplate 使用模板在缓存位置附近验证文本是否仍然存在
+// @description: 在缓存区域外扩 TemplateVerifyMargin 像素的范围内进行模板匹配
+// @param: hand syscall.Handle 窗口句柄
+// @param: textKey string 目标文本key
+// @param: cache *TextPositionCache 缓存位置
+// @return: bool 是否验证通过
+func verifyTextByTemplate(hand syscall.Handle, textKey string, cache *TextPositionCache) bool {
+	if !hasUITemplates(hand, textKey) {
+		return false
+	}
+	margin := _const.TemplateVerifyMargin
+	region := image.Rect(cache.X1-margin, cache.Y1-margin, cache.X2+margin, cache.Y2+margin)
+	newCache, err := searchTextByTemplate(hand, textKey, region)
 	if err != nil {
-		return nil, fmt.Errorf("发送请求失败: %v", err)
+		return false
+	}
+	// 位置有轻微偏移时更新缓存
+	if newCache.X1 != cache.X1 || newCache.Y1 != cache.Y1 || newCache.X2 != cache.X2 || newCache.Y2 != cache.Y2 {
+		setTextPositionCache(textKey, newCache)
+	} else {
+		touchTextPositionCache(textKey)
 	}
-	defer resp.Body.Close()
+	return true
+}
 
-	// 读取并解析响应
-	responseData, err := io.ReadAll(resp.Body)
+// searchTextInFullScreen 全屏搜索文本并返回位置（支持多语言）
+// @description: 全屏搜索文本并返回位置，支持多语言匹配
+// @param: hand syscall.Handle 窗口句柄
+// @param: targetText string 目标文本key（如 "MUTE", "GLOBAL" 等）
+// @return: *TextPositionCache, error
+func searchTextInFullScreen(hand syscall.Handle, targetText string) (*TextPositionCache, error) {
+	// 全屏截图
+	imageData, err := CaptureWindowPNG(hand)
 	if err != nil {
-		return nil, fmt.Errorf("读取响应失败: %v", err)
+		return nil, fmt.Errorf("全屏截图失败: %v", err)
 	}
 
-	// 解析OCR响应
-	if err = json.Unmarshal(responseData, &ocrResult); err != nil {
-		return nil, fmt.Errorf("解析响应JSON失败: %v", err)
+	// 提交到 OCR 调度器（按文本key确定优先级和超时），由服务端按目标文字粗筛
+	priority := ocrPriorityForText(targetText)
+	ctx, cancel := ocrTimeoutFor(priority)
+	defer cancel()
+	ocrResult, err := RecognizeOCR(ctx, OCRCall{
+		Stream:   "fullscreen:" + targetText,
+		Priority: priority,
+		Image:    imageData,
+		Options:  ocrMatchOptions(targetText),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("OCR请求失败: %v", err)
 	}
 
 	// 检查识别结果
-	if ocrResult.Code == 101 {
-		// 检测不到文本
-		return nil, fmt.Errorf("OCR识别失败，code: %d (图片中无文本)", ocrResult.Code)
+	if ocrResult.Code == 200 {
+		// 服务端未找到目标文字（包括图片中没有文字）
+		return nil, fmt.Errorf("全屏搜索未找到文本: '%s' (已尝试: %v)", targetText, getMultilingualTexts(targetText))
 	}
 	if ocrResult.Code != 100 {
 		return nil, fmt.Errorf("OCR识别失败，code: %d", ocrResult.Code)
 	}
 
-	// 优先使用新的 items 数组格式，如果没有则回退到旧的 data 数组格式
-	var itemsToProcess []request.OcrItem
-
-	if len(ocrResult.Items) > 0 {
-		// 使用新格式：items 数组
-		itemsToProcess = ocrResult.Items
-	} else {
-		// 向后兼容：尝试解析 data 为数组
-		dataArray, ok := ocrResult.Data.([]interface{})
-		if ok {
-			// 将旧格式转换为新格式
-			for _, item := range dataArray {
-				itemMap, ok := item.(map[string]interface{})
-				if !ok {
-					continue
-				}
-
-				text, _ := itemMap["text"].(string)
-				confidence, _ := itemMap["confidence"].(float64)
-				boxData, _ := itemMap["box"].([]interface{})
-
-				// 转换 box 坐标
-				var box [][]float64
-				for _, point := range boxData {
-					pointArray, ok := point.([]interface{})
-					if !ok || len(pointArray) < 2 {
-						continue
-					}
-					x, _ := pointArray[0].(float64)
-					y, _ := pointArray[1].(float64)
-					box = append(box, []float64{x, y})
-				}
-
-				// 提取 position（如果有）
-				var position request.OcrPosition
-				if posMap, ok := itemMap["position"].(map[string]interface{}); ok {
-					if left, ok := posMap["left"].(float64); ok {
-						position.Left = int(left)
-					}
-					if top, ok := posMap["top"].(float64); ok {
-						position.Top = int(top)
-					}
-					if right, ok := posMap["right"].(float64); ok {
-						position.Right = int(right)
-					}
-					if bottom, ok := posMap["bottom"].(float64); ok {
-						position.Bottom = int(bottom)
-					}
-				}
-
-				itemsToProcess = append(itemsToProcess, request.OcrItem{
-					Text:       text,
-					Confidence: confidence,
-					Box:        box,
-					Position:   position,
-				})
-			}
-		}
+	// 只需复核服务端匹配到的文本块；旧版本服务端不返回 matches 时检查全部文本块
+	itemsToProcess := ocrResult.Matches
+	if len(itemsToProcess) == 0 {
+		itemsToProcess = parseOcrItems(ocrResult)
 	}
-
 	if len(itemsToProcess) == 0 {
 		return nil, fmt.Errorf("OCR响应中未找到识别结果")
 	}
 
 	// 遍历所有识别到的文本，查找目标文本（支持多语言）
 	for _, item := range itemsToProcess {
-		text := item.Text
-		textUpper := strings.ToUpper(strings.TrimSpace(text))
-
-		// 检查是否匹配任何语言版本
-		matched := false
-		for _, variant := range textVariants {
-			variantUpper := strings.ToUpper(strings.TrimSpace(variant))
-			if strings.Contains(textUpper, variantUpper) || strings.Contains(variantUpper, textUpper) {
-				matched = true
-				break
-			}
+		variant, matched := matchOcrItem(item, targetText)
+		if !matched {
+			continue
 		}
 
-		if matched {
-			// 找到目标文本，使用 position 或 box 计算坐标
-			var x1, y1, x2, y2 int
-
-			// 优先使用 position 字段（更简单直接）
-			// 检查 position 是否有效（right 和 bottom 应该大于 left 和 top）
-			if item.Position.Right > item.Position.Left && item.Position.Bottom > item.Position.Top {
-				x1 = item.Position.Left
-				y1 = item.Position.Top
-				x2 = item.Position.Right
-				y2 = item.Position.Bottom
-			} else if len(item.Box) >= 4 {
-				// 回退到使用 box 计算最小最大坐标
-				var minX, minY, maxX, maxY float64
-				minX, minY = 999999, 999999
-				maxX, maxY = 0, 0
-
-				for _, point := range item.Box {
-					if len(point) < 2 {
-						continue
-					}
-					x := point[0]
-					y := point[1]
-
-					if x < minX {
-						minX = x
-					}
-					if x > maxX {
-						maxX = x
-					}
-					if y < minY {
-						minY = y
-					}
-					if y > maxY {
-						maxY = y
-					}
-				}
-
-				x1 = int(minX)
-				y1 = int(minY)
-				x2 = int(maxX)
-				y2 = int(maxY)
-			} else {
-				continue
-			}
-
-			// 创建缓存对象
-			cache := &TextPositionCache{
-				X1:    x1,
-				Y1:    y1,
-				X2:    x2,
-				Y2:    y2,
-				Found: true,
-			}
+		// 找到目标文本，使用 position 或 box 计算坐标
+		x1, y1, x2, y2, ok := ocrItemRect(item)
+		if !ok {
+			continue
+		}
 
-			fmt.Printf("全屏搜索成功: 找到文本 '%s' (识别为: '%s', 置信度: %.2f) 在位置 [%d,%d,%d,%d]\n",
-				targetText, text, item.Confidence, cache.X1, cache.Y1, cache.X2, cache.Y2)
-			return cache, nil
+		// 创建缓存对象
+		cache := &TextPositionCache{
+			X1:         x1,
+			Y1:         y1,
+			X2:         x2,
+			Y2:         y2,
+			Found:      true,
+			Confidence: item.Confidence,
 		}
+
+		fmt.Printf("全屏搜索成功: 找到文本 '%s' (识别为: '%s', 匹配: '%s', 置信度: %.2f) 在位置 [%d,%d,%d,%d]\n",
+			targetText, item.Text, variant, item.Confidence, cache.X1, cache.Y1, cache.X2, cache.Y2)
+		return cache, nil
 	}
 
-	return nil, fmt.Errorf("全屏搜索未找到文本: '%s' (已尝试: %v)", targetText, textVariants)
+	return nil, fmt.Errorf("全屏搜索未找到文本: '%s' (已尝试: %v)", targetText, getMultilingualTexts(targetText))
 }
 
 // ExtractTextFromSpecifiedAreaAndValidateThreeTimes
@@ -287,259 +331,163 @@ func searchTextInFullScreen(hand syscall.Handle, targetText string) (*TextPositi
 // @param: test string 期望识别的文本key（如 "MUTE", "GLOBAL" 等）
 // @return: error
 func ExtractTextFromSpecifiedAreaAndValidateThreeTimes(hand syscall.Handle, test string) error {
-	// 检查缓存（使用原始key）
-	cache, exists := GetTextPositionFromCache(test)
+	return ValidateTexts(hand, test)[test]
+}
 
-	var isNewlyFound bool
-	if !exists {
-		// 首次搜索，使用全屏搜索
-		newCache, err := searchTextInFullScreen(hand, test)
-		if err != nil {
-			return err
+// ValidateTexts
+// @function: ValidateTexts
+// @description: 同时验证多个文本是否仍在缓存位置（自动缓存位置，支持多语言）
+// 需要 OCR 验证的文本共用同一帧截图和同一次 OCR 请求，每个文本是请求中的一个查询
+// @param: hand syscall.Handle 窗口句柄
+// @param: tests ...string 期望识别的文本key（如 "CONTINUE", "MUTE"）
+// @return: map[string]error 每个文本key的验证结果，nil 表示验证通过
+func ValidateTexts(hand syscall.Handle, tests ...string) map[string]error {
+	results := make(map[string]error, len(tests))
+	caches := make(map[string]*TextPositionCache, len(tests))
+	var pending []string
+	for _, test := range tests {
+		cache, exists := GetTextPositionFromCache(test)
+		if !exists {
+			// 首次搜索，模板匹配优先，OCR全屏搜索兜底；全屏搜索找到即视为验证通过
+			newCache, err := locateText(hand, test)
+			if err == nil {
+				setTextPositionCache(test, newCache)
+			}
+			results[test] = err
+			continue
 		}
-
-		// 保存到缓存
-		setTextPositionCache(test, newCache)
-		cache = newCache
-		isNewlyFound = true
-	} else {
-		isNewlyFound = false
+		// 优先使用模板匹配验证缓存位置，无模板或未匹配时回退到OCR验证
+		if verifyTextByTemplate(hand, test, cache) {
+			results[test] = nil
+			continue
+		}
+		caches[test] = cache
+		pending = append(pending, test)
 	}
-
-	// 如果全屏搜索刚找到文本，直接返回成功（全屏搜索已经验证了文本存在）
-	if isNewlyFound {
-		return nil
+	if len(pending) == 0 {
+		return results
 	}
 
 	// 使用缓存的位置进行识别（仅在使用已有缓存时验证）
-	var ocrVerified bool
+	recognized := make(map[string]bool, len(pending))
 	var hasSuccessfulScreenshot bool
-	for i := 1; i <= 3; i++ {
-		// 截图指定区域
-		imagePath, err := ScreenshotGrayscale(hand, cache.X1, cache.Y1, cache.X2, cache.Y2)
-		if err != nil {
-			fmt.Printf("[ERROR] 第%d次截图失败: %v\n", i, err)
-			continue
+	for i := 1; i <= _const.TextVerifyAttempts && len(pending) > 0; i++ {
+		if i > 1 {
+			// 识别失败，等待后重试
+			time.Sleep(100 * time.Millisecond)
 		}
-
-		// 获取图片文件信息
-		_, err = os.Stat(imagePath)
-		defer func(imgPath string) {
-			// 删除临时文件
-			_ = os.Remove(imgPath)
-		}(imagePath)
-		hasSuccessfulScreenshot = true
-
-		// 读取图片
-		imageData, err := os.ReadFile(imagePath)
-		if err != nil {
-			continue
+		// 只截取并上传包含所有缓存区域的部分
+		region := image.Rectangle{}
+		for _, test := range pending {
+			cache := caches[test]
+			region = region.Union(image.Rect(cache.X1, cache.Y1, cache.X2, cache.Y2))
 		}
-
-		// 将图片转换为Base64编码
-		base64Data := base64.StdEncoding.EncodeToString(imageData)
-
-		// 将请求数据转换为JSON
-		jsonData, err := json.Marshal(map[string]interface{}{
-			"image": base64Data,
-		})
+		region = region.Inset(-_const.TextVerifyMargin)
+		imageData, origin, err := CaptureWindowRegionPNG(hand, region)
 		if err != nil {
+			fmt.Printf("[ERROR] 第%d次截图失败: %v\n", i, err)
 			continue
 		}
+		hasSuccessfulScreenshot = true
 
-		// 发送POST请求到PaddleOCR服务
-		var ocrResult request.OcrResult
-		client := &http.Client{Timeout: _const.OCRServiceAPITimeout}
-		ocrAPIURL := fmt.Sprintf("http://%s:%d/api/ocr", global.OCRServiceHost, global.OCRServicePort)
-		resp, err := client.Post(ocrAPIURL,
-			"application/json", bytes.NewBuffer(jsonData))
+		ocrResult, err := recognizeCachedTexts(imageData, origin, pending, caches)
 		if err != nil {
+			fmt.Printf("第%d次OCR请求失败: %v\n", i, err)
 			continue
 		}
 
-		// 读取并解析响应
-		responseData, err := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if err != nil {
-			fmt.Printf("第%d次读取响应失败: %v\n", i, err)
-			continue
-		}
-		// 解析OCR响应
-		if err = json.Unmarshal(responseData, &ocrResult); err != nil {
-			fmt.Printf("第%d次解析响应JSON失败: %v\n", i, err)
-			continue
+		// 检查每个查询的结果：服务端找到目标文字后再按匹配策略复核
+		var remaining []string
+		for _, test := range pending {
+			result := findQueryResult(ocrResult, test)
+			if result != nil && result.Code == 100 && matchAnyOcrItem(result.Matches, test) {
+				touchTextPositionCache(test)
+				results[test] = nil
+				continue
+			}
+			if result != nil && len(result.Items) > 0 {
+				recognized[test] = true // OCR成功识别了文本，只是不匹配
+			}
+			remaining = append(remaining, test)
 		}
+		pending = remaining
+	}
 
-		// 检查识别结果
-		textVariants := getMultilingualTexts(test)
-		if ocrResult.Code == 100 {
-			// 识别成功，检查是否包含目标文本（支持多语言）
-			// 优先使用新的 items 数组格式
-			var itemsToProcess []request.OcrItem
-
-			if len(ocrResult.Items) > 0 {
-				// 使用新格式：items 数组
-				itemsToProcess = ocrResult.Items
+	// 判断失败原因
+	for _, test := range pending {
+		switch {
+		case !hasSuccessfulScreenshot:
+			// 如果所有截图都失败，保留缓存（位置可能是正确的，只是截图功能有问题）
+			results[test] = errors.New("截图失败，无法验证文本")
+		case recognized[test]:
+			// 如果OCR成功识别了文本，但文本不匹配，说明位置可能已变化，清除缓存
+			deleteTextPositionCache(test)
+			results[test] = errors.New("文本位置已变化，缓存已清除")
+		default:
+			// OCR识别失败，可能是临时问题，尝试全屏搜索一次确认文本是否还在
+			if newCache, err := locateText(hand, test); err == nil && newCache != nil {
+				// 全屏搜索找到了文本，但位置已变化，更新缓存
+				setTextPositionCache(test, newCache)
+				results[test] = nil
 			} else {
-				// 向后兼容：尝试解析 data 为数组
-				dataArray, ok := ocrResult.Data.([]interface{})
-				if ok {
-					// 将旧格式转换为新格式
-					for _, item := range dataArray {
-						itemMap, ok := item.(map[string]interface{})
-						if !ok {
-							continue
-						}
-
-						text, _ := itemMap["text"].(string)
-						confidence, _ := itemMap["confidence"].(float64)
-						boxData, _ := itemMap["box"].([]interface{})
-
-						// 转换 box 坐标
-						var box [][]float64
-						for _, point := range boxData {
-							pointArray, ok := point.([]interface{})
-							if !ok || len(pointArray) < 2 {
-								continue
-							}
-							x, _ := pointArray[0].(float64)
-							y, _ := pointArray[1].(float64)
-							box = append(box, []float64{x, y})
-						}
-
-						// 提取 position（如果有）
-						var position request.OcrPosition
-						if posMap, ok := itemMap["position"].(map[string]interface{}); ok {
-							if left, ok := posMap["left"].(float64); ok {
-								position.Left = int(left)
-							}
-							if top, ok := posMap["top"].(float64); ok {
-								position.Top = int(top)
-							}
-							if right, ok := posMap["right"].(float64); ok {
-								position.Right = int(right)
-							}
-							if bottom, ok := posMap["bottom"].(float64); ok {
-								position.Bottom = int(bottom)
-							}
-						}
-
-						itemsToProcess = append(itemsToProcess, request.OcrItem{
-							Text:       text,
-							Confidence: confidence,
-							Box:        box,
-							Position:   position,
-						})
-					}
-				}
+				// 全屏搜索也没找到，可能是界面已变化，保留旧缓存
+				results[test] = errors.New("OCR识别失败，全屏搜索也未找到文本，保留缓存位置")
 			}
+		}
+	}
+	return results
+}
 
-			if len(itemsToProcess) > 0 {
-				for _, item := range itemsToProcess {
-					text := item.Text
-					textUpper := strings.ToUpper(strings.TrimSpace(text))
-
-					// 检查是否匹配任何语言版本
-					for _, variant := range textVariants {
-						variantUpper := strings.ToUpper(strings.TrimSpace(variant))
-						if strings.Contains(textUpper, variantUpper) || strings.Contains(variantUpper, textUpper) {
-							ocrVerified = true
-							return nil
-						}
-					}
-				}
-			}
-			ocrVerified = true // OCR成功识别了文本，只是不匹配
-		} else if ocrResult.Code == 200 {
-			// Code 200: 可能表示"未找到目标文字"或"没有识别到文字"
-			// 检查是否有识别结果
-			var itemsToProcess []request.OcrItem
-			if len(ocrResult.Items) > 0 {
-				itemsToProcess = ocrResult.Items
-			} else {
-				// 尝试解析 data
-				dataArray, ok := ocrResult.Data.([]interface{})
-				if ok {
-					for _, item := range dataArray {
-						itemMap, ok := item.(map[string]interface{})
-						if !ok {
-							continue
-						}
-						text, _ := itemMap["text"].(string)
-						confidence, _ := itemMap["confidence"].(float64)
-						boxData, _ := itemMap["box"].([]interface{})
-						var box [][]float64
-						for _, point := range boxData {
-							pointArray, ok := point.([]interface{})
-							if !ok || len(pointArray) < 2 {
-								continue
-							}
-							x, _ := pointArray[0].(float64)
-							y, _ := pointArray[1].(float64)
-							box = append(box, []float64{x, y})
-						}
-						var position request.OcrPosition
-						if posMap, ok := itemMap["position"].(map[string]interface{}); ok {
-							if left, ok := posMap["left"].(float64); ok {
-								position.Left = int(left)
-							}
-							if top, ok := posMap["top"].(float64); ok {
-								position.Top = int(top)
-							}
-							if right, ok := posMap["right"].(float64); ok {
-								position.Right = int(right)
-							}
-							if bottom, ok := posMap["bottom"].(float64); ok {
-								position.Bottom = int(bottom)
-							}
-						}
-						itemsToProcess = append(itemsToProcess, request.OcrItem{
-							Text:       text,
-							Confidence: confidence,
-							Box:        box,
-							Position:   position,
-						})
-					}
-				}
-			}
+// recognizeCachedTexts 在同一张截图上查询多个文本的缓存区域
+// @description: 每个文本key是一个查询，预处理开关和语言提示取第一个文本key的匹配策略
+// @param: imageData []byte 截图区域的 PNG 数据
+// @param: origin image.Point 截图区域左上角在窗口中的坐标
+// @param: tests []string 文本key
+// @param: caches map[string]*TextPositionCache 文本key的缓存位置（窗口坐标）
+// @return: *request.OcrResult, error
+func recognizeCachedTexts(imageData []byte, origin image.Point, tests []string, caches map[string]*TextPositionCache) (*request.OcrResult, error) {
+	options := ocrMatchOptions(tests[0])
+	options.TargetText = nil
+	options.MinConfidence = 0
+	options.MaxEditDistance = 0
+	priority := OCRPriorityBackground
+	for _, test := range tests {
+		match := ocrMatchOptions(test)
+		cache := caches[test]
+		options.Queries = append(options.Queries, global.OCRQuery{
+			ID:              test,
+			TargetText:      match.TargetText,
+			Crop:            &global.OCRRect{Left: cache.X1 - origin.X, Top: cache.Y1 - origin.Y, Right: cache.X2 - origin.X, Bottom: cache.Y2 - origin.Y},
+			MinConfidence:   &match.MinConfidence,
+			MaxEditDistance: &match.MaxEditDistance,
+		})
+		priority = min(priority, ocrPriorityForText(test))
+	}
 
-			if len(itemsToProcess) > 0 {
-				// 识别到了文字，但没有找到目标文字
-				recognizedTexts := make([]string, 0, len(itemsToProcess))
-				for _, item := range itemsToProcess {
-					recognizedTexts = append(recognizedTexts, item.Text)
-				}
-			}
-		}
+	// 提交到 OCR 调度器，同一组文本的新截图会取消尚未完成的旧请求
+	ctx, cancel := ocrTimeoutFor(priority)
+	defer cancel()
+	return RecognizeOCR(ctx, OCRCall{Stream: "verify:" + strings.Join(tests, "+"), Priority: priority, Image: imageData, Options: options})
+}
 
-		// 如果识别失败，等待后重试
-		if i < 3 {
-			time.Sleep(100 * time.Millisecond)
+// findQueryResult 按查询ID查找结果，未找到时返回 nil
+func findQueryResult(ocrResult *request.OcrResult, id string) *request.OcrQueryResult {
+	for i := range ocrResult.Results {
+		if ocrResult.Results[i].ID == id {
+			return &ocrResult.Results[i]
 		}
 	}
+	return nil
+}
 
-	// 判断失败原因
-	if !hasSuccessfulScreenshot {
-		// 如果所有截图都失败，保留缓存（位置可能是正确的，只是截图功能有问题）
-		return errors.New("截图失败，无法验证文本")
-	} else if ocrVerified {
-		// 如果OCR成功识别了文本，但文本不匹配，说明位置可能已变化，清除缓存
-		cacheMutex.Lock()
-		delete(textPositionCache, test)
-		cacheMutex.Unlock()
-		return errors.New("文本位置已变化，缓存已清除")
-	} else {
-		// OCR识别失败，可能是临时问题，尝试全屏搜索一次确认文本是否还在
-		newCache, err := searchTextInFullScreen(hand, test)
-		if err == nil && newCache != nil {
-			// 全屏搜索找到了文本，但位置已变化，更新缓存
-			setTextPositionCache(test, newCache)
-			return nil // 位置已更新，验证通过
-		} else {
-			// 全屏搜索也没找到，可能是界面已变化，保留旧缓存
-			return errors.New("OCR识别失败，全屏搜索也未找到文本，保留缓存位置")
+// matchAnyOcrItem 是否有文本块符合文本key的匹配策略
+func matchAnyOcrItem(items []request.OcrItem, textKey string) bool {
+	for _, item := range items {
+		if _, matched := matchOcrItem(item, textKey); matched {
+			return true
 		}
 	}
+	return false
 }
 
 // ClickTextCenter
@@ -553,8 +501,8 @@ func ClickTextCenter(hand syscall.Handle, text string) error {
 	// 获取文本位置（从缓存或全屏搜索）
 	cache, exists := GetTextPositionFromCache(text)
 	if !exists {
-		// 首次搜索，使用全屏搜索
-		newCache, err := searchTextInFullScreen(hand, text)
+		// 首次搜索，模板匹配优先，OCR全屏搜索兜底
+		newCache, err := locateText(hand, text)
 		if err != nil {
 			return fmt.Errorf("全屏搜索文本 '%s' 失败: %v", text, err)
 		}
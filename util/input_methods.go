@@ -2,7 +2,6 @@ package util
 
 import (
 	"fmt"
-	"github.com/atotto/clipboard"
 	_const "qq_client/internal/const"
 	"syscall"
 	"time"
@@ -200,7 +199,11 @@ func (eim *EnhancedInputManager) sendVirtualKey(vk uint8) error {
 }
 
 // SendText 发送文本（智能选择输入方式）
-func (eim *EnhancedInputManager) SendText(text string, preferredMethod InputMethod) error {
+// @param: session *InputSession 调用方持有的输入会话（剪贴板粘贴方式使用）
+// @param: text string
+// @param: preferredMethod InputMethod
+// @return: error
+func (eim *EnhancedInputManager) SendText(session *InputSession, text string, preferredMethod InputMethod) error {
 	if text == "" {
 		return fmt.Errorf("文本不能为空")
 	}
@@ -212,7 +215,7 @@ func (eim *EnhancedInputManager) SendText(text string, preferredMethod InputMeth
 	// 如果指定了首选方法，先尝试首选方法
 	if preferredMethod != INPUT_HYBRID {
 		methodUsed = preferredMethod
-		err = eim.sendTextWithMethod(text, preferredMethod)
+		err = eim.sendTextWithMethod(session, text, preferredMethod)
 	}
 
 	// 如果首选方法失败且启用了回退机制，尝试回退方案
@@ -223,7 +226,7 @@ func (eim *EnhancedInputManager) SendText(text string, preferredMethod InputMeth
 			}
 
 			fmt.Printf("尝试回退方案: %d\n", method)
-			if err = eim.sendTextWithMethod(text, method); err == nil {
+			if err = eim.sendTextWithMethod(session, text, method); err == nil {
 				methodUsed = method
 				break
 			}
@@ -242,7 +245,7 @@ func (eim *EnhancedInputManager) SendText(text string, preferredMethod InputMeth
 }
 
 // sendTextWithMethod 使用指定方法发送文本
-func (eim *EnhancedInputManager) sendTextWithMethod(text string, method InputMethod) error {
+func (eim *EnhancedInputManager) sendTextWithMethod(session *InputSession, text string, method InputMethod) error {
 	switch method {
 	case INPUT_SIMULATE_KEY:
 		return eim.sendTextWithSimulateKey(text)
@@ -251,7 +254,7 @@ func (eim *EnhancedInputManager) sendTextWithMethod(text string, method InputMet
 	case INPUT_UI_AUTOMATION:
 		return eim.sendTextWithUIAutomation(text)
 	case INPUT_CLIPBOARD_PASTE:
-		return eim.sendTextWithClipboardPaste(text)
+		return eim.sendTextWithClipboardPaste(session, text)
 	default:
 		return fmt.Errorf("不支持的输入方法: %d", method)
 	}
@@ -313,18 +316,15 @@ func (eim *EnhancedInputManager) sendTextWithUIAutomation(text string) error {
 	return fmt.Errorf("UI自动化方法暂未实现")
 }
 
-// sendTextWithClipboardPaste 使用剪贴板粘贴发送文本
-func (eim *EnhancedInputManager) sendTextWithClipboardPaste(text string) error {
-	// 备份当前剪贴板内容
-	originalClipboard, _ := clipboard.ReadAll()
-
+// sendTextWithClipboardPaste 使用剪贴板粘贴发送文本（用户的剪贴板内容在会话结束时恢复）
+func (eim *EnhancedInputManager) sendTextWithClipboardPaste(session *InputSession, text string) error {
 	// 清空输入框
 	if err := eim.clearInputBox(); err != nil {
 		return fmt.Errorf("清空输入框失败: %v", err)
 	}
 
 	// 将文本写入剪贴板
-	if err := clipboard.WriteAll(text); err != nil {
+	if err := session.WriteClipboard(text); err != nil {
 		return fmt.Errorf("写入剪贴板失败: %v", err)
 	}
 
@@ -333,17 +333,12 @@ func (eim *EnhancedInputManager) sendTextWithClipboardPaste(text string) error {
 
 	// 模拟Ctrl+V粘贴
 	if err := eim.simulateCtrlV(); err != nil {
-		// 恢复剪贴板内容
-		clipboard.WriteAll(originalClipboard)
 		return fmt.Errorf("粘贴操作失败: %v", err)
 	}
 
 	// 验证粘贴是否成功
 	time.Sleep(100 * time.Millisecond)
 
-	// 恢复剪贴板内容
-	clipboard.WriteAll(originalClipboard)
-
 	return nil
 }
 
@@ -0,0 +1,128 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"qq_client/internal/control"
+	"sync"
+	"time"
+
+	"github.com/atotto/clipboard"
+)
+
+// ErrInputSessionEnded 输入会话结束后继续使用剪贴板
+var ErrInputSessionEnded = errors.New("input session ended")
+
+// inputScheduler 在输入会话之间分配剪贴板和键盘输入（多实例时还包括前台窗口和鼠标）
+// 同一时间只有一个会话持有输入，其他会话按申请顺序排队，保证两条指令不会交错
+type inputScheduler struct {
+	mutex   sync.Mutex
+	busy    bool
+	owner   string
+	waiters []chan struct{}
+}
+
+// input 进程内唯一的剪贴板和键盘输入
+var input inputScheduler
+
+// acquire 等待并持有输入
+// @param: owner string 申请者，用于排队日志
+func (s *inputScheduler) acquire(owner string) {
+	s.mutex.Lock()
+	if s.busy {
+		ready := make(chan struct{})
+		s.waiters = append(s.waiters, ready)
+		holder := s.owner
+		s.mutex.Unlock()
+
+		start := time.Now()
+		<-ready
+		if wait := time.Since(start); wait > time.Second && control.LogEnabled(control.LogDebug) {
+			fmt.Printf("[%s] 等待输入 %v（持有者: %s）\n", owner, wait.Round(time.Millisecond), holder)
+		}
+		s.mutex.Lock()
+	}
+	s.busy = true
+	s.owner = owner
+	s.mutex.Unlock()
+}
+
+// release 释放输入，交给最早排队的申请者
+func (s *inputScheduler) release() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.owner = ""
+	if len(s.waiters) == 0 {
+		s.busy = false
+		return
+	}
+	// busy 保持为 true，直接交给下一个申请者，避免被新的申请插队
+	next := s.waiters[0]
+	s.waiters = s.waiters[1:]
+	close(next)
+}
+
+// InputSession 一条指令独占剪贴板和键盘输入的会话
+// 会话开始时保存用户的剪贴板文本，结束时恢复；剪贴板只能通过会话读写，会话结束后的读写返回 ErrInputSessionEnded
+type InputSession struct {
+	mutex    sync.Mutex
+	ended    bool
+	dirty    bool   // 会话期间是否写过剪贴板
+	original string // 会话开始时的剪贴板文本（剪贴板为空或不是文本时为空）
+}
+
+// BeginInputSession 等待并开始输入会话
+// @description: 按申请顺序排队，直到之前的会话结束；调用方必须调用 End（通常 defer）
+// @param: owner string 会话持有者（实例名称或执行器名称），用于排队日志
+// @return: *InputSession
+func BeginInputSession(owner string) *InputSession {
+	input.acquire(owner)
+	session := &InputSession{}
+	session.original, _ = clipboard.ReadAll()
+	return session
+}
+
+// WriteClipboard 写入剪贴板
+// @param: text string
+// @return: error 写入失败或会话已结束
+func (s *InputSession) WriteClipboard(text string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ended {
+		return ErrInputSessionEnded
+	}
+	s.dirty = true
+	return clipboard.WriteAll(text)
+}
+
+// ClearClipboard 清空剪贴板（准备接收游戏复制的指令结果）
+func (s *InputSession) ClearClipboard() {
+	_ = s.WriteClipboard("")
+}
+
+// ReadClipboard 读取剪贴板
+// @return: string, error 读取失败或会话已结束
+func (s *InputSession) ReadClipboard() (string, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.ended {
+		return "", ErrInputSessionEnded
+	}
+	return clipboard.ReadAll()
+}
+
+// End 恢复用户的剪贴板并结束会话，交给下一个排队的会话（重复调用无效）
+// @description: 剪贴板原来为空或不是文本时无法恢复，写过剪贴板则清空
+func (s *InputSession) End() {
+	s.mutex.Lock()
+	if s.ended {
+		s.mutex.Unlock()
+		return
+	}
+	s.ended = true
+	if s.dirty {
+		_ = clipboard.WriteAll(s.original)
+	}
+	s.mutex.Unlock()
+	input.release()
+}
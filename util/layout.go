@@ -0,0 +1,168 @@
+package util
+
+import (
+	"fmt"
+	"math"
+	"qq_client/global"
+	_const "qq_client/internal/const"
+	"syscall"
+)
+
+// findLayoutProfile 按名称查找布局
+// @description: 优先查找配置中的自定义布局，其次是默认布局
+// @param: name string 布局名称
+// @return: *global.LayoutProfile, bool
+func findLayoutProfile(name string) (*global.LayoutProfile, bool) {
+	for i := range global.ScumConfig.Layouts {
+		if global.ScumConfig.Layouts[i].Name == name {
+			return &global.ScumConfig.Layouts[i], true
+		}
+	}
+	if name == global.DefaultLayoutProfile.Name {
+		return &global.DefaultLayoutProfile, true
+	}
+	return nil, false
+}
+
+// ActiveLayoutProfile 获取当前使用的布局
+// @description: 配置中指定了布局名称时使用指定布局，否则按客户区大小匹配自定义布局，均未命中时使用默认布局
+// @param: hand syscall.Handle 窗口句柄
+// @return: *global.LayoutProfile
+func ActiveLayoutProfile(hand syscall.Handle) *global.LayoutProfile {
+	if name := global.ScumConfig.Layout; name != "" {
+		if profile, ok := findLayoutProfile(name); ok {
+			return profile
+		}
+		fmt.Printf("[WARN] 未找到布局 %s，使用自动选择\n", name)
+	}
+
+	if width, height, ok := GetClientSize(hand); ok {
+		for i := range global.ScumConfig.Layouts {
+			profile := &global.ScumConfig.Layouts[i]
+			if profile.Width == width && profile.Height == height {
+				return profile
+			}
+		}
+	}
+	return &global.DefaultLayoutProfile
+}
+
+// GetLayoutWindow 获取游戏窗口应设置的位置和大小
+// @description: 指定布局时使用该布局的窗口配置（为空表示不调整窗口），否则使用默认布局的窗口配置
+// @return: *global.LayoutWindow 为 nil 时不调整窗口
+func GetLayoutWindow() *global.LayoutWindow {
+	if name := global.ScumConfig.Layout; name != "" {
+		if profile, ok := findLayoutProfile(name); ok {
+			return profile.Window
+		}
+	}
+	return global.DefaultLayoutProfile.Window
+}
+
+// lookupLayoutAnchor 在布局中查找锚点
+// @description: 当前布局未定义该锚点时回退到默认布局
+// @return: anchor, 定义该锚点的布局, ok
+func lookupLayoutAnchor(profile *global.LayoutProfile, name string) (global.LayoutAnchor, *global.LayoutProfile, bool) {
+	if anchor, ok := profile.Anchors[name]; ok {
+		return anchor, profile, true
+	}
+	if anchor, ok := global.DefaultLayoutProfile.Anchors[name]; ok {
+		return anchor, &global.DefaultLayoutProfile, true
+	}
+	return global.LayoutAnchor{}, nil, false
+}
+
+// refPoint 获取参照文本区域上的参照点
+func refPoint(cache *TextPositionCache, point string) (int, int) {
+	switch point {
+	case _const.RefPointTopRight:
+		return cache.X2, cache.Y1
+	case _const.RefPointBottomLeft:
+		return cache.X1, cache.Y2
+	case _const.RefPointBottomRight:
+		return cache.X2, cache.Y2
+	case _const.RefPointCenter:
+		return (cache.X1 + cache.X2) / 2, (cache.Y1 + cache.Y2) / 2
+	default:
+		return cache.X1, cache.Y1
+	}
+}
+
+// LayoutPoint 计算锚点在客户区中的坐标
+// @description: 参照文本已缓存时按参照点+偏移计算（偏移按客户区大小和UI缩放换算），否则按相对位置计算
+// @param: hand syscall.Handle 窗口句柄, name string 锚点名称
+// @return: x, y int 客户区坐标, error
+func LayoutPoint(hand syscall.Handle, name string) (int, int, error) {
+	profile := ActiveLayoutProfile(hand)
+	anchor, owner, ok := lookupLayoutAnchor(profile, name)
+	if !ok {
+		return 0, 0, fmt.Errorf("布局 %s 未定义锚点 %s", profile.Name, name)
+	}
+
+	width, height, ok := GetClientSize(hand)
+	if !ok || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("获取窗口客户区大小失败")
+	}
+
+	if anchor.Ref != "" {
+		if cache, found := GetTextPositionFromCache(anchor.Ref); found {
+			scale := 1.0
+			if owner.Width > 0 {
+				scale = float64(width) / float64(owner.Width)
+			}
+			if owner.UIScale > 0 {
+				scale *= owner.UIScale
+			}
+			x, y := refPoint(cache, anchor.RefPoint)
+			x += int(math.Round(float64(anchor.OffsetX) * scale))
+			y += int(math.Round(float64(anchor.OffsetY) * scale))
+			return x, y, nil
+		}
+		if anchor.RelX == 0 && anchor.RelY == 0 {
+			return 0, 0, fmt.Errorf("锚点 %s 的参照文本 %s 未缓存", name, anchor.Ref)
+		}
+	}
+
+	x := int(math.Round(anchor.RelX * float64(width)))
+	y := int(math.Round(anchor.RelY * float64(height)))
+	return x, y, nil
+}
+
+// LayoutScreenPoint 计算锚点的屏幕坐标
+// @param: hand syscall.Handle 窗口句柄, name string 锚点名称
+// @return: x, y int 屏幕坐标, error
+func LayoutScreenPoint(hand syscall.Handle, name string) (int, int, error) {
+	x, y, err := LayoutPoint(hand, name)
+	if err != nil {
+		return 0, 0, err
+	}
+	screenX, screenY, ok := ClientToScreen(hand, x, y)
+	if !ok {
+		return 0, 0, fmt.Errorf("坐标转换失败")
+	}
+	return screenX, screenY, nil
+}
+
+// LayoutPointColor 获取锚点位置的颜色
+// @param: hand syscall.Handle 窗口句柄, name string 锚点名称
+// @return: string 颜色值（RRGGBB），锚点无法解析时返回 "000000"
+func LayoutPointColor(hand syscall.Handle, name string) string {
+	x, y, err := LayoutPoint(hand, name)
+	if err != nil {
+		fmt.Printf("[WARN] 解析锚点失败: %v\n", err)
+		return "000000"
+	}
+	return SpecifiedCoordinateColor(hand, x, y)
+}
+
+// LayoutColorMatches 判断锚点位置颜色是否与锚点期望颜色接近
+// @param: hand syscall.Handle 窗口句柄, name string 锚点名称
+// @return: bool
+func LayoutColorMatches(hand syscall.Handle, name string) bool {
+	anchor, _, ok := lookupLayoutAnchor(ActiveLayoutProfile(hand), name)
+	if !ok || anchor.Color == "" {
+		return false
+	}
+	similar, err := IsColorSimilar(LayoutPointColor(hand, name), anchor.Color, _const.ColorMatchThreshold)
+	return err == nil && similar
+}
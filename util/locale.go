@@ -0,0 +1,171 @@
+package util
+
+import (
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"qq_client/global"
+	_const "qq_client/internal/const"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// 已加载的界面文本字典
+var (
+	localeStore  map[string]map[string][]string // 语言 -> KEY -> 文本列表
+	localeLoaded bool
+	localeMutex  sync.RWMutex
+)
+
+// ReloadLocales 清空已加载的界面文本字典，下次使用时重新从磁盘加载
+// @description: 字典文件更新后调用
+func ReloadLocales() {
+	localeMutex.Lock()
+	defer localeMutex.Unlock()
+	localeStore = nil
+	localeLoaded = false
+}
+
+// loadLocales 从 locales/ 目录加载所有语言字典
+// @description: 支持 .yaml/.yml/.json 文件，文件名（不含扩展名）即语言代码
+// @return: map[string]map[string][]string
+func loadLocales() map[string]map[string][]string {
+	store := make(map[string]map[string][]string)
+	entries, err := os.ReadDir(_const.LocaleDir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			fmt.Printf("[WARN] 读取界面文本字典目录失败: %v\n", err)
+		}
+		return store
+	}
+
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml" && ext != ".json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(_const.LocaleDir, entry.Name()))
+		if err != nil {
+			fmt.Printf("[WARN] 读取界面文本字典 %s 失败: %v\n", entry.Name(), err)
+			continue
+		}
+		// JSON 是 YAML 的子集，统一使用 YAML 解析
+		var texts map[string][]string
+		if err = yaml.Unmarshal(data, &texts); err != nil {
+			fmt.Printf("[WARN] 解析界面文本字典 %s 失败: %v\n", entry.Name(), err)
+			continue
+		}
+		lang := strings.ToLower(strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name())))
+		byKey := make(map[string][]string, len(texts))
+		for key, values := range texts {
+			byKey[strings.ToUpper(key)] = values
+		}
+		store[lang] = byKey
+	}
+	return store
+}
+
+// getLocales 获取已加载的界面文本字典（首次调用时加载）
+func getLocales() map[string]map[string][]string {
+	localeMutex.RLock()
+	if localeLoaded {
+		defer localeMutex.RUnlock()
+		return localeStore
+	}
+	localeMutex.RUnlock()
+
+	localeMutex.Lock()
+	defer localeMutex.Unlock()
+	if !localeLoaded {
+		localeStore = loadLocales()
+		localeLoaded = true
+	}
+	return localeStore
+}
+
+// localeChain 获取界面语言回退链
+// @description: 指定界面语言时为 [界面语言, 回退语言...]；未指定时为全部已加载语言（默认语言优先）
+// @return: []string
+func localeChain(locales map[string]map[string][]string) []string {
+	language := strings.ToLower(global.ScumConfig.UILanguage)
+	if language == "" || language == "auto" {
+		chain := make([]string, 0, len(locales))
+		for lang := range locales {
+			if lang != _const.DefaultUILanguage {
+				chain = append(chain, lang)
+			}
+		}
+		sort.Strings(chain)
+		return append([]string{_const.DefaultUILanguage}, chain...)
+	}
+
+	chain := []string{language}
+	fallbacks := global.ScumConfig.UIFallbackLanguages
+	if len(fallbacks) == 0 {
+		fallbacks = []string{_const.DefaultUILanguage}
+	}
+	for _, lang := range fallbacks {
+		chain = append(chain, strings.ToLower(lang))
+	}
+	return chain
+}
+
+// normalizeUIText 规范化界面文本以便比较
+// @description: 全角字符转半角、转大写、去除所有空白
+// @param: text string
+// @return: string
+func normalizeUIText(text string) string {
+	var builder strings.Builder
+	builder.Grow(len(text))
+	for _, r := range text {
+		switch {
+		case r == '　':
+			continue
+		case r >= '！' && r <= '～':
+			r -= 0xFEE0
+		}
+		if unicode.IsSpace(r) {
+			continue
+		}
+		builder.WriteRune(unicode.ToUpper(r))
+	}
+	return builder.String()
+}
+
+// editDistance 计算两个字符串的编辑距离（按字符）
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// maxEditDistance 根据期望文本长度计算允许的编辑距离
+// @description: 匹配策略未指定编辑距离时使用；短文本必须精确匹配，避免 LOCAL/GLOBAL 之类的误匹配
+func maxEditDistance(normalized string) int {
+	switch n := len([]rune(normalized)); {
+	case n <= 3:
+		return 0
+	case n <= 6:
+		return 1
+	default:
+		return 2
+	}
+}
@@ -0,0 +1,120 @@
+package util
+
+import (
+	"fmt"
+	"qq_client/global"
+	"qq_client/model/request"
+	"strings"
+)
+
+// resolveMatchPolicy 获取文本key的匹配策略
+// @description: 优先级：配置中的同名策略 > 内置同名策略 > 配置中的 default 策略 > 内置默认策略
+// @param: textKey string 文本key
+// @return: global.UIMatchPolicy
+func resolveMatchPolicy(textKey string) global.UIMatchPolicy {
+	key := strings.ToUpper(textKey)
+	for name, policy := range global.ScumConfig.MatchPolicies {
+		if strings.ToUpper(name) == key {
+			return policy
+		}
+	}
+	if policy, ok := global.DefaultUIMatchPolicies[key]; ok {
+		return policy
+	}
+	for name, policy := range global.ScumConfig.MatchPolicies {
+		if strings.ToLower(name) == "default" {
+			return policy
+		}
+	}
+	return global.DefaultUIMatchPolicy
+}
+
+// checkBoxSize 检查文本区域大小是否在策略允许范围内
+// @return: string 不满足时的原因，满足时为空
+func checkBoxSize(policy global.UIMatchPolicy, width, height int) string {
+	switch {
+	case policy.MinWidth > 0 && width < policy.MinWidth:
+		return fmt.Sprintf("区域宽度 %d 小于 %d", width, policy.MinWidth)
+	case policy.MaxWidth > 0 && width > policy.MaxWidth:
+		return fmt.Sprintf("区域宽度 %d 大于 %d", width, policy.MaxWidth)
+	case policy.MinHeight > 0 && height < policy.MinHeight:
+		return fmt.Sprintf("区域高度 %d 小于 %d", height, policy.MinHeight)
+	case policy.MaxHeight > 0 && height > policy.MaxHeight:
+		return fmt.Sprintf("区域高度 %d 大于 %d", height, policy.MaxHeight)
+	}
+	return ""
+}
+
+// matchOcrItem 按匹配策略判断OCR识别结果是否为目标文本
+// @description: 依次检查置信度、文本区域大小、文本长度和编辑距离，每次判定都会输出日志及原因
+// @param: item request.OcrItem OCR识别结果
+// @param: textKey string 文本key（如 "MUTE"）
+// @return: string 匹配到的语言版本, bool 是否匹配
+func matchOcrItem(item request.OcrItem, textKey string) (string, bool) {
+	variant, reason, ok := evaluateOcrItem(item, textKey)
+	if ok {
+		fmt.Printf("[MATCH] 接受 '%s' <- '%s' (置信度: %.2f): %s\n", textKey, item.Text, item.Confidence, reason)
+	} else {
+		fmt.Printf("[MATCH] 拒绝 '%s' <- '%s' (置信度: %.2f): %s\n", textKey, item.Text, item.Confidence, reason)
+	}
+	return variant, ok
+}
+
+// evaluateOcrItem 计算匹配结果及原因
+func evaluateOcrItem(item request.OcrItem, textKey string) (string, string, bool) {
+	policy := resolveMatchPolicy(textKey)
+
+	if item.Confidence < policy.MinConfidence {
+		return "", fmt.Sprintf("置信度低于 %.2f", policy.MinConfidence), false
+	}
+
+	if x1, y1, x2, y2, ok := ocrItemRect(item); ok {
+		if reason := checkBoxSize(policy, x2-x1, y2-y1); reason != "" {
+			return "", reason, false
+		}
+	} else if policy.MinWidth > 0 || policy.MaxWidth > 0 || policy.MinHeight > 0 || policy.MaxHeight > 0 {
+		return "", "缺少文本区域坐标", false
+	}
+
+	// 识别文本整体及其中每个单词都作为候选
+	candidates := []string{normalizeUIText(item.Text)}
+	if words := strings.Fields(item.Text); len(words) > 1 {
+		for _, word := range words {
+			candidates = append(candidates, normalizeUIText(word))
+		}
+	}
+
+	bestDistance, bestVariant, bestCandidate := -1, "", ""
+	tooShort := true
+	for _, variant := range getMultilingualTexts(textKey) {
+		expected := normalizeUIText(variant)
+		if expected == "" {
+			continue
+		}
+		limit := maxEditDistance(expected)
+		if policy.MaxEditDistance != nil {
+			limit = *policy.MaxEditDistance
+		}
+		for _, candidate := range candidates {
+			if len([]rune(candidate)) < policy.MinLength {
+				continue
+			}
+			tooShort = false
+			distance := editDistance(candidate, expected)
+			if distance <= limit {
+				return variant, fmt.Sprintf("'%s' 与 '%s' 编辑距离 %d (允许 %d)", candidate, expected, distance, limit), true
+			}
+			if bestDistance < 0 || distance < bestDistance {
+				bestDistance, bestVariant, bestCandidate = distance, expected, candidate
+			}
+		}
+	}
+
+	if tooShort {
+		return "", fmt.Sprintf("文本长度小于 %d", policy.MinLength), false
+	}
+	if bestDistance < 0 {
+		return "", "没有可比较的界面文本", false
+	}
+	return "", fmt.Sprintf("最接近 '%s' 与 '%s' 编辑距离 %d，超出允许范围", bestCandidate, bestVariant, bestDistance), false
+}
@@ -0,0 +1,314 @@
+package util
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"qq_client/global"
+	_const "qq_client/internal/const"
+	"qq_client/model/request"
+	"strings"
+	"sync"
+)
+
+// OCRPriority OCR 请求优先级，数值越小越优先
+type OCRPriority int
+
+const (
+	OCRPriorityCritical   OCRPriority = iota // 状态判断相关（MUTE/CONTINUE）
+	OCRPriorityNormal                        // 普通识别
+	OCRPriorityBackground                    // 后台识别（诊断等）
+	ocrPriorityCount
+)
+
+// ErrOCRStale 同一请求流已有更新的帧，旧请求被取消
+var ErrOCRStale = errors.New("OCR 请求已过期（已有更新的帧）")
+
+// criticalOCRTextKeys 影响状态机判断的文本key，使用最高优先级
+var criticalOCRTextKeys = map[string]bool{
+	"MUTE":     true,
+	"CONTINUE": true,
+}
+
+// OCRCall 一次 OCR 请求
+type OCRCall struct {
+	Stream   string            // 请求流标识（同一区域的连续帧），用于合并重复请求和取消过期请求
+	Priority OCRPriority       // 优先级
+	Image    []byte            // PNG 图片数据
+	Options  global.OCRRequest // 目标文字、裁剪区域、语言提示等（Image 字段由发送时填充）
+}
+
+// ocrJob 调度器中的一个识别任务，相同请求流和相同图片的调用共享同一个任务
+type ocrJob struct {
+	key      string
+	call     OCRCall
+	ctx      context.Context
+	cancel   context.CancelFunc
+	waiters  int
+	started  bool
+	finished bool
+	stale    bool
+	done     chan struct{}
+	result   *request.OcrResult
+	err      error
+}
+
+// ocrScheduler OCR 请求调度器：固定数量的工作协程按优先级处理请求
+type ocrScheduler struct {
+	mutex   sync.Mutex
+	cond    *sync.Cond
+	queues  [ocrPriorityCount][]*ocrJob
+	jobs    map[string]*ocrJob // 任务key -> 未完成的任务
+	latest  map[string]*ocrJob // 请求流 -> 最新的任务
+	workers int                // 工作协程数量
+	started bool
+}
+
+var defaultOCRScheduler = newOCRScheduler()
+
+// newOCRScheduler 创建调度器（工作协程在首次提交请求时启动）
+func newOCRScheduler() *ocrScheduler {
+	s := &ocrScheduler{
+		jobs:    make(map[string]*ocrJob),
+		latest:  make(map[string]*ocrJob),
+		workers: _const.OCRWorkerCount,
+	}
+	s.cond = sync.NewCond(&s.mutex)
+	return s
+}
+
+// ocrPriorityForText 根据文本key确定请求优先级
+func ocrPriorityForText(textKey string) OCRPriority {
+	if criticalOCRTextKeys[strings.ToUpper(textKey)] {
+		return OCRPriorityCritical
+	}
+	return OCRPriorityNormal
+}
+
+// ocrMatchOptions 根据文本key的匹配策略生成服务端匹配参数
+// @description: 服务端按这些参数粗筛，客户端仍用 matchOcrItem 做最终判定（文本区域大小、最小长度等）
+// @param: textKey string 文本key（如 "MUTE"）
+// @return: global.OCRRequest
+func ocrMatchOptions(textKey string) global.OCRRequest {
+	policy := resolveMatchPolicy(textKey)
+	variants := getMultilingualTexts(textKey)
+	distance := 0
+	for _, variant := range variants {
+		limit := maxEditDistance(normalizeUIText(variant))
+		if policy.MaxEditDistance != nil {
+			limit = *policy.MaxEditDistance
+		}
+		distance = max(distance, limit)
+	}
+	return global.OCRRequest{
+		TargetText:      variants,
+		Lang:            global.ScumConfig.OCRLanguage,
+		MinConfidence:   policy.MinConfidence,
+		MaxEditDistance: distance,
+		Preprocess:      policy.Preprocess,
+	}
+}
+
+// ocrTimeoutFor 根据优先级确定单次调用的超时时间
+func ocrTimeoutFor(priority OCRPriority) (context.Context, context.CancelFunc) {
+	if priority == OCRPriorityCritical {
+		return context.WithTimeout(context.Background(), _const.OCRCriticalTimeout)
+	}
+	return context.WithTimeout(context.Background(), _const.OCRServiceAPITimeout)
+}
+
+// RecognizeOCR 提交 OCR 请求并等待结果
+// @description: 相同请求流、相同图片的并发调用合并为一次请求；同一请求流提交新帧时取消旧帧的请求
+// @param: ctx context.Context 调用方的截止时间
+// @param: call OCRCall 请求内容
+// @return: *request.OcrResult, error
+func RecognizeOCR(ctx context.Context, call OCRCall) (*request.OcrResult, error) {
+	return defaultOCRScheduler.submit(ctx, call)
+}
+
+// submit 提交请求并等待结果
+func (s *ocrScheduler) submit(ctx context.Context, call OCRCall) (*request.OcrResult, error) {
+	if call.Priority < 0 || call.Priority >= ocrPriorityCount {
+		call.Priority = OCRPriorityNormal
+	}
+	// 图片和识别参数都相同的请求才能合并
+	hash := sha256.New()
+	hash.Write(call.Image)
+	if options, err := json.Marshal(call.Options); err == nil {
+		hash.Write(options)
+	}
+	key := call.Stream + "|" + hex.EncodeToString(hash.Sum(nil)[:8])
+
+	s.mutex.Lock()
+	if !s.started {
+		s.started = true
+		for i := 0; i < s.workers; i++ {
+			go s.worker()
+		}
+	}
+
+	job, exists := s.jobs[key]
+	if exists {
+		// 合并到正在进行的相同请求
+		job.waiters++
+		// 更高优先级的调用方加入时，提升未开始任务的优先级
+		if !job.started && call.Priority < job.call.Priority {
+			s.removeQueuedLocked(job)
+			job.call.Priority = call.Priority
+			s.queues[job.call.Priority] = append(s.queues[job.call.Priority], job)
+		}
+	} else {
+		// 同一请求流已有更新的帧，取消旧帧的请求
+		if previous := s.latest[call.Stream]; previous != nil && !previous.finished {
+			previous.stale = true
+			previous.cancel()
+			if !previous.started {
+				s.removeQueuedLocked(previous)
+				s.finishLocked(previous, nil, ErrOCRStale)
+			}
+		}
+
+		jobCtx, cancel := context.WithTimeout(context.Background(), _const.OCRServiceAPITimeout)
+		job = &ocrJob{
+			key:     key,
+			call:    call,
+			ctx:     jobCtx,
+			cancel:  cancel,
+			waiters: 1,
+			done:    make(chan struct{}),
+		}
+		s.jobs[key] = job
+		s.latest[call.Stream] = job
+		s.queues[call.Priority] = append(s.queues[call.Priority], job)
+		s.cond.Signal()
+	}
+	s.mutex.Unlock()
+
+	select {
+	case <-job.done:
+		return job.result, job.err
+	case <-ctx.Done():
+		s.mutex.Lock()
+		job.waiters--
+		if job.waiters == 0 && !job.finished {
+			// 没有调用方在等待，放弃该任务
+			job.cancel()
+			if !job.started {
+				s.removeQueuedLocked(job)
+				s.finishLocked(job, nil, ctx.Err())
+			}
+		}
+		s.mutex.Unlock()
+		return nil, fmt.Errorf("OCR 请求超时或已取消: %v", ctx.Err())
+	}
+}
+
+// removeQueuedLocked 从等待队列中移除任务（需持有锁）
+func (s *ocrScheduler) removeQueuedLocked(job *ocrJob) {
+	queue := s.queues[job.call.Priority]
+	for i, queued := range queue {
+		if queued == job {
+			s.queues[job.call.Priority] = append(queue[:i], queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// finishLocked 完成任务并通知所有等待方（需持有锁）
+func (s *ocrScheduler) finishLocked(job *ocrJob, result *request.OcrResult, err error) {
+	if job.finished {
+		return
+	}
+	job.finished = true
+	job.result, job.err = result, err
+	job.cancel()
+	if s.jobs[job.key] == job {
+		delete(s.jobs, job.key)
+	}
+	if s.latest[job.call.Stream] == job {
+		delete(s.latest, job.call.Stream)
+	}
+	close(job.done)
+}
+
+// nextLocked 取出优先级最高的任务，没有任务时等待（需持有锁）
+func (s *ocrScheduler) nextLocked() *ocrJob {
+	for {
+		for priority := range s.queues {
+			if len(s.queues[priority]) > 0 {
+				job := s.queues[priority][0]
+				s.queues[priority] = s.queues[priority][1:]
+				return job
+			}
+		}
+		s.cond.Wait()
+	}
+}
+
+// worker 工作协程：按优先级依次处理任务
+func (s *ocrScheduler) worker() {
+	for {
+		s.mutex.Lock()
+		job := s.nextLocked()
+		if job.finished {
+			s.mutex.Unlock()
+			continue
+		}
+		job.started = true
+		s.mutex.Unlock()
+
+		result, err := postOCRRequest(job.ctx, job.call)
+
+		s.mutex.Lock()
+		if job.stale {
+			result, err = nil, ErrOCRStale
+		}
+		s.finishLocked(job, result, err)
+		s.mutex.Unlock()
+	}
+}
+
+// postOCRRequest 向 PaddleOCR 服务发送识别请求
+// @param: ctx context.Context 请求的截止时间
+// @param: call OCRCall 请求内容
+// @return: *request.OcrResult, error
+func postOCRRequest(ctx context.Context, call OCRCall) (*request.OcrResult, error) {
+	// 将请求数据转换为JSON
+	payload := call.Options
+	payload.Image = base64.StdEncoding.EncodeToString(call.Image)
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("序列化请求失败: %v", err)
+	}
+
+	ocrAPIURL := fmt.Sprintf("http://%s:%d/api/ocr", global.OCRServiceHost, global.OCRServicePort)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ocrAPIURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("创建请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("发送请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// 读取并解析响应
+	responseData, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("读取响应失败: %v", err)
+	}
+	var ocrResult request.OcrResult
+	if err = json.Unmarshal(responseData, &ocrResult); err != nil {
+		return nil, fmt.Errorf("解析响应JSON失败: %v", err)
+	}
+	return &ocrResult, nil
+}
@@ -0,0 +1,295 @@
+package util
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"qq_client/global"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+const schedulerWaitTimeout = 5 * time.Second
+
+// fakeOCRServer 记录请求顺序的 OCR 服务，target_text 为 "block" 的请求在 release 关闭前不返回
+type fakeOCRServer struct {
+	mutex    sync.Mutex
+	order    []string
+	received chan string
+	release  chan struct{}
+}
+
+// newFakeOCRServer 启动测试服务并将 OCR 服务地址指向它
+func newFakeOCRServer(t *testing.T) *fakeOCRServer {
+	t.Helper()
+	fake := &fakeOCRServer{received: make(chan string, 64), release: make(chan struct{})}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload global.OCRRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || len(payload.TargetText) == 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		name := payload.TargetText[0]
+		fake.mutex.Lock()
+		fake.order = append(fake.order, name)
+		fake.mutex.Unlock()
+		fake.received <- name
+		if name == "block" {
+			select {
+			case <-fake.release:
+			case <-r.Context().Done():
+				return
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"code": 100, "data": name})
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() {
+		select {
+		case <-fake.release:
+		default:
+			close(fake.release)
+		}
+	})
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	previousHost, previousPort := global.OCRServiceHost, global.OCRServicePort
+	global.OCRServiceHost = host
+	global.OCRServicePort, _ = strconv.Atoi(port)
+	t.Cleanup(func() { global.OCRServiceHost, global.OCRServicePort = previousHost, previousPort })
+	return fake
+}
+
+// waitReceived 等待服务端收到名为 name 的请求
+func (f *fakeOCRServer) waitReceived(t *testing.T, name string) {
+	t.Helper()
+	for {
+		select {
+		case got := <-f.received:
+			if got == name {
+				return
+			}
+		case <-time.After(schedulerWaitTimeout):
+			t.Fatalf("OCR server did not receive %q", name)
+		}
+	}
+}
+
+// snapshot 服务端收到请求的顺序
+func (f *fakeOCRServer) snapshot() []string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	return append([]string(nil), f.order...)
+}
+
+// ocrCall 测试用的请求，name 同时作为目标文字，服务端按它识别请求
+func ocrCall(stream, name string, priority OCRPriority, image string) OCRCall {
+	return OCRCall{
+		Stream:   stream,
+		Priority: priority,
+		Image:    []byte(image),
+		Options:  global.OCRRequest{TargetText: []string{name}},
+	}
+}
+
+// ocrOutcome 一次 submit 的结果
+type ocrOutcome struct {
+	data interface{}
+	err  error
+}
+
+// submitAsync 在后台提交请求
+func submitAsync(s *ocrScheduler, call OCRCall) chan ocrOutcome {
+	outcome := make(chan ocrOutcome, 1)
+	go func() {
+		result, err := s.submit(context.Background(), call)
+		if err != nil {
+			outcome <- ocrOutcome{err: err}
+			return
+		}
+		outcome <- ocrOutcome{data: result.Data}
+	}()
+	return outcome
+}
+
+// waitOutcome 等待 submit 返回
+func waitOutcome(t *testing.T, outcome chan ocrOutcome) ocrOutcome {
+	t.Helper()
+	select {
+	case o := <-outcome:
+		return o
+	case <-time.After(schedulerWaitTimeout):
+		t.Fatal("submit did not return")
+		return ocrOutcome{}
+	}
+}
+
+// waitQueued 等待调度器中排队的任务数达到 n
+func waitQueued(t *testing.T, s *ocrScheduler, n int) {
+	t.Helper()
+	deadline := time.Now().Add(schedulerWaitTimeout)
+	for time.Now().Before(deadline) {
+		s.mutex.Lock()
+		queued := 0
+		for _, queue := range s.queues {
+			queued += len(queue)
+		}
+		s.mutex.Unlock()
+		if queued >= n {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatalf("scheduler did not queue %d jobs", n)
+}
+
+// blockWorker 使用单个工作协程的调度器，并让工作协程阻塞在一个请求上
+func blockWorker(t *testing.T, fake *fakeOCRServer) (*ocrScheduler, chan ocrOutcome) {
+	t.Helper()
+	s := newOCRScheduler()
+	s.workers = 1
+	blocked := submitAsync(s, ocrCall("block", "block", OCRPriorityBackground, "block"))
+	fake.waitReceived(t, "block")
+	return s, blocked
+}
+
+// TestSchedulerPriorityOrder 工作协程空闲后按优先级处理排队的请求，同优先级按提交顺序
+func TestSchedulerPriorityOrder(t *testing.T) {
+	fake := newFakeOCRServer(t)
+	s, blocked := blockWorker(t, fake)
+
+	calls := []OCRCall{
+		ocrCall("diagnostics", "background", OCRPriorityBackground, "1"),
+		ocrCall("chat", "normal-1", OCRPriorityNormal, "2"),
+		ocrCall("verify:MUTE", "critical", OCRPriorityCritical, "3"),
+		ocrCall("verify:GLOBAL", "normal-2", OCRPriorityNormal, "4"),
+	}
+	var outcomes []chan ocrOutcome
+	for i, call := range calls {
+		outcomes = append(outcomes, submitAsync(s, call))
+		waitQueued(t, s, i+1)
+	}
+	close(fake.release)
+
+	if o := waitOutcome(t, blocked); o.err != nil {
+		t.Fatal(o.err)
+	}
+	for _, outcome := range outcomes {
+		if o := waitOutcome(t, outcome); o.err != nil {
+			t.Fatal(o.err)
+		}
+	}
+	want := []string{"block", "critical", "normal-1", "normal-2", "background"}
+	got := fake.snapshot()
+	if len(got) != len(want) {
+		t.Fatalf("request order = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("request order = %v, want %v", got, want)
+		}
+	}
+}
+
+// TestSchedulerCoalescesIdenticalCalls 相同请求流、相同图片和参数的并发调用只发送一次请求，并提升到最高调用方的优先级
+func TestSchedulerCoalescesIdenticalCalls(t *testing.T) {
+	fake := newFakeOCRServer(t)
+	s, blocked := blockWorker(t, fake)
+
+	var outcomes []chan ocrOutcome
+	for _, priority := range []OCRPriority{OCRPriorityBackground, OCRPriorityNormal, OCRPriorityCritical} {
+		outcomes = append(outcomes, submitAsync(s, ocrCall("verify:MUTE", "shared", priority, "frame")))
+	}
+	outcomes = append(outcomes, submitAsync(s, ocrCall("chat", "other", OCRPriorityNormal, "frame")))
+	deadline := time.Now().Add(schedulerWaitTimeout)
+	for {
+		s.mutex.Lock()
+		waiters := 0
+		for _, job := range s.jobs {
+			if job.call.Stream == "verify:MUTE" {
+				waiters = job.waiters
+			}
+		}
+		jobs := len(s.jobs)
+		s.mutex.Unlock()
+		if waiters == 3 && jobs == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("calls were not coalesced: %d waiters, %d jobs", waiters, jobs)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(fake.release)
+
+	if o := waitOutcome(t, blocked); o.err != nil {
+		t.Fatal(o.err)
+	}
+	for i, outcome := range outcomes[:3] {
+		o := waitOutcome(t, outcome)
+		if o.err != nil || o.data != "shared" {
+			t.Fatalf("call %d = (%v, %v), want the shared result", i, o.data, o.err)
+		}
+	}
+	if o := waitOutcome(t, outcomes[3]); o.err != nil || o.data != "other" {
+		t.Fatalf("other stream = (%v, %v)", o.data, o.err)
+	}
+	// 合并的请求被提升为最高优先级，先于同一时间排队的普通请求
+	got := fake.snapshot()
+	want := []string{"block", "shared", "other"}
+	if len(got) != len(want) || got[1] != "shared" || got[2] != "other" {
+		t.Fatalf("requests = %v, want %v", got, want)
+	}
+}
+
+// TestSchedulerCancelsQueuedStaleFrame 同一请求流提交新帧时，排队中的旧帧立即返回 ErrOCRStale 且不发送
+func TestSchedulerCancelsQueuedStaleFrame(t *testing.T) {
+	fake := newFakeOCRServer(t)
+	s, blocked := blockWorker(t, fake)
+
+	old := submitAsync(s, ocrCall("verify:MUTE", "old", OCRPriorityCritical, "frame-1"))
+	waitQueued(t, s, 1)
+	latest := submitAsync(s, ocrCall("verify:MUTE", "new", OCRPriorityCritical, "frame-2"))
+
+	if o := waitOutcome(t, old); !errors.Is(o.err, ErrOCRStale) {
+		t.Fatalf("old frame err = %v, want ErrOCRStale", o.err)
+	}
+	close(fake.release)
+	if o := waitOutcome(t, blocked); o.err != nil {
+		t.Fatal(o.err)
+	}
+	if o := waitOutcome(t, latest); o.err != nil || o.data != "new" {
+		t.Fatalf("new frame = (%v, %v)", o.data, o.err)
+	}
+	for _, name := range fake.snapshot() {
+		if name == "old" {
+			t.Fatal("stale frame was sent to the OCR server")
+		}
+	}
+}
+
+// TestSchedulerCancelsRunningStaleFrame 同一请求流提交新帧时，正在识别的旧帧被取消并返回 ErrOCRStale
+func TestSchedulerCancelsRunningStaleFrame(t *testing.T) {
+	fake := newFakeOCRServer(t)
+	s := newOCRScheduler()
+	s.workers = 1
+
+	old := submitAsync(s, ocrCall("verify:MUTE", "block", OCRPriorityCritical, "frame-1"))
+	fake.waitReceived(t, "block")
+	latest := submitAsync(s, ocrCall("verify:MUTE", "new", OCRPriorityCritical, "frame-2"))
+
+	if o := waitOutcome(t, old); !errors.Is(o.err, ErrOCRStale) {
+		t.Fatalf("running old frame err = %v, want ErrOCRStale", o.err)
+	}
+	if o := waitOutcome(t, latest); o.err != nil || o.data != "new" {
+		t.Fatalf("new frame = (%v, %v)", o.data, o.err)
+	}
+}
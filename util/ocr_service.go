@@ -9,31 +9,36 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"qq_client/assets"
 	"qq_client/global"
 	_const "qq_client/internal/const"
+	"qq_client/internal/ocrenv"
 	"runtime"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
 
-var ocrProcess *exec.Cmd
 var ocrServiceRunning = false
 
-const (
-	// 国内外下载源
-	embeddedPythonURLCN   = "https://scum.npc0.com/python-3.12.10-embed-amd64.zip"
-	getPipURLCN           = "https://scum.npc0.com/get-pip.py"
-	embeddedPythonURLOff  = "https://www.python.org/ftp/python/3.12.10/python-3.12.10-embed-amd64.zip"
-	getPipURLOff          = "https://bootstrap.pypa.io/get-pip.py"
-	pypiTsinghuaIndex     = "https://pypi.tuna.tsinghua.edu.cn/simple"
-	pypiTsinghuaHost      = "pypi.tuna.tsinghua.edu.cn"
-	regionEnvKey          = "SCUM_REGION" // CN / INTL
-	regionChinaFlagEnvKey = "SCUM_CN"     // 1/true/yes 表示中国区
-
-	embedDir = "py_embed"
+// 由本程序启动的 OCR 进程，ocrProcessMutex 保护以下变量（监控协程与启动、停止服务并发访问）
+var (
+	ocrProcessMutex sync.Mutex
+	ocrProcess      *exec.Cmd
+	// ocrProcessExited 当前 OCR 进程退出时关闭（由唯一的回收 goroutine 负责 Wait）
+	ocrProcessExited  chan struct{}
+	ocrProcessExitErr error
+	// ocrProcessGeneration 每启动一个进程加一；ocrStoppedGeneration 为 StopOCRService 主动停止的最后一代，
+	// 监控据此区分主动停止和意外退出
+	ocrProcessGeneration uint64
+	ocrStoppedGeneration uint64
 )
 
+// embedDir 内置 Python 目录
+const embedDir = "py_embed"
+
 func ensureDir(dir string) error {
 	if dir == "" {
 		return nil
@@ -49,76 +54,71 @@ func fileExists(p string) bool {
 	return err == nil
 }
 
-// fastReachable 尝试快速访问指定 URL，判断可达
-func fastReachable(url string, timeout time.Duration) bool {
-	client := &http.Client{Timeout: timeout}
-	req, err := http.NewRequest("HEAD", url, nil)
-	if err != nil {
-		return false
-	}
-	resp, err := client.Do(req)
-	if err != nil {
-		return false
-	}
-	_ = resp.Body.Close()
-	return resp.StatusCode >= 200 && resp.StatusCode < 500
+// ocrLocalDir 本地离线安装目录（绝对路径），不存在时返回空
+// 本地离线目录不存在但有离线安装包时先解压安装包
+func ocrLocalDir() string {
+	return ocrenv.LocalDir(_const.OCRWheelhouseDir, _const.OCRBundleFile)
 }
 
-// shouldUseChinaMirror 根据环境变量/时区/网络探测选择是否使用国内镜像
-func shouldUseChinaMirror() bool {
-	// 环境变量强制
-	if strings.EqualFold(os.Getenv(regionEnvKey), "CN") {
-		return true
-	}
-	if strings.EqualFold(os.Getenv(regionEnvKey), "INTL") {
-		return false
-	}
-	s := strings.ToLower(os.Getenv(regionChinaFlagEnvKey))
-	if s == "1" || s == "true" || s == "yes" {
-		return true
-	}
-
-	// 时区为 +8 作为弱指示
-	_, offset := time.Now().Zone()
-	if offset == 8*3600 {
-		return true
-	}
+// loadOCRManifest 读取 OCR 环境清单
+// 本地离线目录中有清单时（离线安装包自带，哈希已固定）优先使用
+func loadOCRManifest(localDir string) (*ocrenv.Manifest, error) {
+	return ocrenv.LoadLocalManifest(localDir, _const.OCRManifestFile)
+}
 
-	// 网络探测：清华源是否可达
-	if fastReachable(pypiTsinghuaIndex, 1500*time.Millisecond) {
-		return true
+// isRemoteOCRService 是否使用其他主机上的 OCR 服务（配置了非本机的 ocr_host）
+// 远程服务由对方主机管理，本机不安装环境也不启动进程
+func isRemoteOCRService() bool {
+	host := global.OCRServiceHost
+	if host == "localhost" {
+		return false
 	}
-	return false
+	ip := net.ParseIP(host)
+	return ip == nil || !ip.IsLoopback()
 }
 
-// selectDownloadSources 选择下载源与镜像策略
-func selectDownloadSources() (embeddedURL, getPipURL string, useChina bool) {
-	useChina = shouldUseChinaMirror()
-	if useChina {
-		return embeddedPythonURLCN, getPipURLCN, true
+// waitRemoteOCRService 等待远程 OCR 服务健康检查通过
+func waitRemoteOCRService() error {
+	fmt.Printf("使用远程 OCR 服务: %s:%d\n", global.OCRServiceHost, global.OCRServicePort)
+	maxWait := int(_const.OCRServiceMaxWaitTime / time.Second)
+	for i := 0; i < maxWait; i++ {
+		if IsOCRServiceRunning() {
+			ocrServiceRunning = true
+			return nil
+		}
+		fmt.Printf("等待远程 OCR 服务... (%d/%d)\n", i+1, maxWait)
+		time.Sleep(_const.ShortWaitTime)
 	}
-	return embeddedPythonURLOff, getPipURLOff, false
+	return fmt.Errorf("远程 OCR 服务 %s:%d 不可用，请检查对方主机上的服务和防火墙", global.OCRServiceHost, global.OCRServicePort)
 }
 
-func downloadFile(url, dest string) error {
-	resp, err := http.Get(url)
-	if err != nil {
-		return err
+// installOfflineModels 从本地离线目录安装识别模型（已存在的模型跳过）
+// 联网环境由 PaddleOCR 首次运行时自行下载，不需要安装
+func installOfflineModels() {
+	localDir := ocrLocalDir()
+	if localDir == "" {
+		return
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("下载失败: %s -> http %d", url, resp.StatusCode)
+	manifest, err := loadOCRManifest(localDir)
+	if err != nil {
+		fmt.Printf("[WARN] 读取 OCR 环境清单失败: %v\n", err)
+		return
 	}
-	if err := ensureDir(filepath.Dir(dest)); err != nil {
-		return err
+	if err = manifest.InstallModels(localDir, localDir); err != nil {
+		fmt.Printf("[WARN] 安装离线模型失败，OCR 服务首次运行时将尝试联网下载: %v\n", err)
 	}
-	out, err := os.Create(dest)
-	if err != nil {
-		return err
+}
+
+// runPython 运行 Python 命令（Windows 下隐藏窗口），输出到控制台
+func runPython(python, dir string, args ...string) error {
+	cmd := exec.Command(python, args...)
+	cmd.Dir = dir
+	if runtime.GOOS == "windows" {
+		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
 	}
-	defer out.Close()
-	_, err = io.Copy(out, resp.Body)
-	return err
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
 }
 
 func unzip(srcZip, destDir string) error {
@@ -201,23 +201,34 @@ func enableImportSite(pthPath string) error {
 	return nil
 }
 
+// ensureEmbeddedPython 按清单准备内置 Python 环境
+// @description: 所有下载文件按清单中的 SHA-256 校验，依赖按锁文件安装（--require-hashes）；
+// 存在本地离线目录（wheelhouse）时只从该目录安装，不访问网络
+// @return: string python.exe 绝对路径, error
 func ensureEmbeddedPython() (string, error) {
 	if runtime.GOOS != "windows" {
 		return "", fmt.Errorf("仅 Windows 支持内置 Python")
 	}
 
-	// 选择下载源
-	embeddedURL, getPipURL, useChina := selectDownloadSources()
-
 	pythonExe := filepath.Join(embedDir, "python.exe")
 	if _, err := os.Stat(pythonExe); err == nil {
 		abs, _ := filepath.Abs(pythonExe)
 		return abs, nil
 	}
-	fmt.Println("未检测到内置 Python，开始自动下载并解压...")
-	zipPath := filepath.Join(embedDir, "python-embed.zip")
-	if err := downloadFile(embeddedURL, zipPath); err != nil {
-		return "", fmt.Errorf("下载 Python 失败: %v", err)
+
+	localDir := ocrLocalDir()
+	manifest, err := loadOCRManifest(localDir)
+	if err != nil {
+		return "", err
+	}
+	if localDir != "" {
+		fmt.Printf("检测到本地离线目录 %s，将不访问网络\n", localDir)
+	}
+
+	fmt.Println("未检测到内置 Python，开始准备内置 Python 环境...")
+	zipPath, err := ocrenv.Fetch(manifest.Python, embedDir, localDir, manifest.RequireHashes)
+	if err != nil {
+		return "", fmt.Errorf("获取 Python 失败: %v", err)
 	}
 	if err := unzip(zipPath, embedDir); err != nil {
 		return "", fmt.Errorf("解压 Python 失败: %v", err)
@@ -257,62 +268,37 @@ func ensureEmbeddedPython() (string, error) {
 	absPython, _ := filepath.Abs(pythonExe)
 	fmt.Printf("使用内置 Python: %s\n", absPython)
 
-	// 下载 get-pip.py
-	getPipPath := filepath.Join(embedDir, "get-pip.py")
-	if err := downloadFile(getPipURL, getPipPath); err != nil {
-		return "", fmt.Errorf("下载 get-pip.py 失败: %v", err)
+	// 获取并运行 get-pip.py（使用绝对路径）
+	getPipPath, err := ocrenv.Fetch(manifest.GetPip, embedDir, localDir, manifest.RequireHashes)
+	if err != nil {
+		return "", fmt.Errorf("获取 get-pip.py 失败: %v", err)
 	}
-
-	// 安装 pip（使用绝对路径）
-	cmd := exec.Command(absPython, "get-pip.py", "--no-warn-script-location")
-	cmd.Dir = embedDir
-	if runtime.GOOS == "windows" {
-		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	getPipPath, _ = filepath.Abs(getPipPath)
+	getPipArgs := []string{getPipPath, "--no-warn-script-location"}
+	if localDir != "" {
+		getPipArgs = append(getPipArgs, "--no-index", "--find-links", localDir)
 	}
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
+	if err := runPython(absPython, embedDir, getPipArgs...); err != nil {
 		return "", fmt.Errorf("安装 pip 失败: %v", err)
 	}
 
-	// 中国区：配置 pip 全局镜像为清华源
-	if useChina {
-		cfg1 := exec.Command(absPython, "-m", "pip", "config", "set", "global.index-url", pypiTsinghuaIndex)
-		cfg1.Dir = embedDir
-		if runtime.GOOS == "windows" {
-			cfg1.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-		}
-		cfg1.Stdout = os.Stdout
-		cfg1.Stderr = os.Stderr
-		_ = cfg1.Run()
-
-		cfg2 := exec.Command(absPython, "-m", "pip", "config", "set", "global.trusted-host", pypiTsinghuaHost)
-		cfg2.Dir = embedDir
-		if runtime.GOOS == "windows" {
-			cfg2.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-		}
-		cfg2.Stdout = os.Stdout
-		cfg2.Stderr = os.Stderr
-		_ = cfg2.Run()
+	// 安装依赖（按锁文件固定版本和哈希），依次尝试清单中的索引
+	reqFile, hashed, err := manifest.RequirementsFile("windows")
+	if err != nil {
+		return "", err
 	}
-
-	// 安装依赖
+	reqFile, _ = filepath.Abs(reqFile)
 	fmt.Println("正在安装 PaddlePaddle 及 PaddleOCR 依赖... (首次可能较慢)")
-	pipArgs := []string{"-m", "pip", "install", "--no-warn-script-location"}
-	if useChina {
-		pipArgs = append(pipArgs, "-i", pypiTsinghuaIndex, "--trusted-host", pypiTsinghuaHost)
-	}
-	// 指定 paddleocr 版本为 2.7.0.3，确保 API 兼容性
-	pipArgs = append(pipArgs, "setuptools", "paddlepaddle==3.0.0b2", "paddleocr==2.8.1", "flask", "requests", "pillow")
-
-	pipCmd := exec.Command(absPython, pipArgs...)
-	pipCmd.Dir = embedDir
-	if runtime.GOOS == "windows" {
-		pipCmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	for _, index := range manifest.IndexSources(localDir) {
+		if index != "" {
+			fmt.Printf("使用 pip 索引: %s\n", index)
+		}
+		if err = runPython(absPython, embedDir, ocrenv.PipInstallArgs(reqFile, hashed, localDir, index)...); err == nil {
+			break
+		}
+		fmt.Printf("安装依赖失败: %v\n", err)
 	}
-	pipCmd.Stdout = os.Stdout
-	pipCmd.Stderr = os.Stderr
-	if err := pipCmd.Run(); err != nil {
+	if err != nil {
 		return "", fmt.Errorf("安装依赖失败: %v", err)
 	}
 
@@ -328,6 +314,9 @@ func StartOCRService() error {
 		fmt.Println("OCR 服务已经在运行")
 		return nil
 	}
+	if isRemoteOCRService() {
+		return waitRemoteOCRService()
+	}
 
 	// 检查环境：若存在内置 Python 则视为已就绪，否则检查虚拟环境
 	if !(runtime.GOOS == "windows" && fileExists(filepath.Join(embedDir, "python.exe"))) {
@@ -372,9 +361,10 @@ func StartOCRService() error {
 	}
 
 	// 启动 OCR 服务，使用绝对路径
-	ocrProcess = exec.Command(pythonExe, ocrServerPath)
+	cmd := exec.Command(pythonExe, ocrServerPath,
+		"--host", global.OCRServiceHost, "--port", strconv.Itoa(global.OCRServicePort))
 	// 设置工作目录为当前目录，确保相对路径引用正确
-	ocrProcess.Dir = currentDir
+	cmd.Dir = currentDir
 
 	// 设置环境变量，确保能找到 python312.dll
 	if runtime.GOOS == "windows" {
@@ -389,8 +379,8 @@ func StartOCRService() error {
 		// 注意：不需要设置 PYTHONPATH，因为 _pth 文件已经配置了 Python 路径
 		// 如果使用的是嵌入式 Python，路径配置由 python312._pth 文件管理
 
-		ocrProcess.Env = env
-		ocrProcess.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+		cmd.Env = env
+		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
 	}
 
 	// 创建管道用于同时输出到控制台和日志文件
@@ -399,22 +389,43 @@ func StartOCRService() error {
 	if err != nil {
 		fmt.Printf("无法创建OCR服务日志文件: %v\n", err)
 		// 即使无法创建日志文件，也继续启动服务，只输出到控制台
-		ocrProcess.Stdout = os.Stdout
-		ocrProcess.Stderr = os.Stderr
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
 	} else {
 		// 使用 MultiWriter 同时输出到控制台和日志文件
 		multiOut := io.MultiWriter(os.Stdout, logFile)
 		multiErr := io.MultiWriter(os.Stderr, logFile)
-		ocrProcess.Stdout = multiOut
-		ocrProcess.Stderr = multiErr
-		defer logFile.Close()
+		cmd.Stdout = multiOut
+		cmd.Stderr = multiErr
 	}
 
-	err = ocrProcess.Start()
+	err = cmd.Start()
 	if err != nil {
+		if logFile != nil {
+			logFile.Close()
+		}
 		return fmt.Errorf("启动 OCR 服务失败: %v", err)
 	}
 
+	// 回收进程：日志文件需要在进程退出后关闭，否则输出管道写满后服务会阻塞
+	exited := make(chan struct{})
+	ocrProcessMutex.Lock()
+	ocrProcess = cmd
+	ocrProcessExited = exited
+	ocrProcessExitErr = nil
+	ocrProcessGeneration++
+	ocrProcessMutex.Unlock()
+	go func(cmd *exec.Cmd, logFile *os.File) {
+		err := cmd.Wait()
+		if logFile != nil {
+			logFile.Close()
+		}
+		ocrProcessMutex.Lock()
+		ocrProcessExitErr = err
+		ocrProcessMutex.Unlock()
+		close(exited)
+	}(cmd, logFile)
+
 	// 等待服务启动
 	fmt.Println("等待 OCR 服务初始化...")
 	fmt.Println("========== OCR 服务启动日志 ==========")
@@ -448,33 +459,24 @@ func StartOCRService() error {
 	}
 
 	// 端口未监听，检查进程状态
-	if ocrProcess != nil && ocrProcess.Process != nil {
-		// 检查进程是否还在运行（使用 Wait 的非阻塞方式，带超时）
-		done := make(chan error, 1)
-		go func() {
-			done <- ocrProcess.Wait()
-		}()
-		select {
-		case err := <-done:
-			// 进程已退出
-			if err != nil {
-				return fmt.Errorf("OCR 服务启动失败，进程已退出: %v。请检查日志文件 logs/ocr_service.log 查看详细错误信息。如果提示缺少模块（如 flask 或 paddleocr），请重新运行 ocr_setup.bat 安装依赖", err)
-			}
-			return fmt.Errorf("OCR 服务启动失败，进程已退出。请检查日志文件 logs/ocr_service.log 查看详细错误信息。如果提示缺少模块（如 flask 或 paddleocr），请重新运行 ocr_setup.bat 安装依赖")
-		case <-time.After(100 * time.Millisecond):
-			// 100ms 内进程未退出，说明进程还在运行但端口未监听
-			// 可能是启动时间过长或其他问题，杀死进程
-			ocrProcess.Process.Kill()
-			<-done // 等待 goroutine 完成
-			return fmt.Errorf("OCR 服务启动超时，端口未监听。请检查日志文件 logs/ocr_service.log 查看详细错误信息")
+	select {
+	case <-exited:
+		// 进程已退出
+		if exitErr := ocrProcessExitError(); exitErr != nil {
+			return fmt.Errorf("OCR 服务启动失败，进程已退出: %v。请检查日志文件 logs/ocr_service.log 查看详细错误信息。如果提示缺少模块（如 flask 或 paddleocr），请重新运行 ocr_setup.bat 安装依赖", exitErr)
 		}
+		return fmt.Errorf("OCR 服务启动失败，进程已退出。请检查日志文件 logs/ocr_service.log 查看详细错误信息。如果提示缺少模块（如 flask 或 paddleocr），请重新运行 ocr_setup.bat 安装依赖")
+	case <-time.After(100 * time.Millisecond):
+		// 100ms 内进程未退出，说明进程还在运行但端口未监听
+		// 可能是启动时间过长或其他问题，杀死进程
+		StopOCRService()
+		return fmt.Errorf("OCR 服务启动超时，端口未监听。请检查日志文件 logs/ocr_service.log 查看详细错误信息")
 	}
-	return fmt.Errorf("OCR 服务启动超时")
 }
 
 // isPortListening 检查指定端口是否在监听
 func isPortListening(host string, port int, timeout time.Duration) bool {
-	address := fmt.Sprintf("%s:%d", host, port)
+	address := net.JoinHostPort(host, strconv.Itoa(port))
 	conn, err := net.DialTimeout("tcp", address, timeout)
 	if err != nil {
 		return false
@@ -505,24 +507,74 @@ func IsOCRServiceRunning() bool {
 }
 
 // StopOCRService 停止 OCR 服务
+// 主动停止的进程退出时监控不会自动重启
 func StopOCRService() {
-	if ocrProcess != nil && ocrProcess.Process != nil {
+	ocrProcessMutex.Lock()
+	cmd, exited := ocrProcess, ocrProcessExited
+	ocrStoppedGeneration = ocrProcessGeneration
+	ocrProcessMutex.Unlock()
+
+	if cmd != nil && cmd.Process != nil {
 		fmt.Println("正在停止 OCR 服务...")
 
 		// 在Windows下使用taskkill
 		if runtime.GOOS == "windows" {
-			cmd := exec.Command("taskkill", "/PID", fmt.Sprintf("%d", ocrProcess.Process.Pid), "/T", "/F")
-			cmd.Run()
+			kill := exec.Command("taskkill", "/PID", fmt.Sprintf("%d", cmd.Process.Pid), "/T", "/F")
+			kill.Run()
 		} else {
-			ocrProcess.Process.Kill()
+			cmd.Process.Kill()
 		}
 
-		ocrProcess.Wait()
+		// 等待回收 goroutine 确认进程退出
+		if exited != nil {
+			select {
+			case <-exited:
+			case <-time.After(_const.OCRServiceRestartWaitTime):
+				fmt.Println("等待 OCR 服务进程退出超时")
+			}
+		}
 		fmt.Println("OCR 服务已停止")
 	}
 	ocrServiceRunning = false
 }
 
+// currentOCRProcessExit 当前进程的退出通道和代数，没有由本程序启动的进程时通道为 nil
+func currentOCRProcessExit() (chan struct{}, uint64) {
+	ocrProcessMutex.Lock()
+	defer ocrProcessMutex.Unlock()
+	return ocrProcessExited, ocrProcessGeneration
+}
+
+// ocrProcessExitError 最近一个进程的退出错误
+func ocrProcessExitError() error {
+	ocrProcessMutex.Lock()
+	defer ocrProcessMutex.Unlock()
+	return ocrProcessExitErr
+}
+
+// ocrProcessStoppedIntentionally 第 generation 代进程是否由 StopOCRService 主动停止
+func ocrProcessStoppedIntentionally(generation uint64) bool {
+	ocrProcessMutex.Lock()
+	defer ocrProcessMutex.Unlock()
+	return generation <= ocrStoppedGeneration
+}
+
+// isOCRProcessAlive 由本程序启动的 OCR 进程是否仍在运行
+func isOCRProcessAlive() bool {
+	ocrProcessMutex.Lock()
+	cmd, exited := ocrProcess, ocrProcessExited
+	ocrProcessMutex.Unlock()
+	if cmd == nil || cmd.Process == nil || exited == nil {
+		return false
+	}
+	select {
+	case <-exited:
+		return false
+	default:
+		return true
+	}
+}
+
 // checkOCREnvironment 检查 OCR 环境是否已设置
 func checkOCREnvironment() bool {
 	// 检查虚拟环境目录
@@ -574,24 +626,26 @@ func checkPythonDependencies(pythonExe string) error {
 }
 
 // SetupOCREnvironment 设置 OCR 环境
+// @description: 按 ocr_manifest.json 准备内置 Python 并校验全部文件；失败时直接返回错误。
+// 旧的批处理安装不校验哈希，只在配置 ocr_legacy_setup 后使用
 func SetupOCREnvironment() error {
 	fmt.Println("开始设置 OCR 环境...")
 
-	// 优先尝试下载并准备内置 Python（仅 Windows）
-	if runtime.GOOS == "windows" {
-		if _, err := ensureEmbeddedPython(); err == nil {
-			fmt.Println("已准备好内置 Python 环境")
-			return nil
-		} else {
-			fmt.Printf("内置 Python 准备失败，回退到批处理安装: %v\n", err)
-		}
-	}
-
 	// 检查是否在 Windows 系统
 	if runtime.GOOS != "windows" {
 		return fmt.Errorf("目前只支持 Windows 系统")
 	}
 
+	if !global.ScumConfig.OCRLegacySetup {
+		if _, err := ensureEmbeddedPython(); err != nil {
+			return fmt.Errorf("内置 Python 环境准备失败: %v", err)
+		}
+		fmt.Println("已准备好内置 Python 环境")
+		return nil
+	}
+
+	fmt.Println("[WARN] 已配置 ocr_legacy_setup，使用批处理安装（依赖未固定哈希）")
+
 	// 检查安装脚本是否存在，优先使用简化版本
 	var setupScript string
 	if _, err := os.Stat("ocr_setup_simple.bat"); err == nil {
@@ -625,6 +679,9 @@ func EnsureOCRService() error {
 	if IsOCRServiceRunning() {
 		return nil
 	}
+	if isRemoteOCRService() {
+		return waitRemoteOCRService()
+	}
 
 	// 检查环境是否已设置
 	if !(runtime.GOOS == "windows" && fileExists(filepath.Join(embedDir, "python.exe"))) {
@@ -636,16 +693,23 @@ func EnsureOCRService() error {
 		}
 	}
 
+	// 离线环境安装识别模型
+	installOfflineModels()
+
 	// 启动服务
 	return StartOCRService()
 }
 
 // RestartOCRService 重启 OCR 服务
+// 手动重启会清除监控的退避和崩溃循环状态
 func RestartOCRService() error {
 	fmt.Println("正在重启 OCR 服务...")
-	StopOCRService()
-	time.Sleep(_const.OCRServiceRestartWaitTime)
-	return StartOCRService()
+	ocrSupervisorMutex.Lock()
+	ocrCrashLoop = false
+	ocrBackoffLevel = 0
+	ocrNextRestartAt = time.Time{}
+	ocrSupervisorMutex.Unlock()
+	return restartOCRService("手动重启", false)
 }
 
 // GetOCRServiceStatus 获取 OCR 服务状态
@@ -653,7 +717,19 @@ func GetOCRServiceStatus() map[string]interface{} {
 	status := map[string]interface{}{
 		"environment_ready": checkOCREnvironment() || (runtime.GOOS == "windows" && fileExists(filepath.Join(embedDir, "python.exe"))),
 		"service_running":   IsOCRServiceRunning(),
-		"process_alive":     ocrProcess != nil && ocrProcess.Process != nil,
+		"process_alive":     isOCRProcessAlive(),
+		"remote":            isRemoteOCRService(),
+		"address":           fmt.Sprintf("%s:%d", global.OCRServiceHost, global.OCRServicePort),
+	}
+	for key, value := range getOCRSupervisorStatus() {
+		status[key] = value
+	}
+
+	// 与当前版本不一致的脚本和字典（被用户修改或尚未更新）
+	if mismatches, err := assets.Status("."); err != nil {
+		status["assets_error"] = err.Error()
+	} else {
+		status["assets_mismatch"] = mismatches
 	}
 
 	// 尝试获取服务详细信息
@@ -0,0 +1,273 @@
+package util
+
+import (
+	"fmt"
+	"net/http"
+	"qq_client/global"
+	_const "qq_client/internal/const"
+	"qq_client/internal/status"
+	"sync"
+	"time"
+)
+
+// OCRRestartRecord OCR 服务重启记录
+type OCRRestartRecord struct {
+	Time      time.Time `json:"time"`
+	Reason    string    `json:"reason"`
+	Automatic bool      `json:"automatic"` // 是否由监控自动触发
+	Success   bool      `json:"success"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// OCR 服务监控状态
+var (
+	ocrSupervisorMutex     sync.Mutex
+	ocrSupervisorStop      chan struct{}
+	ocrRestartMutex        sync.Mutex // 串行化重启（监控自动重启与手动重启）
+	ocrHealthy             = false
+	ocrHealthySince        time.Time
+	ocrLastProbeError      string
+	ocrConsecutiveFailures int
+	ocrBackoffLevel        int
+	ocrNextRestartAt       time.Time
+	ocrCrashLoop           bool
+	ocrRestartHistory      []OCRRestartRecord
+)
+
+// probeOCRHealth 检查 OCR 服务健康状态
+// @description: 与 IsOCRServiceRunning 不同，要求 /health 返回 200 才视为健康
+// @return: error 不健康时返回原因
+func probeOCRHealth() error {
+	if !isPortListening(global.OCRServiceHost, global.OCRServicePort, _const.OCRServicePortCheckTimeout) {
+		return fmt.Errorf("端口 %d 未监听", global.OCRServicePort)
+	}
+	client := &http.Client{Timeout: _const.OCRServiceHealthCheckTimeout}
+	resp, err := client.Get(fmt.Sprintf("http://%s:%d/health", global.OCRServiceHost, global.OCRServicePort))
+	if err != nil {
+		return fmt.Errorf("健康检查请求失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("健康检查返回状态码 %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// StartOCRSupervisor 启动 OCR 服务监控
+// @description: 定期检查 /health 并监听进程退出，服务异常时按指数退避自动重启，短时间内重启次数过多时停止自动重启
+func StartOCRSupervisor() {
+	ocrSupervisorMutex.Lock()
+	defer ocrSupervisorMutex.Unlock()
+	if ocrSupervisorStop != nil {
+		return
+	}
+	ocrSupervisorStop = make(chan struct{})
+	ocrHealthy = probeOCRHealth() == nil
+	if ocrHealthy {
+		ocrHealthySince = time.Now()
+	}
+	go ocrSupervisorLoop(ocrSupervisorStop)
+	fmt.Println("OCR 服务监控已启动")
+}
+
+// StopOCRSupervisor 停止 OCR 服务监控
+func StopOCRSupervisor() {
+	ocrSupervisorMutex.Lock()
+	defer ocrSupervisorMutex.Unlock()
+	if ocrSupervisorStop != nil {
+		close(ocrSupervisorStop)
+		ocrSupervisorStop = nil
+	}
+}
+
+// IsOCRServiceAvailable OCR 服务当前是否可用
+// @description: 监控运行时返回最近一次检查结果，否则实时检查
+// @return: bool
+func IsOCRServiceAvailable() bool {
+	ocrSupervisorMutex.Lock()
+	running, healthy := ocrSupervisorStop != nil, ocrHealthy
+	ocrSupervisorMutex.Unlock()
+	if running {
+		return healthy
+	}
+	return IsOCRServiceRunning()
+}
+
+// GetOCRUnavailableReason 获取最近一次健康检查失败的原因
+// @return: string 服务健康时为空
+func GetOCRUnavailableReason() string {
+	ocrSupervisorMutex.Lock()
+	defer ocrSupervisorMutex.Unlock()
+	if ocrCrashLoop {
+		return "崩溃循环，已停止自动重启: " + ocrLastProbeError
+	}
+	return ocrLastProbeError
+}
+
+// ocrSupervisorLoop 监控主循环
+func ocrSupervisorLoop(stop chan struct{}) {
+	ticker := time.NewTicker(_const.OCRSupervisorProbeInterval)
+	defer ticker.Stop()
+
+	// 已处理过的进程退出通道（退出后通道一直可读，避免重复触发）
+	var handledExit chan struct{}
+	for {
+		// 上报最近一次检查结果（可用性变化时触发 client_status）
+		status.SetOCRHealth(IsOCRServiceAvailable(), GetOCRUnavailableReason())
+
+		exited, generation := currentOCRProcessExit()
+		if exited == handledExit {
+			exited = nil
+		}
+		select {
+		case <-stop:
+			return
+		case <-exited:
+			handledExit = exited
+			// 手动重启、启动超时等主动停止的进程不自动重启（重启时新进程已经或即将启动）
+			if ocrProcessStoppedIntentionally(generation) {
+				continue
+			}
+			exitErr := ocrProcessExitError()
+			fmt.Printf("[WARN] OCR 服务进程已退出: %v\n", exitErr)
+			ocrSupervisorMutex.Lock()
+			ocrHealthy = false
+			ocrConsecutiveFailures = _const.OCRSupervisorFailureThreshold
+			ocrLastProbeError = fmt.Sprintf("进程已退出: %v", exitErr)
+			ocrSupervisorMutex.Unlock()
+			ocrSupervisorCheckRestart("进程退出")
+		case <-ticker.C:
+			err := probeOCRHealth()
+			ocrSupervisorMutex.Lock()
+			if err == nil {
+				if !ocrHealthy {
+					fmt.Println("OCR 服务已恢复")
+					ocrHealthySince = time.Now()
+				}
+				ocrHealthy = true
+				ocrConsecutiveFailures = 0
+				ocrLastProbeError = ""
+				// 持续健康一段时间后重置退避和崩溃循环标记
+				if time.Since(ocrHealthySince) >= _const.OCRSupervisorStableTime {
+					ocrBackoffLevel = 0
+					ocrCrashLoop = false
+				}
+				ocrSupervisorMutex.Unlock()
+				continue
+			}
+			ocrHealthy = false
+			ocrConsecutiveFailures++
+			ocrLastProbeError = err.Error()
+			failures := ocrConsecutiveFailures
+			ocrSupervisorMutex.Unlock()
+
+			fmt.Printf("[WARN] OCR 服务健康检查失败 (%d/%d): %v\n", failures, _const.OCRSupervisorFailureThreshold, err)
+			if failures >= _const.OCRSupervisorFailureThreshold {
+				ocrSupervisorCheckRestart("健康检查连续失败: " + err.Error())
+			}
+		}
+	}
+}
+
+// ocrSupervisorCheckRestart 在退避时间到达且未进入崩溃循环时重启服务
+func ocrSupervisorCheckRestart(reason string) {
+	ocrSupervisorMutex.Lock()
+	if ocrCrashLoop {
+		ocrSupervisorMutex.Unlock()
+		return
+	}
+	if time.Now().Before(ocrNextRestartAt) {
+		ocrSupervisorMutex.Unlock()
+		return
+	}
+
+	// 崩溃循环检测：统计窗口内的自动重启次数
+	recent := 0
+	for _, record := range ocrRestartHistory {
+		if record.Automatic && time.Since(record.Time) <= _const.OCRSupervisorCrashLoopWindow {
+			recent++
+		}
+	}
+	if recent >= _const.OCRSupervisorCrashLoopLimit {
+		ocrCrashLoop = true
+		ocrSupervisorMutex.Unlock()
+		fmt.Printf("[ERROR] OCR 服务在 %v 内已重启 %d 次，停止自动重启，请检查 logs/ocr_service.log\n",
+			_const.OCRSupervisorCrashLoopWindow, recent)
+		return
+	}
+
+	backoff := _const.OCRSupervisorBackoffBase << ocrBackoffLevel
+	if backoff > _const.OCRSupervisorBackoffMax || backoff <= 0 {
+		backoff = _const.OCRSupervisorBackoffMax
+	} else {
+		ocrBackoffLevel++
+	}
+	ocrNextRestartAt = time.Now().Add(backoff)
+	ocrSupervisorMutex.Unlock()
+
+	fmt.Printf("正在自动重启 OCR 服务（原因: %s，下次重启最早在 %v 后）\n", reason, backoff)
+	err := restartOCRService(reason, true)
+
+	ocrSupervisorMutex.Lock()
+	defer ocrSupervisorMutex.Unlock()
+	if err == nil && probeOCRHealth() == nil {
+		ocrHealthy = true
+		ocrHealthySince = time.Now()
+		ocrConsecutiveFailures = 0
+		ocrLastProbeError = ""
+	}
+}
+
+// recordOCRRestart 记录一次重启
+func recordOCRRestart(reason string, automatic bool, err error) {
+	record := OCRRestartRecord{Time: time.Now(), Reason: reason, Automatic: automatic, Success: err == nil}
+	if err != nil {
+		record.Error = err.Error()
+	}
+	status.AddOCRRestart()
+	ocrSupervisorMutex.Lock()
+	defer ocrSupervisorMutex.Unlock()
+	ocrRestartHistory = append(ocrRestartHistory, record)
+	if len(ocrRestartHistory) > _const.OCRSupervisorHistorySize {
+		ocrRestartHistory = ocrRestartHistory[len(ocrRestartHistory)-_const.OCRSupervisorHistorySize:]
+	}
+}
+
+// restartOCRService 重启 OCR 服务并记录结果
+func restartOCRService(reason string, automatic bool) error {
+	ocrRestartMutex.Lock()
+	defer ocrRestartMutex.Unlock()
+
+	StopOCRService()
+	time.Sleep(_const.OCRServiceRestartWaitTime)
+	err := StartOCRService()
+	recordOCRRestart(reason, automatic, err)
+	if err != nil {
+		fmt.Printf("[ERROR] OCR 服务重启失败: %v\n", err)
+	}
+	return err
+}
+
+// getOCRSupervisorStatus 获取监控状态（用于 GetOCRServiceStatus）
+func getOCRSupervisorStatus() map[string]interface{} {
+	ocrSupervisorMutex.Lock()
+	defer ocrSupervisorMutex.Unlock()
+
+	history := make([]OCRRestartRecord, len(ocrRestartHistory))
+	copy(history, ocrRestartHistory)
+	status := map[string]interface{}{
+		"supervisor_running":   ocrSupervisorStop != nil,
+		"healthy":              ocrHealthy,
+		"consecutive_failures": ocrConsecutiveFailures,
+		"crash_loop":           ocrCrashLoop,
+		"restart_count":        len(history),
+		"restart_history":      history,
+	}
+	if ocrLastProbeError != "" {
+		status["last_probe_error"] = ocrLastProbeError
+	}
+	if !ocrNextRestartAt.IsZero() {
+		status["next_restart_at"] = ocrNextRestartAt
+	}
+	return status
+}
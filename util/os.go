@@ -59,6 +59,19 @@ type BITMAPINFO struct {
 	BmiColors [1]uint32
 }
 
+// GetClientSize 获取窗口客户区大小
+// @description: 获取窗口客户区的宽高（即截图的分辨率）
+// @param: hwnd syscall.Handle 窗口句柄
+// @return: width, height int, ok bool
+func GetClientSize(hwnd syscall.Handle) (int, int, bool) {
+	var rect RECT
+	ret, _, _ := procGetClientRect.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&rect)))
+	if ret == 0 {
+		return 0, 0, false
+	}
+	return int(rect.Right - rect.Left), int(rect.Bottom - rect.Top), true
+}
+
 // captureWindowImage 截取指定窗口的图像
 // @description: 截取指定窗口的图像，支持最小化窗口截图，包含窗口状态检查和重试机制
 func captureWindowImage(hwnd syscall.Handle) (*image.RGBA, error) {
@@ -308,6 +321,63 @@ func ScreenshotGrayscale(hand syscall.Handle, x1, y1, x2, y2 int) (string, error
 	return filePath, nil
 }
 
+// CaptureWindowPNG
+// @function: CaptureWindowPNG
+// @description: 截取整个窗口并编码为 PNG（不落盘），用于一帧多次查询或由 OCR 服务端裁剪
+// @param: hand syscall.Handle 窗口句柄
+// @return: []byte, error
+func CaptureWindowPNG(hand syscall.Handle) ([]byte, error) {
+	img, err := captureWindowImage(hand)
+	if err != nil {
+		return nil, errors.New("无法截取窗口图像:" + err.Error())
+	}
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img); err != nil {
+		return nil, errors.New("编码图片失败:" + err.Error())
+	}
+	return buf.Bytes(), nil
+}
+
+// CaptureWindowRegionPNG
+// @function: CaptureWindowRegionPNG
+// @description: 截取整个窗口后只将指定区域编码为 PNG，减少上传到 OCR 服务的数据量
+// @param: hand syscall.Handle 窗口句柄
+// @param: region image.Rectangle 窗口客户区坐标，超出窗口的部分会被裁掉
+// @return: []byte PNG 数据, image.Point 区域左上角在窗口中的坐标, error
+func CaptureWindowRegionPNG(hand syscall.Handle, region image.Rectangle) ([]byte, image.Point, error) {
+	img, err := captureWindowImage(hand)
+	if err != nil {
+		return nil, image.Point{}, errors.New("无法截取窗口图像:" + err.Error())
+	}
+	region = region.Intersect(img.Bounds())
+	if region.Empty() {
+		return nil, image.Point{}, errors.New("截图区域不在窗口内")
+	}
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img.SubImage(region)); err != nil {
+		return nil, image.Point{}, errors.New("编码图片失败:" + err.Error())
+	}
+	return buf.Bytes(), region.Min, nil
+}
+
+// CaptureWindowCompressedPNG
+// @function: CaptureWindowCompressedPNG
+// @description: 截取整个窗口并以最高压缩级别编码为 PNG（用于远程诊断，体积优先于速度）
+// @param: hand syscall.Handle 窗口句柄
+// @return: []byte, error
+func CaptureWindowCompressedPNG(hand syscall.Handle) ([]byte, error) {
+	img, err := captureWindowImage(hand)
+	if err != nil {
+		return nil, errors.New("无法截取窗口图像:" + err.Error())
+	}
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestCompression}
+	if err = encoder.Encode(&buf, img); err != nil {
+		return nil, errors.New("编码图片失败:" + err.Error())
+	}
+	return buf.Bytes(), nil
+}
+
 // SpecifiedCoordinateColor
 // @author: [Fantasia](https://www.npc0.com)
 // @function: 获取指定坐标颜色
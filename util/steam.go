@@ -0,0 +1,104 @@
+package util
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	_const "qq_client/internal/const"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	vdfPathRegexp    = regexp.MustCompile(`"path"\s+"([^"]+)"`)
+	acfBuildIDRegexp = regexp.MustCompile(`"buildid"\s+"(\d+)"`)
+
+	// 游戏版本缓存（按 appmanifest 文件修改时间失效）
+	gameBuildMutex   sync.Mutex
+	gameBuildPath    string
+	gameBuildModTime time.Time
+	gameBuildID      string
+)
+
+// getSteamPath 获取 Steam 安装路径
+// @description: 从注册表读取 SteamPath，失败时使用默认安装路径
+// @return: string
+func getSteamPath() string {
+	cmd := exec.Command("reg", "query", `HKCU\Software\Valve\Steam`, "/v", "SteamPath")
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err == nil {
+		for _, line := range strings.Split(out.String(), "\n") {
+			fields := strings.SplitN(strings.TrimSpace(line), "REG_SZ", 2)
+			if len(fields) == 2 && strings.TrimSpace(fields[0]) == "SteamPath" {
+				return filepath.FromSlash(strings.TrimSpace(fields[1]))
+			}
+		}
+	}
+	return _const.DefaultSteamPath
+}
+
+// findSCUMAppManifest 查找 SCUM 的 appmanifest 文件
+// @description: 依次在 Steam 主目录及 libraryfolders.vdf 中登记的库目录下查找
+// @return: string 文件路径, error
+func findSCUMAppManifest() (string, error) {
+	steamPath := getSteamPath()
+	libraries := []string{steamPath}
+
+	if data, err := os.ReadFile(filepath.Join(steamPath, "steamapps", "libraryfolders.vdf")); err == nil {
+		for _, match := range vdfPathRegexp.FindAllStringSubmatch(string(data), -1) {
+			// vdf 中路径分隔符为转义的反斜杠
+			libraries = append(libraries, strings.ReplaceAll(match[1], `\\`, `\`))
+		}
+	}
+
+	manifestName := "appmanifest_" + _const.SCUMAppID + ".acf"
+	for _, library := range libraries {
+		manifestPath := filepath.Join(library, "steamapps", manifestName)
+		if _, err := os.Stat(manifestPath); err == nil {
+			return manifestPath, nil
+		}
+	}
+	return "", fmt.Errorf("未找到 %s", manifestName)
+}
+
+// GetSCUMBuildID 获取 SCUM 游戏版本号（Steam buildid）
+// @description: 读取 appmanifest 中的 buildid，文件未变化时使用缓存结果
+// @return: string buildid，获取失败时返回 "unknown"
+func GetSCUMBuildID() string {
+	gameBuildMutex.Lock()
+	defer gameBuildMutex.Unlock()
+
+	if gameBuildPath == "" {
+		manifestPath, err := findSCUMAppManifest()
+		if err != nil {
+			return "unknown"
+		}
+		gameBuildPath = manifestPath
+	}
+
+	info, err := os.Stat(gameBuildPath)
+	if err != nil {
+		gameBuildPath = ""
+		return "unknown"
+	}
+	if gameBuildID != "" && info.ModTime().Equal(gameBuildModTime) {
+		return gameBuildID
+	}
+
+	data, err := os.ReadFile(gameBuildPath)
+	if err != nil {
+		return "unknown"
+	}
+	match := acfBuildIDRegexp.FindStringSubmatch(string(data))
+	if match == nil {
+		return "unknown"
+	}
+	gameBuildID = match[1]
+	gameBuildModTime = info.ModTime()
+	return gameBuildID
+}
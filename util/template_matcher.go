@@ -0,0 +1,351 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	_const "qq_client/internal/const"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// uiTemplate 界面控件参考模板（灰度）
+type uiTemplate struct {
+	Key    string    // 文本key（如 "MUTE"）
+	Lang   string    // 模板所属语言目录
+	Path   string    // 模板文件路径
+	Width  int       // 模板宽度
+	Height int       // 模板高度
+	Pixels []float64 // 去均值后的灰度像素
+	Energy float64   // 去均值像素的平方和
+}
+
+// grayFrame 灰度帧及其积分图，用于快速计算任意窗口的均值和方差
+type grayFrame struct {
+	Width  int
+	Height int
+	Pixels []float64
+	sum    []float64 // (Width+1)*(Height+1) 积分图
+	sqSum  []float64 // (Width+1)*(Height+1) 平方积分图
+}
+
+// 按分辨率缓存的模板集合
+var (
+	templateStore = make(map[string]map[string][]*uiTemplate) // "宽x高" -> key -> 模板列表
+	templateMutex sync.RWMutex
+)
+
+// ReloadUITemplates 清空已加载的模板，下次匹配时重新从磁盘加载
+// @description: 清空模板缓存（模板文件更新后调用）
+func ReloadUITemplates() {
+	templateMutex.Lock()
+	defer templateMutex.Unlock()
+	templateStore = make(map[string]map[string][]*uiTemplate)
+}
+
+// resolutionKey 生成分辨率目录名
+func resolutionKey(width, height int) string {
+	return fmt.Sprintf("%dx%d", width, height)
+}
+
+// getUITemplates 获取指定分辨率下某个文本key的所有语言模板
+// @description: 首次访问某分辨率时从 templates/<语言>/<宽>x<高>/ 加载全部模板
+// @param: width, height int 窗口客户区分辨率
+// @param: textKey string 文本key
+// @return: []*uiTemplate
+func getUITemplates(width, height int, textKey string) []*uiTemplate {
+	resKey := resolutionKey(width, height)
+	textKey = strings.ToUpper(textKey)
+
+	templateMutex.RLock()
+	byKey, loaded := templateStore[resKey]
+	templateMutex.RUnlock()
+	if loaded {
+		return byKey[textKey]
+	}
+
+	templateMutex.Lock()
+	defer templateMutex.Unlock()
+	if byKey, loaded = templateStore[resKey]; loaded {
+		return byKey[textKey]
+	}
+	byKey = loadUITemplates(resKey)
+	templateStore[resKey] = byKey
+	return byKey[textKey]
+}
+
+// loadUITemplates 从磁盘加载指定分辨率的模板
+func loadUITemplates(resKey string) map[string][]*uiTemplate {
+	result := make(map[string][]*uiTemplate)
+
+	langDirs, err := os.ReadDir(_const.TemplateDir)
+	if err != nil {
+		// 模板目录不存在时静默回退到 OCR
+		return result
+	}
+
+	for _, langDir := range langDirs {
+		if !langDir.IsDir() {
+			continue
+		}
+		dir := filepath.Join(_const.TemplateDir, langDir.Name(), resKey)
+		files, err := filepath.Glob(filepath.Join(dir, "*.png"))
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			tpl, err := loadUITemplate(file, langDir.Name())
+			if err != nil {
+				fmt.Printf("加载模板失败 %s: %v\n", file, err)
+				continue
+			}
+			result[tpl.Key] = append(result[tpl.Key], tpl)
+		}
+	}
+
+	count := 0
+	for _, list := range result {
+		count += len(list)
+	}
+	if count > 0 {
+		fmt.Printf("已加载 %d 个界面模板（分辨率 %s）\n", count, resKey)
+	}
+	return result
+}
+
+// loadUITemplate 加载单个模板文件
+// @description: 文件名格式为 KEY.png 或 KEY-序号.png（同一控件的多个参考截图）
+func loadUITemplate(path, lang string) (*uiTemplate, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	img, err := png.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width < 4 || height < 4 {
+		return nil, errors.New("模板尺寸过小")
+	}
+
+	pixels := make([]float64, 0, width*height)
+	var total float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			v := luminance(img.At(x, y).RGBA())
+			pixels = append(pixels, v)
+			total += v
+		}
+	}
+
+	// 去均值，使互相关只与纹理有关而与整体亮度无关
+	mean := total / float64(len(pixels))
+	var energy float64
+	for i := range pixels {
+		pixels[i] -= mean
+		energy += pixels[i] * pixels[i]
+	}
+	if energy == 0 {
+		return nil, errors.New("模板为纯色，无法匹配")
+	}
+
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	if idx := strings.Index(stem, "-"); idx > 0 {
+		stem = stem[:idx]
+	}
+
+	return &uiTemplate{
+		Key:    strings.ToUpper(stem),
+		Lang:   lang,
+		Path:   path,
+		Width:  width,
+		Height: height,
+		Pixels: pixels,
+		Energy: energy,
+	}, nil
+}
+
+// luminance 将 RGBA（16位通道）转换为 0-255 的亮度值
+func luminance(r, g, b, _ uint32) float64 {
+	return (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 257
+}
+
+// newGrayFrame 将窗口截图转换为灰度帧并构建积分图
+func newGrayFrame(img *image.RGBA) *grayFrame {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	frame := &grayFrame{
+		Width:  width,
+		Height: height,
+		Pixels: make([]float64, width*height),
+		sum:    make([]float64, (width+1)*(height+1)),
+		sqSum:  make([]float64, (width+1)*(height+1)),
+	}
+
+	stride := width + 1
+	for y := 0; y < height; y++ {
+		var rowSum, rowSq float64
+		for x := 0; x < width; x++ {
+			offset := img.PixOffset(bounds.Min.X+x, bounds.Min.Y+y)
+			pix := img.Pix[offset : offset+3 : offset+3]
+			v := 0.299*float64(pix[0]) + 0.587*float64(pix[1]) + 0.114*float64(pix[2])
+			frame.Pixels[y*width+x] = v
+			rowSum += v
+			rowSq += v * v
+			frame.sum[(y+1)*stride+x+1] = frame.sum[y*stride+x+1] + rowSum
+			frame.sqSum[(y+1)*stride+x+1] = frame.sqSum[y*stride+x+1] + rowSq
+		}
+	}
+	return frame
+}
+
+// windowStats 计算帧中以 (x,y) 为左上角、w*h 大小窗口的像素和与平方和
+func (f *grayFrame) windowStats(x, y, w, h int) (sum, sqSum float64) {
+	stride := f.Width + 1
+	a, b := y*stride+x, y*stride+x+w
+	c, d := (y+h)*stride+x, (y+h)*stride+x+w
+	return f.sum[d] - f.sum[b] - f.sum[c] + f.sum[a], f.sqSum[d] - f.sqSum[b] - f.sqSum[c] + f.sqSum[a]
+}
+
+// ncc 计算模板在 (x,y) 处的归一化互相关系数
+func (f *grayFrame) ncc(tpl *uiTemplate, x, y int) float64 {
+	n := float64(tpl.Width * tpl.Height)
+	sum, sqSum := f.windowStats(x, y, tpl.Width, tpl.Height)
+	variance := sqSum - sum*sum/n
+	if variance <= 1e-6 {
+		return -1
+	}
+
+	// 模板已去均值，Σ(T'·I) 即等于 Σ(T'·(I-Ī))
+	var num float64
+	for ty := 0; ty < tpl.Height; ty++ {
+		row := f.Pixels[(y+ty)*f.Width+x : (y+ty)*f.Width+x+tpl.Width]
+		tplRow := tpl.Pixels[ty*tpl.Width : (ty+1)*tpl.Width]
+		for tx, v := range row {
+			num += tplRow[tx] * v
+		}
+	}
+	return num / math.Sqrt(tpl.Energy*variance)
+}
+
+// matchTemplateInRegion 在帧的指定区域内搜索模板
+// @description: 先按 TemplateCoarseStep 步长粗搜索，再在最佳位置附近逐像素精修
+// @param: frame *grayFrame 灰度帧
+// @param: tpl *uiTemplate 模板
+// @param: region image.Rectangle 搜索区域（空区域表示整帧）
+// @return: x, y int 最佳匹配左上角, score float64 匹配得分
+func matchTemplateInRegion(frame *grayFrame, tpl *uiTemplate, region image.Rectangle) (int, int, float64) {
+	full := image.Rect(0, 0, frame.Width, frame.Height)
+	if region.Empty() {
+		region = full
+	}
+	region = region.Intersect(full)
+
+	maxX := region.Max.X - tpl.Width
+	maxY := region.Max.Y - tpl.Height
+	if maxX < region.Min.X || maxY < region.Min.Y {
+		return 0, 0, -1
+	}
+
+	bestX, bestY, best := region.Min.X, region.Min.Y, -1.0
+	step := _const.TemplateCoarseStep
+	for y := region.Min.Y; y <= maxY; y += step {
+		for x := region.Min.X; x <= maxX; x += step {
+			if score := frame.ncc(tpl, x, y); score > best {
+				bestX, bestY, best = x, y, score
+			}
+		}
+	}
+
+	// 粗搜索得分过低，说明区域内不存在该控件
+	if best < _const.TemplateMatchThreshold-_const.TemplateCoarseSlack {
+		return bestX, bestY, best
+	}
+
+	// 在粗搜索最佳位置附近精修
+	cx, cy := bestX, bestY
+	for y := cy - step; y <= cy+step; y++ {
+		for x := cx - step; x <= cx+step; x++ {
+			if x < region.Min.X || y < region.Min.Y || x > maxX || y > maxY {
+				continue
+			}
+			if score := frame.ncc(tpl, x, y); score > best {
+				bestX, bestY, best = x, y, score
+			}
+		}
+	}
+	return bestX, bestY, best
+}
+
+// matchUITemplate 在窗口截图中查找文本key对应的控件模板
+// @description: 遍历该分辨率下所有语言的模板，返回得分最高且超过阈值的位置
+// @param: img *image.RGBA 窗口截图
+// @param: textKey string 文本key（如 "MUTE"）
+// @param: region image.Rectangle 搜索区域（空区域表示全屏）
+// @return: *TextPositionCache, float64 最佳得分, error
+func matchUITemplate(img *image.RGBA, textKey string, region image.Rectangle) (*TextPositionCache, float64, error) {
+	bounds := img.Bounds()
+	templates := getUITemplates(bounds.Dx(), bounds.Dy(), textKey)
+	if len(templates) == 0 {
+		return nil, -1, fmt.Errorf("分辨率 %s 下没有文本 '%s' 的模板", resolutionKey(bounds.Dx(), bounds.Dy()), textKey)
+	}
+
+	frame := newGrayFrame(img)
+	var bestTpl *uiTemplate
+	bestX, bestY, bestScore := 0, 0, -1.0
+	for _, tpl := range templates {
+		x, y, score := matchTemplateInRegion(frame, tpl, region)
+		if score > bestScore {
+			bestTpl, bestX, bestY, bestScore = tpl, x, y, score
+		}
+	}
+
+	if bestTpl == nil || bestScore < _const.TemplateMatchThreshold {
+		return nil, bestScore, fmt.Errorf("模板匹配未找到文本 '%s' (最高得分: %.3f)", textKey, bestScore)
+	}
+
+	fmt.Printf("模板匹配成功: 文本 '%s' (语言: %s, 得分: %.3f) 在位置 [%d,%d,%d,%d]\n",
+		textKey, bestTpl.Lang, bestScore, bestX, bestY, bestX+bestTpl.Width, bestY+bestTpl.Height)
+	return &TextPositionCache{
+		X1:         bestX,
+		Y1:         bestY,
+		X2:         bestX + bestTpl.Width,
+		Y2:         bestY + bestTpl.Height,
+		Found:      true,
+		Confidence: bestScore,
+	}, bestScore, nil
+}
+
+// searchTextByTemplate 使用模板匹配在窗口中查找文本
+// @description: 截取窗口图像后进行模板匹配，未配置模板或未匹配时返回错误
+// @param: hand syscall.Handle 窗口句柄
+// @param: textKey string 文本key
+// @param: region image.Rectangle 搜索区域（空区域表示全屏）
+// @return: *TextPositionCache, error
+func searchTextByTemplate(hand syscall.Handle, textKey string, region image.Rectangle) (*TextPositionCache, error) {
+	img, err := captureWindowImage(hand)
+	if err != nil {
+		return nil, fmt.Errorf("截图失败: %v", err)
+	}
+	cache, _, err := matchUITemplate(img, textKey, region)
+	return cache, err
+}
+
+// hasUITemplates 判断当前窗口分辨率下是否存在指定文本的模板
+func hasUITemplates(hand syscall.Handle, textKey string) bool {
+	width, height, ok := GetClientSize(hand)
+	if !ok {
+		return false
+	}
+	return len(getUITemplates(width, height, textKey)) > 0
+}
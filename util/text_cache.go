@@ -0,0 +1,197 @@
+package util
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"qq_client/global"
+	_const "qq_client/internal/const"
+	"syscall"
+	"time"
+)
+
+// textCacheKey 文本位置缓存键
+// 任一字段变化都意味着界面布局可能变化，缓存整体失效
+type textCacheKey struct {
+	Width      int    `json:"width"`       // 窗口客户区宽度
+	Height     int    `json:"height"`      // 窗口客户区高度
+	UILanguage string `json:"ui_language"` // 游戏界面语言
+	GameBuild  string `json:"game_build"`  // 游戏版本（Steam buildid）
+}
+
+// textCacheFile 文本位置缓存文件结构
+type textCacheFile struct {
+	Key     textCacheKey                  `json:"key"`
+	SavedAt time.Time                     `json:"saved_at"`
+	Entries map[string]*TextPositionCache `json:"entries"`
+}
+
+// 缓存持久化状态（受 cacheMutex 保护）
+var (
+	textCacheKeyCurrent *textCacheKey
+	textCacheDirty      bool
+	textCacheLastSave   time.Time
+)
+
+// currentTextCacheKey 计算当前的缓存键
+// @param: hand syscall.Handle 窗口句柄
+// @return: textCacheKey, bool 获取窗口大小失败时返回 false
+func currentTextCacheKey(hand syscall.Handle) (textCacheKey, bool) {
+	width, height, ok := GetClientSize(hand)
+	if !ok || width <= 0 || height <= 0 {
+		return textCacheKey{}, false
+	}
+	language := global.ScumConfig.UILanguage
+	if language == "" {
+		language = "auto"
+	}
+	return textCacheKey{
+		Width:      width,
+		Height:     height,
+		UILanguage: language,
+		GameBuild:  GetSCUMBuildID(),
+	}, true
+}
+
+// LoadTextPositionCache 从磁盘加载文本位置缓存
+// @description: 程序启动时调用，丢弃超过 TextCacheMaxAge 未验证的条目；缓存键在首次同步时校验
+// @return: error 缓存文件不存在时返回 nil
+func LoadTextPositionCache() error {
+	data, err := os.ReadFile(_const.TextCacheFile)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("读取缓存文件失败: %v", err)
+	}
+
+	var file textCacheFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("解析缓存文件失败: %v", err)
+	}
+
+	entries := make(map[string]*TextPositionCache, len(file.Entries))
+	for text, cache := range file.Entries {
+		if cache == nil || time.Since(cache.LastVerified) > _const.TextCacheMaxAge {
+			continue
+		}
+		entries[text] = cache
+	}
+
+	cacheMutex.Lock()
+	defer cacheMutex.Unlock()
+	textPositionCache = entries
+	key := file.Key
+	textCacheKeyCurrent = &key
+	textCacheDirty = false
+	fmt.Printf("已加载文本位置缓存: %d 条 (窗口: %dx%d, 语言: %s, 版本: %s)\n",
+		len(entries), key.Width, key.Height, key.UILanguage, key.GameBuild)
+	return nil
+}
+
+// SyncTextPositionCache 校验缓存键并按需落盘
+// @description: 窗口大小、界面语言或游戏版本变化时清空缓存；缓存有变化且距上次写入超过 TextCacheSaveInterval 时写入磁盘
+// @param: hand syscall.Handle 窗口句柄
+func SyncTextPositionCache(hand syscall.Handle) {
+	key, ok := currentTextCacheKey(hand)
+	if !ok {
+		return
+	}
+
+	cacheMutex.Lock()
+	if textCacheKeyCurrent == nil || *textCacheKeyCurrent != key {
+		if textCacheKeyCurrent != nil && len(textPositionCache) > 0 {
+			fmt.Printf("缓存键已变化 (%dx%d/%s/%s -> %dx%d/%s/%s)，清空文本位置缓存\n",
+				textCacheKeyCurrent.Width, textCacheKeyCurrent.Height, textCacheKeyCurrent.UILanguage, textCacheKeyCurrent.GameBuild,
+				key.Width, key.Height, key.UILanguage, key.GameBuild)
+			textPositionCache = make(map[string]*TextPositionCache)
+		}
+		textCacheKeyCurrent = &key
+		textCacheDirty = true
+	}
+	shouldSave := textCacheDirty && time.Since(textCacheLastSave) >= _const.TextCacheSaveInterval
+	cacheMutex.Unlock()
+
+	if shouldSave {
+		if err := SaveTextPositionCache(); err != nil {
+			fmt.Printf("[WARN] 保存文本位置缓存失败: %v\n", err)
+		}
+	}
+}
+
+// SaveTextPositionCache 将文本位置缓存写入磁盘
+// @description: 先写入临时文件再重命名，避免写入中断导致缓存文件损坏
+// @return: error
+func SaveTextPositionCache() error {
+	cacheMutex.Lock()
+	if textCacheKeyCurrent == nil {
+		cacheMutex.Unlock()
+		return nil
+	}
+	file := textCacheFile{
+		Key:     *textCacheKeyCurrent,
+		SavedAt: time.Now(),
+		Entries: make(map[string]*TextPositionCache, len(textPositionCache)),
+	}
+	for text, cache := range textPositionCache {
+		entry := *cache
+		file.Entries[text] = &entry
+	}
+	textCacheDirty = false
+	textCacheLastSave = file.SavedAt
+	cacheMutex.Unlock()
+
+	if err := writeTextCacheFile(&file); err != nil {
+		// 写入失败时保留脏标记，下次同步时重试
+		cacheMutex.Lock()
+		textCacheDirty = true
+		cacheMutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// DumpTextPositionCache 导出当前的文本位置缓存
+// @description: 格式与缓存文件相同（saved_at 为导出时间），不影响落盘状态；用于远程诊断
+// @return: []byte, error
+func DumpTextPositionCache() ([]byte, error) {
+	cacheMutex.RLock()
+	file := textCacheFile{
+		SavedAt: time.Now(),
+		Entries: make(map[string]*TextPositionCache, len(textPositionCache)),
+	}
+	if textCacheKeyCurrent != nil {
+		file.Key = *textCacheKeyCurrent
+	}
+	for text, cache := range textPositionCache {
+		entry := *cache
+		file.Entries[text] = &entry
+	}
+	cacheMutex.RUnlock()
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("序列化缓存失败: %v", err)
+	}
+	return data, nil
+}
+
+// writeTextCacheFile 写入缓存文件
+func writeTextCacheFile(file *textCacheFile) error {
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化缓存失败: %v", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(_const.TextCacheFile), 0755); err != nil {
+		return fmt.Errorf("创建缓存目录失败: %v", err)
+	}
+	tmpPath := _const.TextCacheFile + ".tmp"
+	if err = os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("写入缓存文件失败: %v", err)
+	}
+	if err = os.Rename(tmpPath, _const.TextCacheFile); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("替换缓存文件失败: %v", err)
+	}
+	return nil
+}
@@ -2,6 +2,7 @@ package util
 
 import (
 	"fmt"
+	"sync"
 	"syscall"
 	"time"
 	"unsafe"
@@ -25,6 +26,9 @@ var (
 	procSetFocus                 = user32.NewProc("SetFocus")
 	procAttachThreadInput        = user32.NewProc("AttachThreadInput")
 	procGetWindowThreadProcessId = user32.NewProc("GetWindowThreadProcessId")
+	procEnumWindows              = user32.NewProc("EnumWindows")
+	procGetClassNameW            = user32.NewProc("GetClassNameW")
+	procGetWindowTextW           = user32.NewProc("GetWindowTextW")
 
 	kernel32               = syscall.NewLazyDLL("kernel32.dll")
 	procGetCurrentThreadId = kernel32.NewProc("GetCurrentThreadId")
@@ -93,6 +97,89 @@ func FindWindow(className, windowName string) syscall.Handle {
 	return syscall.Handle(r0)
 }
 
+// 按进程号查找窗口（EnumWindows 回调只创建一次，syscall.NewCallback 创建的回调无法释放）
+var (
+	findWindowMutex     sync.Mutex
+	findWindowClassName string
+	findWindowPID       uint32
+	findWindowResult    syscall.Handle
+	findWindowCallback  = syscall.NewCallback(func(hwnd syscall.Handle, _ uintptr) uintptr {
+		if _, pid := GetWindowThreadProcessId(hwnd); pid != findWindowPID {
+			return 1
+		}
+		var className [256]uint16
+		n, _, _ := procGetClassNameW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&className[0])), uintptr(len(className)))
+		if syscall.UTF16ToString(className[:n]) != findWindowClassName {
+			return 1
+		}
+		findWindowResult = hwnd
+		return 0
+	})
+)
+
+// FindWindowByPID
+// @function: FindWindowByPID
+// @description: 查找指定进程中指定类名的顶层窗口
+// @param: className string 类名, pid uint32 进程号
+// @return: syscall.Handle 未找到时为 0
+func FindWindowByPID(className string, pid uint32) syscall.Handle {
+	findWindowMutex.Lock()
+	defer findWindowMutex.Unlock()
+	findWindowClassName, findWindowPID, findWindowResult = className, pid, 0
+	procEnumWindows.Call(findWindowCallback, 0)
+	return findWindowResult
+}
+
+// 按类名列出窗口（回调只创建一次）
+var (
+	listWindowsClassName string
+	listWindowsResult    []syscall.Handle
+	listWindowsCallback  = syscall.NewCallback(func(hwnd syscall.Handle, _ uintptr) uintptr {
+		var className [256]uint16
+		n, _, _ := procGetClassNameW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&className[0])), uintptr(len(className)))
+		if syscall.UTF16ToString(className[:n]) == listWindowsClassName {
+			listWindowsResult = append(listWindowsResult, hwnd)
+		}
+		return 1
+	})
+)
+
+// FindWindowsByClass
+// @function: FindWindowsByClass
+// @description: 列出指定类名的所有顶层窗口
+// @param: className string 类名
+// @return: []syscall.Handle
+func FindWindowsByClass(className string) []syscall.Handle {
+	findWindowMutex.Lock()
+	defer findWindowMutex.Unlock()
+	listWindowsClassName, listWindowsResult = className, nil
+	procEnumWindows.Call(listWindowsCallback, 0)
+	return listWindowsResult
+}
+
+// GetWindowTitle
+// @function: GetWindowTitle
+// @description: 获取窗口标题
+// @param: hwnd syscall.Handle 窗口句柄
+// @return: string
+func GetWindowTitle(hwnd syscall.Handle) string {
+	var title [256]uint16
+	n, _, _ := procGetWindowTextW.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&title[0])), uintptr(len(title)))
+	return syscall.UTF16ToString(title[:n])
+}
+
+// FindGameWindow
+// @function: FindGameWindow
+// @description: 查找游戏实例的窗口（UnrealWindow），配置了进程号时按进程号匹配，否则按标题匹配
+// @param: pid int32 进程号, title string 窗口标题
+// @return: syscall.Handle 未找到时为 0
+func FindGameWindow(pid int32, title string) syscall.Handle {
+	if pid > 0 {
+		return FindWindowByPID("UnrealWindow", uint32(pid))
+	}
+	return FindWindow("UnrealWindow", title)
+}
+
 // SetForegroundWindow
 // @author: [Fantasia](https://www.npc0.com)
 // @function: SetForegroundWindow